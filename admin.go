@@ -0,0 +1,382 @@
+// ФАЙЛ: admin.go
+// НАЗНАЧЕНИЕ: Служебные endpoint'ы для администрирования и диагностики
+// ОСОБЕННОСТИ:
+//   - Доступ по токену ADMIN_TOKEN (заголовок Authorization: Bearer)
+//     или по логину/паролю ADMIN_USER/ADMIN_PASS (HTTP Basic Auth, удобно из браузера)
+//   - Список заблокированных IP с причинами (/admin/blocked)
+//   - Изменение лимитов rate limiter без перезапуска (/admin/config)
+//   - История доставки алертов (/admin/alerts, см. alertaudit.go)
+//   - Сводка блокировок по причинам без IP (/admin/security/summary, см. blocksummary.go)
+//   - Полная проверка подсистем (/health/full, см. health.go)
+//   - Профилирование через net/http/pprof (включается ENABLE_PPROF)
+//   - Служебные маршруты не проходят через rate limiter, но неудачные попытки входа
+//     считаются по IP: после ADMIN_MAX_AUTH_FAILURES (по умолчанию 5) за
+//     ADMIN_AUTH_FAILURE_WINDOW (по умолчанию 15m) IP блокируется на BLOCK_DURATION
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ АДМИНИСТРИРОВАНИЯ
+var (
+	// Токен администратора (из переменных окружения)
+	adminToken string
+	// Логин и пароль для Basic Auth (работают, только если заданы оба)
+	adminUser string
+	adminPass string
+	// Сколько неудачных попыток входа с одного IP допускается за окно (0 — не ограничивать)
+	adminMaxAuthFailures = 5
+	// Окно подсчёта неудачных попыток входа
+	adminAuthFailureWindow = 15 * time.Minute
+	// Неудачные попытки входа: IP → число и время первой попытки в окне
+	adminAuthFailures = make(map[string]adminAuthFailure)
+	adminAuthMutex    sync.Mutex
+)
+
+// СТРУКТУРА: Неудачные попытки входа администратора с одного IP
+type adminAuthFailure struct {
+	Count int
+	First time.Time
+}
+
+// ИНИЦИАЛИЗАЦИЯ АДМИН-ДОСТУПА
+func initAdmin() {
+	adminToken = os.Getenv("ADMIN_TOKEN")
+	adminUser = os.Getenv("ADMIN_USER")
+	adminPass = os.Getenv("ADMIN_PASS")
+
+	adminMaxAuthFailures = getEnvInt("ADMIN_MAX_AUTH_FAILURES", 5)
+	if adminMaxAuthFailures < 0 {
+		logger.InfoLogger.Println("⚠️ ADMIN_MAX_AUTH_FAILURES не может быть отрицательным, используем 5")
+		adminMaxAuthFailures = 5
+	}
+	adminAuthFailureWindow = getEnvDuration("ADMIN_AUTH_FAILURE_WINDOW", 15*time.Minute)
+	if adminAuthFailureWindow <= 0 {
+		logger.InfoLogger.Println("⚠️ ADMIN_AUTH_FAILURE_WINDOW должен быть больше нуля, используем 15m")
+		adminAuthFailureWindow = 15 * time.Minute
+	}
+
+	if (adminUser == "") != (adminPass == "") {
+		logger.InfoLogger.Println("⚠️ Для Basic Auth нужны оба параметра ADMIN_USER и ADMIN_PASS, вход по паролю отключен")
+	}
+
+	if !isAdminConfigured() {
+		logger.InfoLogger.Println("⚠️ ADMIN_TOKEN и ADMIN_USER/ADMIN_PASS не заданы, служебные endpoint'ы недоступны")
+		return
+	}
+
+	if adminToken != "" {
+		logger.InfoLogger.Println("🔑 Админ-доступ по токену активирован")
+	}
+	if isBasicAuthConfigured() {
+		logger.InfoLogger.Println("🔑 Админ-доступ по логину и паролю (Basic Auth) активирован")
+	}
+}
+
+// Настроен ли хотя бы один способ входа администратора
+func isAdminConfigured() bool {
+	return adminToken != "" || isBasicAuthConfigured()
+}
+
+// Basic Auth включается только парой логин + пароль
+func isBasicAuthConfigured() bool {
+	return adminUser != "" && adminPass != ""
+}
+
+// MIDDLEWARE: Проверка токена администратора
+func adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := getIP(r)
+
+		// Без настроенного доступа служебные endpoint'ы закрыты полностью
+		if !isAdminConfigured() {
+			logSecurityEvent("ADMIN_DISABLED", ip, r.URL.Path)
+			http.Error(w, "Админ-доступ не настроен", http.StatusForbidden)
+			return
+		}
+
+		// IP, заблокированный за подбор пароля (или другим правилом), не проверяем вовсе:
+		// иначе перебор продолжался бы и во время блокировки
+		limited := !securityDisabled && !isTrusted(ip)
+		if limited {
+			if entry, blocked := getBlock(ip); blocked {
+				logSecurityEventWithReason("BLOCKED_ACCESS", ip, r.URL.Path, entry.Reason)
+				rateLimitRejections.WithLabelValues(entry.Reason).Inc()
+				http.Error(w, "Доступ временно заблокирован", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		if !isAdminRequest(r) {
+			logSecurityEvent("ADMIN_UNAUTHORIZED", ip, r.URL.Path)
+			if limited && recordAdminAuthFailure(ip) {
+				blockIP(ip, blockReasonAdminAuth)
+				logSecurityEventWithReason("ADMIN_BRUTE_FORCE_BLOCKED", ip, r.URL.Path, blockReasonAdminAuth)
+				rateLimitRejections.WithLabelValues(blockReasonAdminAuth).Inc()
+				http.Error(w, "Слишком много неудачных попыток входа. Попробуйте позже.", http.StatusTooManyRequests)
+				return
+			}
+			// Заголовок заставляет браузер показать окно ввода логина и пароля
+			if isBasicAuthConfigured() {
+				w.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			}
+			http.Error(w, "Требуется авторизация администратора", http.StatusUnauthorized)
+			return
+		}
+
+		resetAdminAuthFailures(ip)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ФУНКЦИЯ: recordAdminAuthFailure
+// НАЗНАЧЕНИЕ: Учитывает неудачную попытку входа с IP; true — лимит попыток исчерпан
+func recordAdminAuthFailure(ip string) bool {
+	if adminMaxAuthFailures == 0 {
+		return false
+	}
+
+	adminAuthMutex.Lock()
+	defer adminAuthMutex.Unlock()
+
+	now := securityClock.Now()
+	failure, exists := adminAuthFailures[ip]
+	if !exists || now.Sub(failure.First) > adminAuthFailureWindow {
+		failure = adminAuthFailure{First: now}
+	}
+	failure.Count++
+
+	// После блокировки счёт начинается заново: следующая попытка придёт только после её истечения
+	if failure.Count >= adminMaxAuthFailures {
+		delete(adminAuthFailures, ip)
+		return true
+	}
+	adminAuthFailures[ip] = failure
+	return false
+}
+
+// Успешный вход сбрасывает счётчик неудачных попыток
+func resetAdminAuthFailures(ip string) {
+	adminAuthMutex.Lock()
+	defer adminAuthMutex.Unlock()
+	delete(adminAuthFailures, ip)
+}
+
+// Удаляем попытки, окно которых уже закрылось (вызывается из cleanupSecurityState)
+func cleanupAdminAuthFailures(now time.Time) {
+	adminAuthMutex.Lock()
+	defer adminAuthMutex.Unlock()
+
+	for ip, failure := range adminAuthFailures {
+		if now.Sub(failure.First) > adminAuthFailureWindow {
+			delete(adminAuthFailures, ip)
+		}
+	}
+}
+
+// Проверяем, передан ли корректный токен или логин/пароль администратора
+func isAdminRequest(r *http.Request) bool {
+	if adminToken != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			// Сравнение за постоянное время защищает от атак по времени
+			return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+		}
+	}
+
+	if isBasicAuthConfigured() {
+		if user, pass, ok := r.BasicAuth(); ok {
+			// Сравниваем оба поля всегда, чтобы время ответа не выдавало верный логин
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(adminUser))
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(adminPass))
+			return userOK&passOK == 1
+		}
+	}
+
+	return false
+}
+
+// ФУНКЦИЯ: registerAdminHandlers
+// НАЗНАЧЕНИЕ: Регистрирует служебные endpoint'ы /admin/...
+// Они не проходят через securityMiddleware: путь /admin считается подозрительным.
+// Подбор пароля ограничивает adminMiddleware (см. recordAdminAuthFailure)
+func registerAdminHandlers() {
+	appMux.Handle("/admin/blocked", adminMiddleware(http.HandlerFunc(blockedIPsHandler)))
+	appMux.Handle("/admin/config", adminMiddleware(http.HandlerFunc(limiterConfigHandler)))
+	appMux.Handle("GET /health/full", adminMiddleware(http.HandlerFunc(fullHealthHandler)))
+	appMux.Handle("GET /admin/alerts", adminMiddleware(http.HandlerFunc(alertAuditHandler)))
+	appMux.Handle("GET /admin/security/summary", adminMiddleware(http.HandlerFunc(securitySummaryHandler)))
+
+	// Другие методы на тех же путях иначе ушли бы в запасной маршрут "/",
+	// где правило подозрительного пути /admin заблокировало бы IP администратора
+	appMux.Handle("/health/full", adminMiddleware(http.HandlerFunc(methodNotAllowedHandler)))
+	appMux.Handle("/admin/alerts", adminMiddleware(http.HandlerFunc(methodNotAllowedHandler)))
+	appMux.Handle("/admin/security/summary", adminMiddleware(http.HandlerFunc(methodNotAllowedHandler)))
+}
+
+// СТРУКТУРА ЗАПИСИ В СПИСКЕ БЛОКИРОВОК
+type blockedIPInfo struct {
+	IP        string    `json:"ip"`
+	Reason    string    `json:"reason"`
+	BlockedAt time.Time `json:"blocked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ОБРАБОТЧИК: GET /admin/blocked
+// Список действующих блокировок IP с причинами
+func blockedIPsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	countMutex.Lock()
+	blocked := make([]blockedIPInfo, 0, len(blockedIPs))
+	for ip, entry := range blockedIPs {
+		expiresAt := entry.expiresAt()
+		if securityClock.Now().After(expiresAt) {
+			continue // Блокировка истекла, но ещё не удалена очисткой
+		}
+		blocked = append(blocked, blockedIPInfo{
+			IP:        ip,
+			Reason:    entry.Reason,
+			BlockedAt: entry.BlockedAt,
+			ExpiresAt: expiresAt,
+		})
+	}
+	countMutex.Unlock()
+
+	// Свежие блокировки первыми
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].BlockedAt.After(blocked[j].BlockedAt)
+	})
+
+	writeJSON(w, r, http.StatusOK, blocked)
+}
+
+// СТРУКТУРА НАСТРОЕК RATE LIMITER
+// В запросе поля необязательны: меняются только переданные
+type limiterConfig struct {
+	RequestLimit         *int `json:"request_limit"`
+	BlockDurationMinutes *int `json:"block_duration_minutes"`
+}
+
+// ФУНКЦИЯ: limiterConfigHandler
+// НАЗНАЧЕНИЕ: Меняет лимиты rate limiter на лету (POST /admin/config)
+// и возвращает действующие значения; GET просто показывает текущие
+func limiterConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var update limiterConfig
+		if err := decodeJSONBody(w, r, &update); err != nil {
+			writeDecodeError(w, r, err)
+			return
+		}
+
+		var errs []FieldError
+		if update.RequestLimit == nil && update.BlockDurationMinutes == nil {
+			errs = append(errs, FieldError{Field: "request_limit", Code: ValidationCodeRequired})
+		}
+		if update.RequestLimit != nil && *update.RequestLimit <= 0 {
+			errs = append(errs, FieldError{Field: "request_limit", Code: ValidationCodePositive})
+		}
+		if update.BlockDurationMinutes != nil && *update.BlockDurationMinutes <= 0 {
+			errs = append(errs, FieldError{Field: "block_duration_minutes", Code: ValidationCodePositive})
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, r, errs)
+			return
+		}
+
+		countMutex.Lock()
+		if update.RequestLimit != nil {
+			requestLimit = *update.RequestLimit
+		}
+		if update.BlockDurationMinutes != nil {
+			blockDuration = time.Duration(*update.BlockDurationMinutes) * time.Minute
+		}
+		countMutex.Unlock()
+
+		logSecurityEvent("ADMIN_LIMITER_CONFIG_CHANGED", getIP(r), r.URL.Path)
+	}
+
+	countMutex.Lock()
+	limit, minutes := requestLimit, int(blockDuration/time.Minute)
+	countMutex.Unlock()
+
+	writeJSON(w, r, http.StatusOK, limiterConfig{RequestLimit: &limit, BlockDurationMinutes: &minutes})
+}
+
+// ФУНКЦИЯ: registerPprofHandlers
+// НАЗНАЧЕНИЕ: Регистрирует /debug/pprof/ за токеном администратора
+func registerPprofHandlers() {
+	if !getEnvBool("ENABLE_PPROF", false) {
+		return
+	}
+
+	pprofMux := http.NewServeMux()
+	pprofMux.HandleFunc("/debug/pprof/", pprof.Index)
+	pprofMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	pprofMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	pprofMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	pprofMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// Без securityMiddleware: долгий CPU-профиль не должен попадать под rate limiting
+	appMux.Handle("/debug/pprof/", adminMiddleware(pprofWriteDeadline(pprofMux)))
+	logger.InfoLogger.Println("🩺 Endpoint /debug/pprof/ зарегистрирован (только для администратора)")
+}
+
+// Длительность по умолчанию, как в net/http/pprof: CPU-профиль 30 секунд, трасса — 1
+const (
+	defaultPprofProfileSeconds = 30
+	defaultPprofTraceSeconds   = 1
+)
+
+// ФУНКЦИЯ: pprofWriteDeadline
+// НАЗНАЧЕНИЕ: Продлевает таймаут записи на время снятия профиля
+// /debug/pprof/profile и /debug/pprof/trace отвечают только через seconds секунд,
+// и WriteTimeout сервера оборвал бы соединение раньше
+func pprofWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var seconds float64
+		switch r.URL.Path {
+		case "/debug/pprof/profile":
+			seconds = defaultPprofProfileSeconds
+		case "/debug/pprof/trace":
+			seconds = defaultPprofTraceSeconds
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if value, err := strconv.ParseFloat(r.URL.Query().Get("seconds"), 64); err == nil && value > 0 {
+			seconds = value
+		}
+
+		// Запас сверху — обычный WriteTimeout сервера на отправку самого профиля
+		server, ok := r.Context().Value(http.ServerContextKey).(*http.Server)
+		if ok && server.WriteTimeout > 0 {
+			deadline := time.Now().Add(server.WriteTimeout + time.Duration(seconds*float64(time.Second)))
+			if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.LogError(err, "Не удалось продлить таймаут записи для pprof")
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
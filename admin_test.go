@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Проверка токена администратора
+func TestAdminMiddleware(t *testing.T) {
+	// Пишем события безопасности в никуда, чтобы не трогать security.log
+	securityLogger = log.New(io.Discard, "", 0)
+	handler := adminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer func(previous string) { adminToken = previous }(adminToken)
+
+	cases := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{"токен не настроен", "", "Bearer secret", http.StatusForbidden},
+		{"без заголовка", "secret", "", http.StatusUnauthorized},
+		{"неверный токен", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"верный токен", "secret", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		adminToken = tc.token
+
+		req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, recorder.Code)
+		}
+	}
+}
+
+// ТЕСТ: Basic Auth как альтернатива токену
+func TestAdminMiddlewareBasicAuth(t *testing.T) {
+	securityLogger = log.New(io.Discard, "", 0)
+	handler := adminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer func(token, user, pass string) { adminToken, adminUser, adminPass = token, user, pass }(adminToken, adminUser, adminPass)
+	adminToken, adminUser, adminPass = "", "ops", "s3cret"
+
+	cases := []struct {
+		name   string
+		user   string
+		pass   string
+		status int
+	}{
+		{"верный логин и пароль", "ops", "s3cret", http.StatusOK},
+		{"неверный пароль", "ops", "wrong", http.StatusUnauthorized},
+		{"неверный логин", "root", "s3cret", http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/admin/blocked", nil)
+		req.SetBasicAuth(tc.user, tc.pass)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, recorder.Code)
+		}
+	}
+
+	// Без авторизации браузер должен получить приглашение ввести пароль
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/blocked", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("WWW-Authenticate"), "Basic ") {
+		t.Errorf("Expected WWW-Authenticate Basic challenge, got %q", recorder.Header().Get("WWW-Authenticate"))
+	}
+}
+
+// ТЕСТ: Подбор пароля блокирует IP, успешный вход и истёкшее окно сбрасывают счётчик
+func TestAdminMiddlewareBruteForce(t *testing.T) {
+	securityLogger = log.New(io.Discard, "", 0)
+	clock := useFakeSecurityClock(t)
+	handler := adminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer func(user, pass string, max int, window time.Duration) {
+		adminUser, adminPass, adminMaxAuthFailures, adminAuthFailureWindow = user, pass, max, window
+	}(adminUser, adminPass, adminMaxAuthFailures, adminAuthFailureWindow)
+	adminUser, adminPass = "ops", "s3cret"
+	adminMaxAuthFailures, adminAuthFailureWindow = 3, time.Minute
+
+	login := func(ip, pass string) int {
+		req := httptest.NewRequest("GET", "/admin/blocked", nil)
+		req.RemoteAddr = ip + ":5000"
+		req.SetBasicAuth("ops", pass)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Две ошибки, успешный вход, снова две ошибки — лимит не исчерпан
+	for _, pass := range []string{"a", "b", "s3cret", "c", "d"} {
+		login("198.51.100.20", pass)
+	}
+	if isBlocked("198.51.100.20") {
+		t.Fatalf("Expected successful login to reset the failure counter")
+	}
+
+	// Ошибки в разных окнах не складываются
+	clock.Advance(2 * time.Minute)
+	login("198.51.100.20", "e")
+	login("198.51.100.20", "f")
+	if isBlocked("198.51.100.20") {
+		t.Fatalf("Expected failures from an expired window to be forgotten")
+	}
+
+	// Третья ошибка в окне блокирует IP, дальше отказ даже с верным паролем
+	if code := login("198.51.100.20", "g"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d on the last allowed failure, got %d", http.StatusTooManyRequests, code)
+	}
+	if entry, blocked := getBlock("198.51.100.20"); !blocked || entry.Reason != blockReasonAdminAuth {
+		t.Errorf("Expected IP blocked with reason %q, got %+v (blocked=%v)", blockReasonAdminAuth, entry, blocked)
+	}
+	if code := login("198.51.100.20", "s3cret"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected blocked IP to get status %d with valid credentials, got %d", http.StatusTooManyRequests, code)
+	}
+
+	// Другие IP и доверенные адреса не затронуты
+	if code := login("198.51.100.21", "s3cret"); code != http.StatusOK {
+		t.Errorf("Expected another IP to log in, got status %d", code)
+	}
+	for range 5 {
+		login("127.0.0.1", "wrong")
+	}
+	if isBlocked("127.0.0.1") {
+		t.Errorf("Expected trusted IP not to be blocked for failed logins")
+	}
+
+	// После блокировки снова можно войти
+	clock.Advance(blockDuration + time.Second)
+	if code := login("198.51.100.20", "s3cret"); code != http.StatusOK {
+		t.Errorf("Expected login after the block expires, got status %d", code)
+	}
+}
+
+// ТЕСТ: Изменение created_at без токена администратора запрещено
+func TestAdminPatchGoalForbidden(t *testing.T) {
+	securityLogger = log.New(io.Discard, "", 0)
+	defer func(previous string) { adminToken = previous }(adminToken)
+	adminToken = "secret"
+
+	req := httptest.NewRequest("PATCH", "/goals/1", strings.NewReader(`{"created_at": "2020-01-01T00:00:00Z"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	adminPatchGoalHandler(recorder, req)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+}
+
+// ТЕСТ: Неверный метод на служебном пути даёт 405, а не блокировку IP
+func TestAdminRoutesWrongMethod(t *testing.T) {
+	newTestServer(t)
+	registerAdminHandlers()
+	useFakeSecurityClock(t)
+	securityLogger = log.New(io.Discard, "", 0)
+	defer func(previous string) { adminToken = previous }(adminToken)
+	adminToken = "secret"
+
+	for _, path := range []string{"/admin/alerts", "/admin/security/summary", "/health/full"} {
+		for _, method := range []string{"POST", "DELETE"} {
+			req := httptest.NewRequest(method, path, nil)
+			req.RemoteAddr = "198.51.100.40:12345"
+			req.Header.Set("Authorization", "Bearer secret")
+			recorder := httptest.NewRecorder()
+			appMux.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: expected status %d, got %d", method, path, http.StatusMethodNotAllowed, recorder.Code)
+			}
+		}
+	}
+
+	// Без токена — 401 от adminMiddleware, но тоже без блокировки по пути
+	req := httptest.NewRequest("POST", "/admin/alerts", nil)
+	req.RemoteAddr = "198.51.100.40:12345"
+	recorder := httptest.NewRecorder()
+	appMux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+
+	if isBlocked("198.51.100.40") {
+		t.Errorf("Expected admin IP not to be blocked after wrong-method requests")
+	}
+}
+
+// ТЕСТ: Список блокировок содержит причину
+func TestBlockedIPsHandlerIncludesReason(t *testing.T) {
+	blockIP("203.0.113.7", blockReasonSuspiciousPath)
+	defer func() {
+		countMutex.Lock()
+		delete(blockedIPs, "203.0.113.7")
+		countMutex.Unlock()
+	}()
+
+	recorder := httptest.NewRecorder()
+	blockedIPsHandler(recorder, httptest.NewRequest("GET", "/admin/blocked", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var blocked []blockedIPInfo
+	if err := json.Unmarshal(recorder.Body.Bytes(), &blocked); err != nil {
+		t.Fatalf("Failed to parse blocked list: %v", err)
+	}
+
+	found := false
+	for _, b := range blocked {
+		if b.IP == "203.0.113.7" {
+			found = true
+			if b.Reason != blockReasonSuspiciousPath {
+				t.Errorf("Expected reason %q, got %q", blockReasonSuspiciousPath, b.Reason)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected 203.0.113.7 in blocked list, got %v", blocked)
+	}
+}
+
+// ТЕСТ: Изменение лимитов через /admin/config
+func TestLimiterConfigHandler(t *testing.T) {
+	securityLogger = log.New(io.Discard, "", 0)
+
+	countMutex.Lock()
+	previousLimit, previousDuration := requestLimit, blockDuration
+	countMutex.Unlock()
+	defer func() {
+		countMutex.Lock()
+		requestLimit, blockDuration = previousLimit, previousDuration
+		countMutex.Unlock()
+	}()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		limiterConfigHandler(recorder, req)
+		return recorder
+	}
+
+	recorder := post(`{"request_limit": 500, "block_duration_minutes": 15}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var config map[string]int
+	if err := json.Unmarshal(recorder.Body.Bytes(), &config); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if config["request_limit"] != 500 || config["block_duration_minutes"] != 15 {
+		t.Errorf("Expected new config in response, got %v", config)
+	}
+	if currentRequestLimit() != 500 {
+		t.Errorf("Expected live request limit 500, got %d", currentRequestLimit())
+	}
+
+	for _, body := range []string{`{"request_limit": 0}`, `{"block_duration_minutes": -5}`, `{}`} {
+		if recorder := post(body); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusUnprocessableEntity, recorder.Code)
+		}
+	}
+	if currentRequestLimit() != 500 {
+		t.Errorf("Invalid updates must not change the limit, got %d", currentRequestLimit())
+	}
+}
+
+// ТЕСТ: Трасса дольше WriteTimeout доходит до клиента целиком
+func TestPprofWriteDeadline(t *testing.T) {
+	handler := pprofWriteDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond) // Имитация снятия трассы
+		w.Write([]byte("trace"))
+	}))
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/trace?seconds=0.3")
+	if err != nil {
+		t.Fatalf("Expected trace response, got error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "trace" {
+		t.Errorf("Expected body %q, got %q (err %v)", "trace", body, err)
+	}
+}
@@ -0,0 +1,244 @@
+// ФАЙЛ: alertaudit.go
+// НАЗНАЧЕНИЕ: Журнал доставки алертов (таблица alerts_sent)
+// ОСОБЕННОСТИ:
+//   - ALERT_AUDIT=true включает журнал; ALERT_AUDIT_DATABASE_URL задаёт отдельную базу,
+//     без неё журнал пишется в основную
+//   - Запись делается после доставки в канал (успешной или после всех повторов)
+//     через очередь и фоновую горутину, поэтому медленная база не задерживает алерты
+//   - При переполненной очереди запись теряется с предупреждением в логе
+//   - GET /admin/alerts — история доставок, новые сверху (?limit=, ?offset=)
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Таблица журнала (создаётся при запуске, если её нет)
+const alertAuditTable = "alerts_sent"
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ЖУРНАЛА
+var (
+	// Пул базы журнала (nil — журнал выключен); может совпадать с dbPool
+	alertAuditPool *pgxpool.Pool
+	// Очередь записей для фоновой горутины
+	alertAuditQueue chan alertDelivery
+	// Размер очереди: столько доставок может ждать записи одновременно
+	alertAuditQueueSize = 256
+)
+
+// Размер страницы GET /admin/alerts
+const (
+	defaultAlertAuditPage = 50
+	maxAlertAuditPage     = 500
+)
+
+// СТРУКТУРА ЗАПИСИ О ДОСТАВКЕ
+type alertDelivery struct {
+	ID         int64     `json:"id"`
+	Channel    string    `json:"channel"`
+	Context    string    `json:"context"`
+	IP         string    `json:"ip"`
+	Count      int       `json:"count"`
+	Severity   string    `json:"severity"`
+	AlertedAt  time.Time `json:"alerted_at"`
+	Delivered  bool      `json:"delivered"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ИНИЦИАЛИЗАЦИЯ ЖУРНАЛА
+// Недоступная база журнала не останавливает сервер: алерты отправляются без записи
+func initAlertAudit() {
+	if !getEnvBool("ALERT_AUDIT", false) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, store := dbPool, "основная база"
+	if url := strings.TrimSpace(os.Getenv("ALERT_AUDIT_DATABASE_URL")); url != "" {
+		var err error
+		pool, err = newDBPool(ctx, url)
+		if err != nil {
+			logger.LogError(err, "⚠️ Не удалось подключиться к ALERT_AUDIT_DATABASE_URL, журнал алертов выключен")
+			return
+		}
+		store = maskDBURL(url)
+	}
+
+	if err := createAlertAuditTable(ctx, pool); err != nil {
+		logger.LogError(err, "⚠️ Не удалось создать таблицу "+alertAuditTable+", журнал алертов выключен")
+		if pool != dbPool {
+			pool.Close()
+		}
+		return
+	}
+
+	alertAuditPool = pool
+	alertAuditQueue = make(chan alertDelivery, alertAuditQueueSize)
+	startBackground("alert-audit", writeAlertAudit)
+	logger.InfoLogger.Printf("🗒️ Журнал доставки алертов: %s (%s)", alertAuditTable, store)
+}
+
+// ФУНКЦИЯ: createAlertAuditTable
+// НАЗНАЧЕНИЕ: Создаёт таблицу журнала; миграции сюда не подходят, база может быть отдельной
+func createAlertAuditTable(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+alertAuditTable+` (
+		id BIGSERIAL PRIMARY KEY,
+		channel TEXT NOT NULL,
+		context TEXT NOT NULL,
+		ip TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		severity TEXT NOT NULL,
+		alerted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		delivered BOOLEAN NOT NULL,
+		attempts INTEGER NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// ФУНКЦИЯ: recordAlertDelivery
+// НАЗНАЧЕНИЕ: Ставит запись о доставке в очередь журнала (не блокируется)
+func recordAlertDelivery(channel string, alert Alert, attempts int, err error) {
+	queue := alertAuditQueue
+	if queue == nil {
+		return
+	}
+
+	delivery := alertDelivery{
+		Channel:   channel,
+		Context:   alert.Context,
+		IP:        alert.IP,
+		Count:     alert.Count,
+		Severity:  alert.Severity,
+		AlertedAt: alert.Timestamp,
+		Delivered: err == nil,
+		Attempts:  attempts,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	select {
+	case queue <- delivery:
+	default:
+		logger.InfoLogger.Printf("⚠️ Очередь журнала алертов переполнена, запись о доставке (%s) потеряна", channel)
+	}
+}
+
+// Фоновая запись очереди в базу журнала
+func writeAlertAudit(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-alertAuditQueue:
+			if err := insertAlertDelivery(ctx, alertAuditPool, delivery); err != nil {
+				logger.LogError(err, "Ошибка записи в журнал алертов ("+delivery.Channel+")")
+			}
+		}
+	}
+}
+
+// Вставка одной записи журнала
+func insertAlertDelivery(ctx context.Context, pool *pgxpool.Pool, d alertDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := pool.Exec(ctx, `INSERT INTO `+alertAuditTable+`
+		(channel, context, ip, count, severity, alerted_at, delivered, attempts, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		d.Channel, d.Context, d.IP, d.Count, d.Severity, d.AlertedAt, d.Delivered, d.Attempts, d.Error)
+	return err
+}
+
+// ФУНКЦИЯ: listAlertDeliveries
+// НАЗНАЧЕНИЕ: Страница журнала, новые записи сверху
+func listAlertDeliveries(ctx context.Context, pool *pgxpool.Pool, limit, offset int) ([]alertDelivery, error) {
+	rows, err := pool.Query(ctx, `SELECT id, channel, context, ip, count, severity, alerted_at,
+		delivered, attempts, error, recorded_at FROM `+alertAuditTable+`
+		ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []alertDelivery{}
+	for rows.Next() {
+		var d alertDelivery
+		if err := rows.Scan(&d.ID, &d.Channel, &d.Context, &d.IP, &d.Count, &d.Severity, &d.AlertedAt,
+			&d.Delivered, &d.Attempts, &d.Error, &d.RecordedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ФУНКЦИЯ: parsePageParams
+// НАЗНАЧЕНИЕ: Читает ?limit= и ?offset=; ok=false — некорректное значение
+func parsePageParams(r *http.Request, defaultLimit, maxLimit int) (limit, offset int, ok bool) {
+	limit, offset = defaultLimit, 0
+	query := r.URL.Query()
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return 0, 0, false
+		}
+		limit = min(parsed, maxLimit)
+	}
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return limit, offset, true
+}
+
+// ОБРАБОТЧИК: GET /admin/alerts
+// История доставки алертов с постраничным выводом
+func alertAuditHandler(w http.ResponseWriter, r *http.Request) {
+	pool := alertAuditPool
+	if pool == nil {
+		http.Error(w, "Журнал алертов выключен (ALERT_AUDIT)", http.StatusNotFound)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
+		return
+	}
+
+	limit, offset, ok := parsePageParams(r, defaultAlertAuditPage, maxAlertAuditPage)
+	if !ok {
+		http.Error(w, "limit должен быть положительным числом, offset — неотрицательным", http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	deliveries, err := listAlertDeliveries(ctx, pool, limit, offset)
+	if err != nil {
+		logger.LogError(err, "Ошибка чтения журнала алертов в alertAuditHandler")
+		writeDBError(w, r, err, "Ошибка чтения журнала алертов")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"alerts": deliveries,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
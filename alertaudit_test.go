@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Итог доставки попадает в очередь журнала, токен Telegram в ошибку не попадает
+func TestSendWithRetryRecordsDelivery(t *testing.T) {
+	defer func(queue chan alertDelivery, api string, backoff time.Duration) {
+		alertAuditQueue, telegramAPIURL, alertRetryBackoff = queue, api, backoff
+	}(alertAuditQueue, telegramAPIURL, alertRetryBackoff)
+	alertAuditQueue = make(chan alertDelivery, 2)
+	alertRetryBackoff = time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	alert := Alert{Severity: alertSeverityWarning, Context: "GET /goals", IP: "203.0.113.7", Count: 6, Timestamp: time.Now()}
+
+	sendWithRetry(&webhookAlerter{url: server.URL}, alert)
+	server.Close()
+
+	// Сервер закрыт: соединение не установится, адрес с токеном не должен попасть в журнал
+	telegramAPIURL = server.URL
+	sendWithRetry(&telegramAlerter{token: "secret-token", chatID: "42"}, alert)
+
+	delivered := <-alertAuditQueue
+	if delivered.Channel != "webhook" || !delivered.Delivered || delivered.Attempts != 1 || delivered.IP != alert.IP || delivered.Count != 6 {
+		t.Errorf("Unexpected delivered record: %+v", delivered)
+	}
+	failed := <-alertAuditQueue
+	if failed.Channel != "telegram:42" || failed.Delivered || failed.Attempts != alertRetryAttempts || failed.Error == "" {
+		t.Errorf("Unexpected failed record: %+v", failed)
+	}
+	if strings.Contains(failed.Error, "secret-token") {
+		t.Errorf("Expected bot token to be stripped from error, got %q", failed.Error)
+	}
+}
+
+// ТЕСТ: Переполненная очередь не блокирует доставку алертов
+func TestRecordAlertDeliveryQueueFull(t *testing.T) {
+	defer func(queue chan alertDelivery) { alertAuditQueue = queue }(alertAuditQueue)
+	alertAuditQueue = make(chan alertDelivery, 1)
+
+	done := make(chan struct{})
+	go func() {
+		recordAlertDelivery("webhook", Alert{}, 1, nil)
+		recordAlertDelivery("webhook", Alert{}, 1, nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected recordAlertDelivery not to block on a full queue")
+	}
+	if len(alertAuditQueue) != 1 {
+		t.Errorf("Expected 1 queued record, got %d", len(alertAuditQueue))
+	}
+}
+
+// ТЕСТ: Разбор ?limit= и ?offset= для GET /admin/alerts
+func TestParsePageParams(t *testing.T) {
+	cases := []struct {
+		query  string
+		limit  int
+		offset int
+		ok     bool
+	}{
+		{"", 50, 0, true},
+		{"?limit=10&offset=20", 10, 20, true},
+		{"?limit=100000", 500, 0, true},
+		{"?limit=0", 0, 0, false},
+		{"?offset=-1", 0, 0, false},
+		{"?limit=abc", 0, 0, false},
+	}
+
+	for _, tc := range cases {
+		limit, offset, ok := parsePageParams(httptest.NewRequest("GET", "/admin/alerts"+tc.query, nil), defaultAlertAuditPage, maxAlertAuditPage)
+		if limit != tc.limit || offset != tc.offset || ok != tc.ok {
+			t.Errorf("%q: expected (%d, %d, %v), got (%d, %d, %v)", tc.query, tc.limit, tc.offset, tc.ok, limit, offset, ok)
+		}
+	}
+}
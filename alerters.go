@@ -0,0 +1,281 @@
+// ФАЙЛ: alerters.go
+// НАЗНАЧЕНИЕ: Каналы доставки алертов (Telegram, произвольный webhook)
+// ОСОБЕННОСТИ:
+//   - Все каналы реализуют интерфейс Alerter, алерт уходит во все настроенные
+//   - Доставка в фоне с повторами и экспоненциальной задержкой,
+//     чтобы недоступный канал не задерживал обработку запроса
+//   - Webhook получает JSON (severity, context, ip, count, timestamp) и заголовки
+//     авторизации из ALERT_WEBHOOK_HEADERS (PagerDuty, внутренние сервисы и т.п.)
+//   - Итог доставки в каждый канал попадает в журнал alerts_sent (см. alertaudit.go)
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// УРОВНИ ВАЖНОСТИ АЛЕРТА
+const (
+	alertSeverityWarning  = "warning"  // Много ошибок от IP
+	alertSeverityCritical = "critical" // Паника в обработчике или общий всплеск ошибок
+)
+
+// СТРУКТУРА АЛЕРТА
+type Alert struct {
+	Severity  string    `json:"severity"`
+	Context   string    `json:"context"`
+	IP        string    `json:"ip"`
+	Count     int       `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ИНТЕРФЕЙС КАНАЛА ДОСТАВКИ
+type Alerter interface {
+	// Name — название канала для логов
+	Name() string
+	// Send отправляет алерт; ошибка означает, что попытку можно повторить
+	Send(ctx context.Context, alert Alert) error
+}
+
+// НАСТРОЙКИ ДОСТАВКИ
+var (
+	// Настроенные каналы (заполняются в initAlerts)
+	alerters []Alerter
+	// Количество попыток доставки в каждый канал
+	alertRetryAttempts = 3
+	// Задержка перед второй попыткой, дальше удваивается
+	alertRetryBackoff = 1 * time.Second
+	// HTTP-клиент для каналов: без таймаута зависший endpoint держал бы горутину вечно
+	alertHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// ФУНКЦИЯ: configuredAlerters
+// НАЗНАЧЕНИЕ: Собирает каналы доставки из переменных окружения
+func configuredAlerters() []Alerter {
+	var configured []Alerter
+
+	// TELEGRAM_CHAT_ID может содержать несколько чатов через запятую (например, dev и ops).
+	// Каждый чат — отдельный канал: свои повторы и свой результат в логах,
+	// поэтому сбой доставки в один чат не мешает остальным
+	token, chatIDs := os.Getenv("TELEGRAM_BOT_TOKEN"), parseChatIDs(os.Getenv("TELEGRAM_CHAT_ID"))
+	switch {
+	case token != "" && len(chatIDs) > 0:
+		for _, chatID := range chatIDs {
+			configured = append(configured, &telegramAlerter{token: token, chatID: chatID})
+		}
+	case token != "" || len(chatIDs) > 0:
+		logger.InfoLogger.Println("⚠️ Для Telegram нужны оба параметра TELEGRAM_BOT_TOKEN и TELEGRAM_CHAT_ID")
+	}
+
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		configured = append(configured, &webhookAlerter{
+			url:     url,
+			headers: parseWebhookHeaders(os.Getenv("ALERT_WEBHOOK_HEADERS")),
+		})
+	}
+
+	return configured
+}
+
+// ФУНКЦИЯ: parseChatIDs
+// НАЗНАЧЕНИЕ: Разбирает список чатов через запятую, пустые и повторяющиеся пропускает
+func parseChatIDs(raw string) []string {
+	var chatIDs []string
+	seen := map[string]bool{}
+	for _, chatID := range strings.Split(raw, ",") {
+		chatID = strings.TrimSpace(chatID)
+		if chatID == "" || seen[chatID] {
+			continue
+		}
+		seen[chatID] = true
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs
+}
+
+// ФУНКЦИЯ: alertsEnabled
+// НАЗНАЧЕНИЕ: Настроен ли хотя бы один канал доставки
+func alertsEnabled() bool {
+	return len(alerters) > 0
+}
+
+// ФУНКЦИЯ: alerterNames
+// НАЗНАЧЕНИЕ: Названия настроенных каналов (для логов и сводки конфигурации)
+func alerterNames() []string {
+	names := make([]string, 0, len(alerters))
+	for _, alerter := range alerters {
+		names = append(names, alerter.Name())
+	}
+	return names
+}
+
+// ФУНКЦИЯ: dispatchAlert
+// НАЗНАЧЕНИЕ: Отправляет алерт во все каналы в фоне
+func dispatchAlert(alert Alert) {
+	for _, alerter := range alerters {
+		go sendWithRetry(alerter, alert)
+	}
+}
+
+// ФУНКЦИЯ: sendWithRetry
+// НАЗНАЧЕНИЕ: Доставляет алерт в канал, повторяя попытки с удвоением задержки
+func sendWithRetry(alerter Alerter, alert Alert) error {
+	backoff := alertRetryBackoff
+	var err error
+	for attempt := 1; attempt <= alertRetryAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), alertHTTPClient.Timeout)
+		err = withoutRequestURL(alerter.Send(ctx, alert))
+		cancel()
+		if err == nil {
+			logger.InfoLogger.Printf("✅ Алерт отправлен (%s) для IP: %s", alerter.Name(), alert.IP)
+			recordAlertDelivery(alerter.Name(), alert, attempt, nil)
+			return nil
+		}
+
+		logger.InfoLogger.Printf("⚠️ Попытка %d/%d отправки алерта (%s) не удалась: %v",
+			attempt, alertRetryAttempts, alerter.Name(), err)
+		if attempt < alertRetryAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	logger.LogError(err, "Ошибка отправки алерта ("+alerter.Name()+")")
+	recordAlertDelivery(alerter.Name(), alert, alertRetryAttempts, err)
+	return err
+}
+
+// ФУНКЦИЯ: withoutRequestURL
+// НАЗНАЧЕНИЕ: Убирает адрес запроса из ошибки HTTP-клиента
+// В *url.Error есть адрес, а в адресе Telegram — токен бота; наружу (логи, журнал) идёт только причина
+func withoutRequestURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// ФУНКЦИЯ: postAlertJSON
+// НАЗНАЧЕНИЕ: POST JSON в канал; статус не 2xx считается ошибкой
+func postAlertJSON(ctx context.Context, url string, payload interface{}, headers map[string]string) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := alertHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("канал ответил статусом %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// КАНАЛ: TELEGRAM
+type telegramAlerter struct {
+	token  string
+	chatID string
+}
+
+// Адрес Telegram Bot API (в тестах подменяется на httptest-сервер)
+var telegramAPIURL = "https://api.telegram.org"
+
+// ИНТЕРФЕЙС ПРОВЕРКИ КАНАЛА
+// Каналы, которые умеют проверить доступность без отправки алерта (см. /health/full)
+type alerterChecker interface {
+	Check(ctx context.Context) error
+}
+
+// Check вызывает getMe: проверяет токен бота и доступность API, ничего не отправляя в чат
+func (t *telegramAlerter) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, telegramAPIURL+"/bot"+t.token+"/getMe", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := alertHTTPClient.Do(req)
+	if err != nil {
+		return withoutRequestURL(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("getMe ответил статусом %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (t *telegramAlerter) Name() string { return "telegram:" + t.chatID }
+
+func (t *telegramAlerter) Send(ctx context.Context, alert Alert) error {
+	// Формируем сообщение
+	message := "🚨 ALERT: High error rate detected!\n" +
+		"Context: " + alert.Context + "\n" +
+		"IP: " + alert.IP + "\n" +
+		"Error count: " + fmt.Sprintf("%d", alert.Count) + "\n" +
+		"Time: " + alert.Timestamp.Format(time.RFC3339)
+
+	// Формируем URL для Telegram API
+	url := telegramAPIURL + "/bot" + t.token + "/sendMessage"
+
+	return postAlertJSON(ctx, url, map[string]string{
+		"chat_id": t.chatID,
+		"text":    message,
+	}, nil)
+}
+
+// КАНАЛ: ПРОИЗВОЛЬНЫЙ WEBHOOK
+type webhookAlerter struct {
+	url     string
+	headers map[string]string
+}
+
+func (h *webhookAlerter) Name() string { return "webhook" }
+
+func (h *webhookAlerter) Send(ctx context.Context, alert Alert) error {
+	return postAlertJSON(ctx, h.url, alert, h.headers)
+}
+
+// ФУНКЦИЯ: parseWebhookHeaders
+// НАЗНАЧЕНИЕ: Разбирает ALERT_WEBHOOK_HEADERS вида "Authorization: Bearer x, X-Routing-Key: y"
+// Значение отделяется по первому ":", поэтому может содержать ":" и "="
+func parseWebhookHeaders(raw string) map[string]string {
+	headers := map[string]string{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			logger.InfoLogger.Printf("⚠️ Некорректная запись в ALERT_WEBHOOK_HEADERS (ожидается \"Имя: значение\")")
+			continue
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return headers
+}
@@ -1,18 +1,22 @@
 // ФАЙЛ: alerts.go
 // НАЗНАЧЕНИЕ: Система алертинга и уведомлений
 // ОСОБЕННОСТИ:
-//   - Отправка уведомлений в Telegram
+//   - Отправка уведомлений в Telegram и произвольный webhook (см. alerters.go)
 //   - Автоматическая блокировка подозрительных IP
 //   - Нормализация IP-адресов для корректного подсчёта ошибок
+//   - Всплеск ошибок сразу от многих IP даёт один общий алерт, а не алерт на каждый IP
+//   - Гистерезис: алерт по IP отправляется один раз и снимается, только когда ошибок
+//     за минуту становится меньше нижнего порога (ALERT_RESET_THRESHOLD)
 
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,29 +28,60 @@ var (
 	errorCounts = make(map[string]int)
 	// Мьютекс для потокобезопасности
 	alertMutex sync.Mutex
-	// Telegram бот токен (из переменных окружения)
-	telegramBotToken string
-	// Telegram чат ID (из переменных окружения)
-	telegramChatID string
-	// Порог ошибок для отправки алерта
-	errorThreshold = 5
+	// Порог ошибок для отправки алерта (ERROR_THRESHOLD)
+	errorThreshold = defaultErrorThreshold
+	// Порог снятия алерта (ALERT_RESET_THRESHOLD): IP остаётся «в алерте»,
+	// пока за минуту от него приходит не меньше стольких ошибок
+	errorResetThreshold = 2
+	// IP, по которым алерт отправлен, а ситуация ещё не разрешилась (под alertMutex)
+	alertingIPs = make(map[string]bool)
+	// Пороги для отдельных контекстов (ALERT_CONTEXT_THRESHOLDS), перекрывают errorThreshold
+	contextErrorThresholds = map[string]int{}
+	// Записывать ли стек паники в файл логов
+	panicStackTraces = true
+
+	// Порог ошибок от всех IP за окно (GLOBAL_ERROR_THRESHOLD), выше — общий алерт
+	globalErrorThreshold = 50
+	// Окно подсчёта общих ошибок (GLOBAL_ERROR_WINDOW)
+	globalErrorWindow = 1 * time.Minute
+	// Состояние текущего окна (под alertMutex)
+	globalWindowStart  time.Time
+	globalWindowCount  int
+	globalWindowIPs    = make(map[string]struct{})
+	globalSpikeAlerted bool
 )
 
+// Контекст общего алерта о всплеске ошибок
+const contextErrorSpike = "SERVICE-WIDE ERROR SPIKE"
+
+// Сколько разных IP должно дать ошибки, чтобы считать всплеск общим:
+// много ошибок от одного-двух IP — это атака, а не отказ сервиса
+const globalSpikeMinIPs = 3
+
+// Сколько IP окна запоминаем (дальше только считаем ошибки), чтобы флуд не раздувал память
+const maxGlobalWindowIPs = 1000
+
 // ИНИЦИАЛИЗАЦИЯ АЛЕРТИНГА
 func initAlerts() {
-	// Получаем данные из переменных окружения
-	telegramBotToken = os.Getenv("TELEGRAM_BOT_TOKEN")
-	telegramChatID = os.Getenv("TELEGRAM_CHAT_ID")
+	panicStackTraces = getEnvBool("PANIC_STACK_TRACES", true)
+	contextErrorThresholds = parseContextThresholds(os.Getenv("ALERT_CONTEXT_THRESHOLDS"))
+	globalErrorThreshold = getEnvInt("GLOBAL_ERROR_THRESHOLD", 50)
+	globalErrorWindow = getEnvDuration("GLOBAL_ERROR_WINDOW", 1*time.Minute)
+	alertMutex.Lock()
+	errorResetThreshold = resetThresholdFor(errorThreshold)
+	alertMutex.Unlock()
 
-	if telegramBotToken == "" || telegramChatID == "" {
-		logger.InfoLogger.Println("⚠️ TELEGRAM_BOT_TOKEN или TELEGRAM_CHAT_ID не заданы, алертинг отключен")
+	// Получаем каналы доставки из переменных окружения
+	alerters = configuredAlerters()
+	if !alertsEnabled() {
+		logger.InfoLogger.Println("⚠️ Ни Telegram, ни ALERT_WEBHOOK_URL не настроены, алертинг отключен")
 		return
 	}
 
-	logger.InfoLogger.Println("🔔 Система алертинга активирована")
+	logger.InfoLogger.Printf("🔔 Система алертинга активирована (каналы: %s)", strings.Join(alerterNames(), ", "))
 
 	// Запускаем фоновый мониторинг
-	go monitorErrors()
+	startBackground("error-monitor", monitorErrors)
 }
 
 // ФУНКЦИЯ: Логирование ошибок с алертингом
@@ -59,8 +94,8 @@ func logErrorWithAlert(errorMsg string, context string, ip string) {
 	// Логируем ошибку
 	logger.InfoLogger.Printf("ALERT: %s | Error: %s | IP: %s", context, errorMsg, normalizedIP)
 
-	// Если Telegram не настроен — выходим
-	if telegramBotToken == "" || telegramChatID == "" {
+	// Если ни один канал не настроен — выходим
+	if !alertsEnabled() {
 		return
 	}
 
@@ -70,83 +105,201 @@ func logErrorWithAlert(errorMsg string, context string, ip string) {
 	currentCount := errorCounts[normalizedIP]
 	// Добавляем DEBUG лог для отладки
 	logger.InfoLogger.Printf("DEBUG: Error count for IP %s = %d", normalizedIP, currentCount)
+	spike, firstInWindow, spikeCount, spikeIPs := recordGlobalError(normalizedIP, time.Now())
+	fire := !spike && startIPAlert(normalizedIP, currentCount, thresholdForContext(context))
 	alertMutex.Unlock()
 
-	// Если превышен порог — отправляем алерт
-	if currentCount >= errorThreshold {
-		sendTelegramAlert(context, normalizedIP, currentCount)
+	// Всплеск от многих IP — скорее отказ сервиса (например, недоступна БД), чем атака:
+	// один общий алерт за окно, без алертов и блокировок по отдельным IP
+	if spike {
+		if firstInWindow {
+			sendAlert(fmt.Sprintf("%s: %d IP", contextErrorSpike, spikeIPs), "*", spikeCount)
+		}
+		return
+	}
+
+	// Порог превышен впервые с момента снятия прошлого алерта — отправляем алерт
+	if fire {
+		sendAlert(context, normalizedIP, currentCount)
 		blockSuspiciousIP(normalizedIP)
 	}
 }
 
-// ФУНКЦИЯ: Отправка алерта в Telegram
-func sendTelegramAlert(context, ip string, count int) {
-	// Формируем сообщение
-	message := "🚨 ALERT: High error rate detected!\n" +
-		"Context: " + context + "\n" +
-		"IP: " + ip + "\n" +
-		"Error count: " + fmt.Sprintf("%d", count) + "\n" +
-		"Time: " + time.Now().Format(time.RFC3339)
-
-	// Формируем URL для Telegram API
-	url := "https://api.telegram.org/bot" + telegramBotToken + "/sendMessage"
-
-	// Подготавливаем данные
-	payload := map[string]string{
-		"chat_id": telegramChatID,
-		"text":    message,
-	}
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		logger.LogError(err, "Ошибка формирования JSON для Telegram алерта")
-		return
+// ФУНКЦИЯ: resetThresholdFor
+// НАЗНАЧЕНИЕ: Порог снятия алерта (ALERT_RESET_THRESHOLD) для порога срабатывания threshold
+// Порог снятия обязан быть ниже порога срабатывания, иначе гистерезиса нет
+func resetThresholdFor(threshold int) int {
+	reset := getEnvInt("ALERT_RESET_THRESHOLD", threshold/2)
+	if reset < 0 || reset >= threshold {
+		logger.InfoLogger.Printf("⚠️ ALERT_RESET_THRESHOLD должен быть от 0 до %d, используем %d", threshold-1, threshold/2)
+		return threshold / 2
 	}
+	return reset
+}
 
-	// Отправляем запрос
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		logger.LogError(err, "Ошибка отправки Telegram алерта")
-		return
+// ФУНКЦИЯ: startIPAlert
+// НАЗНАЧЕНИЕ: Решает, отправлять ли алерт по IP; вызывается под alertMutex
+// Пока IP «в алерте», повторные превышения порога алертов не дают:
+// иначе число ошибок около порога давало бы алерт на каждую ошибку
+func startIPAlert(ip string, count, threshold int) bool {
+	if count < threshold || alertingIPs[ip] {
+		return false
 	}
-	defer resp.Body.Close()
+	alertingIPs[ip] = true
+	return true
+}
 
-	logger.InfoLogger.Printf("✅ Telegram алерт отправлен для IP: %s", ip)
+// ФУНКЦИЯ: rolloverErrorCounts
+// НАЗНАЧЕНИЕ: Закрывает минутное окно подсчёта ошибок по IP; вызывается под alertMutex
+// Алерт по IP снимается, если за окно от него пришло меньше errorResetThreshold ошибок.
+// Возвращает IP, по которым алерт снят
+func rolloverErrorCounts() []string {
+	var resolved []string
+	for ip := range alertingIPs {
+		if errorCounts[ip] < errorResetThreshold {
+			delete(alertingIPs, ip)
+			resolved = append(resolved, ip)
+		}
+	}
+	errorCounts = make(map[string]int)
+	return resolved
+}
+
+// ФУНКЦИЯ: recordGlobalError
+// НАЗНАЧЕНИЕ: Учитывает ошибку в общем окне; вызывается под alertMutex
+// Возвращает, идёт ли всплеск, первый ли это всплеск в окне, а также число ошибок и IP в окне
+func recordGlobalError(ip string, now time.Time) (spike, firstInWindow bool, count, ips int) {
+	if now.Sub(globalWindowStart) >= globalErrorWindow {
+		globalWindowStart = now
+		globalWindowCount = 0
+		globalWindowIPs = make(map[string]struct{})
+		globalSpikeAlerted = false
+	}
+
+	globalWindowCount++
+	if len(globalWindowIPs) < maxGlobalWindowIPs {
+		globalWindowIPs[ip] = struct{}{}
+	}
+
+	spike = globalWindowCount >= globalErrorThreshold && len(globalWindowIPs) >= globalSpikeMinIPs
+	if spike && !globalSpikeAlerted {
+		globalSpikeAlerted = true
+		firstInWindow = true
+	}
+	return spike, firstInWindow, globalWindowCount, len(globalWindowIPs)
+}
+
+// ФУНКЦИЯ: thresholdForContext
+// НАЗНАЧЕНИЕ: Порог ошибок для контекста; если отдельный не задан — общий errorThreshold
+// Подробности после ": " (например, маршрут) не мешают найти порог базового контекста
+func thresholdForContext(context string) int {
+	if threshold, ok := contextErrorThresholds[context]; ok {
+		return threshold
+	}
+	if base, _, found := strings.Cut(context, ": "); found {
+		if threshold, ok := contextErrorThresholds[base]; ok {
+			return threshold
+		}
+	}
+	return errorThreshold
+}
+
+// ФУНКЦИЯ: parseContextThresholds
+// НАЗНАЧЕНИЕ: Разбирает список вида "контекст=порог,контекст=порог"
+// Некорректные записи пропускаются с предупреждением в лог
+func parseContextThresholds(raw string) map[string]int {
+	thresholds := map[string]int{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// Делим по последнему "=", чтобы контекст мог содержать любые символы, кроме запятой
+		separator := strings.LastIndex(entry, "=")
+		if separator <= 0 {
+			logger.InfoLogger.Printf("⚠️ Некорректная запись в ALERT_CONTEXT_THRESHOLDS: %q", entry)
+			continue
+		}
+		threshold, err := strconv.Atoi(strings.TrimSpace(entry[separator+1:]))
+		if err != nil || threshold <= 0 {
+			logger.InfoLogger.Printf("⚠️ Некорректный порог в ALERT_CONTEXT_THRESHOLDS: %q", entry)
+			continue
+		}
+		thresholds[strings.TrimSpace(entry[:separator])] = threshold
+	}
+	return thresholds
+}
+
+// ФУНКЦИЯ: Отправка алерта во все настроенные каналы
+func sendAlert(context, ip string, count int) {
+	severity := alertSeverityWarning
+	if strings.HasPrefix(context, "PANIC") || strings.HasPrefix(context, contextErrorSpike) {
+		severity = alertSeverityCritical
+	}
+
+	dispatchAlert(Alert{
+		Severity:  severity,
+		Context:   context,
+		IP:        ip,
+		Count:     count,
+		Timestamp: time.Now(),
+	})
 }
 
 // ФУНКЦИЯ: Блокировка подозрительного IP
 func blockSuspiciousIP(ip string) {
 	// Добавляем IP в список заблокированных
-	countMutex.Lock()
-	blockedIPs[ip] = time.Now()
-	countMutex.Unlock()
+	blockIP(ip, blockReasonHighErrorRate)
 
 	logger.InfoLogger.Printf("🔒 IP %s заблокирован за подозрительную активность", ip)
 
 	// Логируем в security.log
-	logSecurityEvent("SUSPICIOUS_IP_BLOCKED", ip, "high_error_rate")
+	logSecurityEventWithReason("SUSPICIOUS_IP_BLOCKED", ip, "-", blockReasonHighErrorRate)
 }
 
 // ФУНКЦИЯ: Мониторинг ошибок в фоне
-func monitorErrors() {
+func monitorErrors(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
 	for {
-		time.Sleep(1 * time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
-		// Очищаем старые записи
+		// Начинаем новое окно и снимаем алерты с IP, от которых ошибок стало мало
 		alertMutex.Lock()
-		for ip, count := range errorCounts {
-			if count < errorThreshold {
-				delete(errorCounts, ip)
-			}
-		}
+		resolved := rolloverErrorCounts()
 		alertMutex.Unlock()
+
+		for _, ip := range resolved {
+			logger.InfoLogger.Printf("✅ Ошибок от IP %s меньше %d в минуту, алерт снят", ip, errorResetThreshold)
+		}
 	}
 }
 
 // ФУНКЦИЯ: Обновление middleware для обработки ошибок
+// Клиент всегда получает общий ответ 500 — стек пишется только в файл логов
 func alertMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// http.ErrAbortHandler — штатный способ прервать ответ, не ошибка
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				var stack []byte
+				if panicStackTraces {
+					stack = debug.Stack()
+				}
+				route := r.Method + " " + r.URL.Path
+				logger.LogPanic(getRequestID(r), route, err, stack)
+				panicsTotal.WithLabelValues(normalizeRoute(r)).Inc()
+
 				ip := getIP(r)
 				// Преобразуем любое значение в строку
 				var errorMsg string
@@ -158,7 +311,7 @@ func alertMiddleware(next http.Handler) http.Handler {
 				default:
 					errorMsg = "Unknown panic"
 				}
-				logErrorWithAlert(errorMsg, "PANIC in request handler", ip)
+				logErrorWithAlert(errorMsg, "PANIC in request handler: "+route, ip)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
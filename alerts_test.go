@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// ТЕСТ: Паника превращается в общий ответ 500 без стека
+func TestAlertMiddlewareHidesPanic(t *testing.T) {
+	handler := requestIDMiddleware(alertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("секретная подробность")
+	})))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/goals", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+	body := recorder.Body.String()
+	if strings.Contains(body, "секретная") || strings.Contains(body, "goroutine") {
+		t.Errorf("Panic details leaked to client: %q", body)
+	}
+	if recorder.Header().Get("X-Request-ID") == "" {
+		t.Errorf("Expected X-Request-ID header to be set")
+	}
+}
+
+// ТЕСТ: Разные контексты получают свои пороги, остальные — общий
+func TestThresholdForContext(t *testing.T) {
+	previous := contextErrorThresholds
+	defer func() { contextErrorThresholds = previous }()
+
+	contextErrorThresholds = parseContextThresholds("Telegram API=20, PANIC in request handler=1, broken, bad=x")
+
+	cases := map[string]int{
+		"Telegram API":                           20,
+		"PANIC in request handler":               1,
+		"PANIC in request handler: GET /goals/5": 1,
+		"Database error":                         errorThreshold,
+		"bad":                                    errorThreshold,
+	}
+	for context, expected := range cases {
+		if got := thresholdForContext(context); got != expected {
+			t.Errorf("Context %q: expected threshold %d, got %d", context, expected, got)
+		}
+	}
+}
+
+// ТЕСТ: Паника учитывается в panics_total с меткой маршрута
+func TestAlertMiddlewareCountsPanicsByRoute(t *testing.T) {
+	handler := alertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	// Маршрут нормализуется по зарегистрированным шаблонам
+	defer func(previous *http.ServeMux) { appMux = previous }(appMux)
+	appMux = http.NewServeMux()
+	appMux.Handle("/goals/", handler)
+
+	resetMetrics()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/goals/7", nil))
+
+	if got := testutil.ToFloat64(panicsTotal.WithLabelValues("/goals/{id}")); got != 1 {
+		t.Errorf("Expected panics_total{route=\"/goals/{id}\"} = 1, got %v", got)
+	}
+}
+
+// ТЕСТ: Webhook получает JSON алерта с заголовками, неудачная попытка повторяется
+func TestWebhookAlerterRetries(t *testing.T) {
+	defer func(previous time.Duration) { alertRetryBackoff = previous }(alertRetryBackoff)
+	alertRetryBackoff = time.Millisecond
+
+	var attempts atomic.Int32
+	var received Alert
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	alerter := &webhookAlerter{url: server.URL, headers: parseWebhookHeaders("Authorization: Token abc==, broken")}
+	alert := Alert{Severity: alertSeverityCritical, Context: "PANIC in request handler", IP: "203.0.113.7", Count: 3, Timestamp: time.Now()}
+
+	if err := sendWithRetry(alerter, alert); err != nil {
+		t.Fatalf("Expected delivery on retry, got %v", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+	if authorization != "Token abc==" {
+		t.Errorf("Expected Authorization header from ALERT_WEBHOOK_HEADERS, got %q", authorization)
+	}
+	if received.IP != alert.IP || received.Count != 3 || received.Severity != alertSeverityCritical {
+		t.Errorf("Unexpected payload: %+v", received)
+	}
+}
+
+// ТЕСТ: Всплеск ошибок от многих IP даёт один общий алерт за окно, от одного IP — нет
+func TestRecordGlobalErrorSpike(t *testing.T) {
+	defer func(threshold int, window time.Duration) {
+		globalErrorThreshold, globalErrorWindow = threshold, window
+		globalWindowStart = time.Time{}
+	}(globalErrorThreshold, globalErrorWindow)
+	globalErrorThreshold, globalErrorWindow = 5, time.Minute
+	globalWindowStart = time.Time{}
+
+	now := time.Now()
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+
+	// Один атакующий IP: ошибок много, но всплеска нет
+	for i := 0; i < 10; i++ {
+		if spike, _, _, _ := recordGlobalError("198.51.100.1", now); spike {
+			t.Fatalf("Expected no spike from a single IP")
+		}
+	}
+
+	// Новое окно: ошибки от многих IP
+	now = now.Add(2 * time.Minute)
+	fired := 0
+	for i := 0; i < 10; i++ {
+		spike, first, _, _ := recordGlobalError(fmt.Sprintf("203.0.113.%d", i), now)
+		if i >= 4 && !spike {
+			t.Errorf("Expected spike after %d errors from different IPs", i+1)
+		}
+		if first {
+			fired++
+		}
+	}
+	if fired != 1 {
+		t.Errorf("Expected exactly one consolidated alert per window, got %d", fired)
+	}
+}
+
+// ТЕСТ: Число ошибок около порога не даёт повторных алертов, пока алерт не снят
+func TestIPAlertHysteresis(t *testing.T) {
+	defer func(fire, reset int) {
+		errorThreshold, errorResetThreshold = fire, reset
+		errorCounts, alertingIPs = make(map[string]int), make(map[string]bool)
+	}(errorThreshold, errorResetThreshold)
+	errorThreshold, errorResetThreshold = 5, 2
+	errorCounts, alertingIPs = make(map[string]int), make(map[string]bool)
+
+	const ip = "203.0.113.9"
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+
+	// Одно минутное окно: n ошибок от IP, возвращает число отправленных алертов
+	window := func(n int) int {
+		fired := 0
+		for i := 0; i < n; i++ {
+			errorCounts[ip]++
+			if startIPAlert(ip, errorCounts[ip], errorThreshold) {
+				fired++
+			}
+		}
+		return fired
+	}
+
+	if fired := window(7); fired != 1 {
+		t.Fatalf("Expected one alert when crossing the threshold, got %d", fired)
+	}
+	if resolved := rolloverErrorCounts(); len(resolved) != 0 {
+		t.Fatalf("Expected alert to stay active above reset threshold, resolved %v", resolved)
+	}
+
+	// Счёт колеблется около порога: 4, 6, 5 — без повторных алертов
+	for _, n := range []int{4, 6, 5} {
+		if fired := window(n); fired != 0 {
+			t.Errorf("Expected no repeated alert while flapping (%d errors), got %d", n, fired)
+		}
+		if resolved := rolloverErrorCounts(); len(resolved) != 0 {
+			t.Errorf("Expected alert to stay active with %d errors, resolved %v", n, resolved)
+		}
+	}
+
+	// Ошибок стало меньше нижнего порога — алерт снят
+	window(1)
+	if resolved := rolloverErrorCounts(); len(resolved) != 1 || resolved[0] != ip {
+		t.Fatalf("Expected alert for %s to be resolved, got %v", ip, resolved)
+	}
+
+	// После снятия новое превышение снова даёт алерт
+	if fired := window(5); fired != 1 {
+		t.Errorf("Expected a new alert after resolution, got %d", fired)
+	}
+}
+
+// ТЕСТ: Каждый чат из TELEGRAM_CHAT_ID становится отдельным каналом доставки
+func TestConfiguredAlertersMultipleChats(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "token")
+	t.Setenv("TELEGRAM_CHAT_ID", " -100123, 456,,-100123 ")
+	t.Setenv("ALERT_WEBHOOK_URL", "")
+
+	configured := configuredAlerters()
+	var names []string
+	for _, alerter := range configured {
+		names = append(names, alerter.Name())
+	}
+	if strings.Join(names, ",") != "telegram:-100123,telegram:456" {
+		t.Errorf("Expected one Telegram channel per unique chat, got %v", names)
+	}
+}
@@ -0,0 +1,97 @@
+// ФАЙЛ: archiver.go
+// НАЗНАЧЕНИЕ: Фоновый перенос давно выполненных целей в goals_archive
+// ОСОБЕННОСТИ:
+//   - Включается переменной ENABLE_ARCHIVER (по умолчанию выключен)
+//   - Интервал запуска ARCHIVER_INTERVAL и возраст ARCHIVER_MIN_AGE_DAYS настраиваются
+//   - Перенос выполняется в одной транзакции: строка либо в goals, либо в архиве
+//   - Останавливается вместе с остальными фоновыми задачами
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ АРХИВАЦИИ
+var (
+	// Как часто запускать архивацию
+	archiverInterval = 1 * time.Hour
+	// Сколько дней цель должна быть выполненной, чтобы попасть в архив
+	archiverMinAgeDays = 30
+)
+
+// ИНИЦИАЛИЗАЦИЯ АРХИВАЦИИ
+func initArchiver() {
+	if !getEnvBool("ENABLE_ARCHIVER", false) {
+		return
+	}
+
+	// Нулевой интервал уронил бы time.NewTicker в фоновой горутине,
+	// а отрицательный возраст архивировал бы сразу все выполненные цели
+	archiverInterval = getEnvDuration("ARCHIVER_INTERVAL", 1*time.Hour)
+	if archiverInterval <= 0 {
+		logger.InfoLogger.Printf("⚠️ ARCHIVER_INTERVAL должен быть больше нуля, используем %s", 1*time.Hour)
+		archiverInterval = 1 * time.Hour
+	}
+	archiverMinAgeDays = getEnvInt("ARCHIVER_MIN_AGE_DAYS", 30)
+	if archiverMinAgeDays <= 0 {
+		logger.InfoLogger.Printf("⚠️ ARCHIVER_MIN_AGE_DAYS должен быть больше нуля, используем %d", 30)
+		archiverMinAgeDays = 30
+	}
+
+	logger.InfoLogger.Printf("🗃️ Архивация выполненных целей активирована: каждые %s, старше %d дн.",
+		archiverInterval, archiverMinAgeDays)
+	startBackground("archiver", runArchiver)
+}
+
+// Фоновый цикл архивации
+func runArchiver(ctx context.Context) {
+	ticker := time.NewTicker(archiverInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			archived, err := archiveCompletedGoals(ctx, time.Now().AddDate(0, 0, -archiverMinAgeDays))
+			if err != nil {
+				logger.LogError(err, "Ошибка архивации выполненных целей")
+				continue
+			}
+			logger.InfoLogger.Printf("🗃️ Архивация: перенесено целей: %d", archived)
+			if archived > 0 {
+				invalidateCachedGoals()
+			}
+		}
+	}
+}
+
+// ФУНКЦИЯ: archiveCompletedGoals
+// НАЗНАЧЕНИЕ: Переносит цели, выполненные раньше cutoff, в goals_archive
+// Возвращает количество перенесённых строк
+func archiveCompletedGoals(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// DELETE ... RETURNING и INSERT в одном запросе: переносятся ровно те строки,
+	// которые были удалены, даже если другие запросы меняют goals параллельно
+	var archived int64
+	err := pgx.BeginFunc(ctx, dbPool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, withTables(`WITH moved AS (
+				DELETE FROM {goals} WHERE completed_at < $1
+				RETURNING id, goal, timeline, salary_target, currency, tags, created_at, completed_at
+			)
+			INSERT INTO {goals_archive} (id, goal, timeline, salary_target, currency, tags, created_at, completed_at)
+			SELECT id, goal, timeline, salary_target, currency, tags, created_at, completed_at FROM moved`), cutoff)
+		if err != nil {
+			return err
+		}
+		archived = tag.RowsAffected()
+		return nil
+	})
+	return archived, err
+}
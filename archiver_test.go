@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// ТЕСТ: неположительные ARCHIVER_INTERVAL и ARCHIVER_MIN_AGE_DAYS заменяются значениями по умолчанию
+func TestInitArchiverRejectsNonPositive(t *testing.T) {
+	defer func(interval time.Duration, days int) {
+		archiverInterval, archiverMinAgeDays = interval, days
+	}(archiverInterval, archiverMinAgeDays)
+	t.Cleanup(stopBackgroundTasks)
+
+	t.Setenv("ENABLE_ARCHIVER", "true")
+	t.Setenv("ARCHIVER_INTERVAL", "0s")
+	t.Setenv("ARCHIVER_MIN_AGE_DAYS", "-5")
+	initArchiver()
+
+	if archiverInterval != time.Hour {
+		t.Errorf("Expected default interval 1h, got %s", archiverInterval)
+	}
+	if archiverMinAgeDays != 30 {
+		t.Errorf("Expected default min age 30 days, got %d", archiverMinAgeDays)
+	}
+}
@@ -0,0 +1,49 @@
+// ФАЙЛ: background.go
+// НАЗНАЧЕНИЕ: Запуск и остановка фоновых циклов
+// ОСОБЕННОСТИ:
+//   - Каждый цикл получает общий контекст и завершается при его отмене
+//   - stopBackgroundTasks ждёт, пока все циклы действительно завершатся
+//   - После остановки циклы можно запустить снова (нужно для тестов)
+
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ ФОНОВЫХ ЗАДАЧ
+var (
+	// Контекст, отмена которого останавливает все фоновые циклы
+	backgroundCtx, backgroundCancel = context.WithCancel(context.Background())
+	// Ожидание завершения запущенных циклов
+	backgroundWG sync.WaitGroup
+	// Защищает backgroundCtx и backgroundCancel
+	backgroundMutex sync.Mutex
+)
+
+// ФУНКЦИЯ: startBackground
+// НАЗНАЧЕНИЕ: Запускает фоновый цикл, который остановится при stopBackgroundTasks
+func startBackground(name string, loop func(ctx context.Context)) {
+	backgroundMutex.Lock()
+	ctx := backgroundCtx
+	backgroundWG.Add(1)
+	backgroundMutex.Unlock()
+
+	go func() {
+		defer backgroundWG.Done()
+		loop(ctx)
+		logger.InfoLogger.Printf("⏹️ Фоновая задача %s остановлена", name)
+	}()
+}
+
+// ФУНКЦИЯ: stopBackgroundTasks
+// НАЗНАЧЕНИЕ: Останавливает все фоновые циклы и ждёт их завершения
+func stopBackgroundTasks() {
+	backgroundMutex.Lock()
+	backgroundCancel()
+	backgroundCtx, backgroundCancel = context.WithCancel(context.Background())
+	backgroundMutex.Unlock()
+
+	backgroundWG.Wait()
+}
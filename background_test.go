@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"go.uber.org/goleak"
+)
+
+// ТЕСТ: После остановки не остаётся ни одной фоновой горутины
+func TestStopBackgroundTasksLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// StatsD пишет в локальный UDP-сокет, чтобы не зависеть от внешнего сервера
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen UDP: %v", err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("udp", listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial UDP: %v", err)
+	}
+	emitter := &statsdEmitter{conn: conn, prefix: "test", lines: make(chan string, 10)}
+
+	startBackground("security-cleanup", cleanRequestCounts)
+	startBackground("error-monitor", monitorErrors)
+	startBackground("statsd", emitter.run)
+
+	stopBackgroundTasks()
+}
@@ -0,0 +1,103 @@
+// ФАЙЛ: banner.go
+// НАЗНАЧЕНИЕ: Сводка итоговой конфигурации при запуске сервера
+// ОСОБЕННОСТИ:
+//   - Один блок в логе вместо разрозненных сообщений отдельных init-функций
+//   - Секреты (пароли, токены) маскируются, показывается только факт их наличия
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ФУНКЦИЯ: logStartupBanner
+// НАЗНАЧЕНИЕ: Выводит итоговую конфигурацию экземпляра после всех шагов инициализации
+func logStartupBanner(port string, useTLS bool) {
+	logger.InfoLogger.Println("📋 Итоговая конфигурация:\n" + strings.Join(startupConfigLines(port, useTLS), "\n"))
+}
+
+// ФУНКЦИЯ: startupConfigLines
+// НАЗНАЧЕНИЕ: Строки сводки конфигурации (вынесено отдельно для тестов)
+func startupConfigLines(port string, useTLS bool) []string {
+	countMutex.Lock()
+	limit, block := requestLimit, blockDuration
+	countMutex.Unlock()
+
+	protocol := "HTTP"
+	if useTLS {
+		protocol = "HTTPS"
+	}
+
+	security := fmt.Sprintf("лимит %d запр./мин, блокировка %s", limit, block)
+	if securityDisabled {
+		security = "отключена (DISABLE_SECURITY)"
+	}
+
+	alerts := "отключены"
+	if alertsEnabled() {
+		alerts = fmt.Sprintf("%s, порог %d ошибок (снятие ниже %d)", strings.Join(alerterNames(), ", "), errorThreshold, errorResetThreshold)
+	}
+
+	quota := "без ограничений"
+	if maxGoalsPerUser > 0 {
+		quota = fmt.Sprintf("%d", maxGoalsPerUser)
+	}
+
+	requestLog := "все запросы"
+	if logger.sampleRate > 1 {
+		requestLog = fmt.Sprintf("успешные 1 из %d, ошибки все", logger.sampleRate)
+	}
+
+	concurrency := "без ограничений"
+	if maxConcurrentRequests > 0 {
+		concurrency = fmt.Sprintf("%d", maxConcurrentRequests)
+	}
+	if maxLongPollRequests > 0 {
+		concurrency += fmt.Sprintf(", long poll %d", maxLongPollRequests)
+	}
+
+	return []string{
+		fmt.Sprintf("   Порт:            %s (%s)", port, protocol),
+		fmt.Sprintf("   Только HTTPS:    %t", forceHTTPS),
+		fmt.Sprintf("   База данных:     %s", maskDBURL(dbURL)),
+		fmt.Sprintf("   Реплика чтения:  %t", readPool != nil),
+		fmt.Sprintf("   Таблица целей:   %s", goalsTable),
+		fmt.Sprintf("   Защита:          %s", security),
+		fmt.Sprintf("   Алерты:          %s", alerts),
+		fmt.Sprintf("   Авторизация:     %s", adminAuthMode()),
+		fmt.Sprintf("   Таймаут запроса: %s", handlerTimeout),
+		fmt.Sprintf("   Параллельность:  %s", concurrency),
+		fmt.Sprintf("   Кэш чтения:      %t (Cache-Control max-age=%d)", readCacheEnabled, responseCacheMaxAge),
+		fmt.Sprintf("   Лимит целей:     %s", quota),
+		fmt.Sprintf("   Формат ответа:   конверт=%t, отступы=%t, поля=%s", responseEnvelope, prettyJSONDefault, jsonNaming),
+		fmt.Sprintf("   Журнал запросов: %s", requestLog),
+		fmt.Sprintf("   StatsD:          %t", statsdClient != nil),
+		fmt.Sprintf("   GeoIP:           %t", geoLookup != nil),
+	}
+}
+
+// ФУНКЦИЯ: adminAuthMode
+// НАЗНАЧЕНИЕ: Описание способов входа в служебные endpoint'ы без раскрытия секретов
+func adminAuthMode() string {
+	var modes []string
+	if adminToken != "" {
+		modes = append(modes, "токен "+maskSecret(adminToken))
+	}
+	if isBasicAuthConfigured() {
+		modes = append(modes, "Basic Auth ("+adminUser+")")
+	}
+	if len(modes) == 0 {
+		return "не настроена"
+	}
+	return strings.Join(modes, ", ")
+}
+
+// ФУНКЦИЯ: maskSecret
+// НАЗНАЧЕНИЕ: Скрывает секрет, оставляя только последние символы для сверки
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
@@ -0,0 +1,208 @@
+// ФАЙЛ: blocksummary.go
+// НАЗНАЧЕНИЕ: Сводка блокировок IP для страницы статуса безопасности
+// ОСОБЕННОСТИ:
+//   - Считается по записям о блокировках в security.log, поэтому переживает перезапуск
+//   - Количество блокировок за последний час и сутки с разбивкой по причинам
+//   - IP-адреса наружу не отдаются: только число различных адресов
+//   - Журнал читается с конца и только до записей старше суток: размер security.log
+//     не влияет на время ответа
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// События security.log, которые означают новую блокировку IP
+var blockEventTypes = map[string]bool{
+	"RATE_LIMIT_EXCEEDED":             true,
+	"SUSPICIOUS_ACTIVITY":             true,
+	"SUSPICIOUS_ACTIVITY_SHORT_BLOCK": true,
+	"SUSPICIOUS_IP_BLOCKED":           true,
+	"ADMIN_BRUTE_FORCE_BLOCKED":       true,
+}
+
+// Формат времени в начале строки security.log (log.Ldate|log.Ltime, UTC)
+const securityLogTimeLayout = "2006/01/02 15:04:05"
+
+// ПАРАМЕТРЫ ЧТЕНИЯ ЖУРНАЛА С КОНЦА
+const (
+	securityLogChunkSize   = 64 * 1024   // Сколько байт читается за раз
+	securityLogMaxLineSize = 1024 * 1024 // Более длинные строки пропускаются
+	// Строки пишутся из разных горутин, и время в них может немного идти вразнобой:
+	// чтение останавливается на записи, которая старше окна с этим запасом
+	securityLogClockSkew = time.Minute
+)
+
+// ЗАПИСЬ О БЛОКИРОВКЕ ИЗ ЖУРНАЛА
+type blockRecord struct {
+	At     time.Time
+	IP     string
+	Reason string
+}
+
+// СВОДКА ЗА ПЕРИОД
+type blockWindowSummary struct {
+	Blocks    int            `json:"blocks"`
+	UniqueIPs int            `json:"unique_ips"`
+	ByReason  map[string]int `json:"by_reason"`
+
+	ips map[string]bool
+}
+
+// ОТВЕТ GET /admin/security/summary
+type blockSummary struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	LastHour    *blockWindowSummary `json:"last_hour"`
+	LastDay     *blockWindowSummary `json:"last_day"`
+}
+
+func newBlockWindowSummary() *blockWindowSummary {
+	return &blockWindowSummary{ByReason: map[string]int{}, ips: map[string]bool{}}
+}
+
+func (s *blockWindowSummary) add(record blockRecord) {
+	s.Blocks++
+	s.ByReason[record.Reason]++
+	if !s.ips[record.IP] {
+		s.ips[record.IP] = true
+		s.UniqueIPs++
+	}
+}
+
+// ФУНКЦИЯ: parseBlockRecord
+// НАЗНАЧЕНИЕ: Разбирает строку security.log вида
+// "SECURITY: 2026/01/22 17:23:01 RATE_LIMIT_EXCEEDED | IP: 1.2.3.4 | PATH: /goals | REASON: rate_limit"
+// ok=false — строка не о блокировке или в другом формате
+func parseBlockRecord(line string) (blockRecord, bool) {
+	at, event, ok := parseSecurityLogLine(line)
+	if !ok {
+		return blockRecord{}, false
+	}
+
+	fields := strings.Split(event, " | ")
+	if !blockEventTypes[fields[0]] {
+		return blockRecord{}, false
+	}
+
+	record := blockRecord{At: at}
+	for _, field := range fields[1:] {
+		if value, ok := strings.CutPrefix(field, "IP: "); ok {
+			record.IP = value
+		} else if value, ok := strings.CutPrefix(field, "REASON: "); ok {
+			record.Reason = value
+		}
+	}
+	if record.Reason == "" {
+		record.Reason = "unknown"
+	}
+	return record, true
+}
+
+// Время записи и текст события из строки security.log; ok=false — строка в другом формате
+func parseSecurityLogLine(line string) (time.Time, string, bool) {
+	line, found := strings.CutPrefix(line, "SECURITY: ")
+	if !found || len(line) < len(securityLogTimeLayout)+1 {
+		return time.Time{}, "", false
+	}
+	at, err := time.Parse(securityLogTimeLayout, line[:len(securityLogTimeLayout)])
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return at, strings.TrimSpace(line[len(securityLogTimeLayout):]), true
+}
+
+// ФУНКЦИЯ: summarizeBlocks
+// НАЗНАЧЕНИЕ: Считает блокировки из журнала path за час и сутки до now
+func summarizeBlocks(path string, now time.Time) (blockSummary, error) {
+	summary := blockSummary{
+		GeneratedAt: now,
+		LastHour:    newBlockWindowSummary(),
+		LastDay:     newBlockWindowSummary(),
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return summary, nil // Журнала ещё нет — блокировок не было
+	}
+	if err != nil {
+		return summary, err
+	}
+	defer file.Close()
+
+	hourAgo, dayAgo := now.Add(-time.Hour), now.Add(-24*time.Hour)
+	err = scanLinesBackward(file, func(line string) bool {
+		// Записи идут по времени: всё, что выше записи старше суток, тоже старше
+		if at, _, ok := parseSecurityLogLine(line); ok && at.Before(dayAgo.Add(-securityLogClockSkew)) {
+			return false
+		}
+		record, ok := parseBlockRecord(line)
+		if !ok || record.At.Before(dayAgo) || record.At.After(now) {
+			return true
+		}
+		summary.LastDay.add(record)
+		if !record.At.Before(hourAgo) {
+			summary.LastHour.add(record)
+		}
+		return true
+	})
+	return summary, err
+}
+
+// ФУНКЦИЯ: scanLinesBackward
+// НАЗНАЧЕНИЕ: Передаёт строки файла в fn от последней к первой, пока fn возвращает true.
+// Файл читается кусками с конца, поэтому непрочитанное начало не загружается в память
+func scanLinesBackward(file *os.File, fn func(line string) bool) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	var partial []byte // Конец строки, начало которой лежит в ещё не прочитанном куске
+	for offset := info.Size(); offset > 0; {
+		n := min(int64(securityLogChunkSize), offset)
+		offset -= n
+		chunk := make([]byte, n, n+int64(len(partial)))
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return err
+		}
+		chunk = append(chunk, partial...)
+
+		for {
+			i := bytes.LastIndexByte(chunk, '\n')
+			if i < 0 {
+				break
+			}
+			if line := chunk[i+1:]; len(line) > 0 && !fn(string(line)) {
+				return nil
+			}
+			chunk = chunk[:i]
+		}
+		partial = chunk
+		if len(partial) > securityLogMaxLineSize {
+			partial = nil // Хвост слишком длинной строки отбрасываем, чтобы не держать его в памяти
+		}
+	}
+	if len(partial) > 0 {
+		fn(string(partial))
+	}
+	return nil
+}
+
+// ОБРАБОТЧИК: GET /admin/security/summary
+// Сводка блокировок без IP-адресов (для внутренней панели и страницы статуса)
+func securitySummaryHandler(w http.ResponseWriter, r *http.Request) {
+	summary, err := summarizeBlocks(securityLogPath, securityClock.Now().UTC())
+	if err != nil {
+		logger.LogError(err, "Ошибка чтения "+securityLogPath+" в securitySummaryHandler")
+		http.Error(w, "Не удалось прочитать журнал безопасности", http.StatusInternalServerError)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, summary)
+}
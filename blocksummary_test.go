@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Сводка считает блокировки за час и сутки по причинам и не раскрывает IP
+func TestSecuritySummaryHandler(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	line := func(ago time.Duration, event string) string {
+		return "SECURITY: " + now.Add(-ago).Format(securityLogTimeLayout) + " " + event
+	}
+	content := strings.Join([]string{
+		line(48*time.Hour, "RATE_LIMIT_EXCEEDED | IP: 198.51.100.1 | PATH: /goals | REASON: rate_limit"),
+		line(3*time.Hour, "SUSPICIOUS_ACTIVITY | IP: 198.51.100.2 | PATH: /.env | REASON: suspicious_path | COUNTRY: NL"),
+		line(30*time.Minute, "RATE_LIMIT_EXCEEDED | IP: 198.51.100.3 | PATH: /goals | REASON: rate_limit"),
+		line(20*time.Minute, "BLOCKED_ACCESS | IP: 198.51.100.3 | PATH: /goals | REASON: rate_limit"),
+		line(10*time.Minute, "SUSPICIOUS_ACTIVITY_SHORT_BLOCK | IP: 198.51.100.3 | PATH: /wp-admin | REASON: suspicious_path"),
+		line(5*time.Minute, "ADMIN_BRUTE_FORCE_BLOCKED | IP: 198.51.100.4 | PATH: /admin/blocked | REASON: admin_auth"),
+		"garbage line",
+	}, "\n") + "\n"
+
+	path := filepath.Join(t.TempDir(), "security.log")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(previousPath string, previousClock clock) {
+		securityLogPath, securityClock = previousPath, previousClock
+	}(securityLogPath, securityClock)
+	securityLogPath, securityClock = path, &fakeClock{now: now}
+
+	recorder := httptest.NewRecorder()
+	securitySummaryHandler(recorder, httptest.NewRequest("GET", "/admin/security/summary", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if strings.Contains(recorder.Body.String(), "198.51.100") {
+		t.Errorf("Expected no IP addresses in summary, got %s", recorder.Body.String())
+	}
+
+	var summary blockSummary
+	json.Unmarshal(recorder.Body.Bytes(), &summary)
+	if hour := summary.LastHour; hour.Blocks != 3 || hour.UniqueIPs != 2 || hour.ByReason["rate_limit"] != 1 || hour.ByReason["suspicious_path"] != 1 || hour.ByReason["admin_auth"] != 1 {
+		t.Errorf("Unexpected last hour summary: %+v", hour)
+	}
+	if day := summary.LastDay; day.Blocks != 4 || day.UniqueIPs != 3 || day.ByReason["suspicious_path"] != 2 {
+		t.Errorf("Unexpected last day summary: %+v", day)
+	}
+}
+
+// ТЕСТ: Строки читаются с конца через границы кусков, чтение можно остановить
+func TestScanLinesBackward(t *testing.T) {
+	var lines []string
+	for i := range 50 {
+		// Длины подобраны так, чтобы строки пересекали границы кусков
+		lines = append(lines, fmt.Sprintf("%03d %s", i, strings.Repeat("x", i*2000)))
+	}
+	path := filepath.Join(t.TempDir(), "security.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var got []string
+	if err := scanLinesBackward(file, func(line string) bool {
+		got = append(got, line)
+		return true
+	}); err != nil {
+		t.Fatalf("scanLinesBackward failed: %v", err)
+	}
+	if len(got) != len(lines) {
+		t.Fatalf("Expected %d lines, got %d", len(lines), len(got))
+	}
+	for i, line := range got {
+		if expected := lines[len(lines)-1-i]; line != expected {
+			t.Errorf("Line %d: expected prefix %q, got %q", i, expected[:3], line[:min(len(line), 3)])
+		}
+	}
+
+	got = nil
+	scanLinesBackward(file, func(line string) bool {
+		got = append(got, line[:3])
+		return len(got) < 3
+	})
+	if strings.Join(got, ",") != "049,048,047" {
+		t.Errorf("Expected scan to stop after 3 lines, got %v", got)
+	}
+}
+
+// ТЕСТ: Записи старше суток не читаются, даже если среди них есть блокировки
+func TestSummarizeBlocksStopsAtWindow(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	line := func(at time.Time, event string) string {
+		return "SECURITY: " + at.Format(securityLogTimeLayout) + " " + event
+	}
+	content := strings.Join([]string{
+		// Выше записи старше суток чтение не идёт: эта блокировка не попадает в сводку
+		line(now.Add(-time.Hour), "RATE_LIMIT_EXCEEDED | IP: 198.51.100.9 | PATH: /goals | REASON: rate_limit"),
+		line(now.Add(-25*time.Hour), "BLOCKED_ACCESS | IP: 198.51.100.1 | PATH: /goals | REASON: rate_limit"),
+		line(now.Add(-2*time.Hour), "RATE_LIMIT_EXCEEDED | IP: 198.51.100.2 | PATH: /goals | REASON: rate_limit"),
+	}, "\n") + "\n"
+	path := filepath.Join(t.TempDir(), "security.log")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := summarizeBlocks(path, now)
+	if err != nil {
+		t.Fatalf("summarizeBlocks failed: %v", err)
+	}
+	if summary.LastDay.Blocks != 1 || summary.LastHour.Blocks != 0 {
+		t.Errorf("Expected only the block after the day-old record, got day=%+v hour=%+v", summary.LastDay, summary.LastHour)
+	}
+}
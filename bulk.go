@@ -0,0 +1,253 @@
+// ФАЙЛ: bulk.go
+// НАЗНАЧЕНИЕ: Быстрая вставка больших пачек целей через COPY (импорт CSV, CreateMany)
+// ОСОБЕННОСТИ:
+//   - Пачки от BULK_COPY_THRESHOLD строк (по умолчанию 100) копируются через COPY,
+//     меньшие вставляются построчно, как раньше
+//   - ID и created_at выделяются заранее (nextval последовательности и NOW() транзакции),
+//     поэтому цели заполняются так же, как при построчной вставке
+//   - BULK_WORKERS > 1 делит пачку на части и параллельно копирует их в нежурналируемую
+//     таблицу {goals_staging}; в таблицу целей они переносятся одним INSERT ... SELECT
+//     в основной транзакции. Поэтому пачка видна читателям либо целиком, либо никак,
+//     а строки неудачной пачки остаются только в промежуточной таблице и удаляются
+//   - Соединения для частей берутся из общего на все импорты семафора (BULK_WORKERS слотов)
+//     и только из свободных в пуле; если их не хватило или пул не выдал соединение
+//     за bulkConnWait, пачка копируется одной транзакцией. Так одновременные импорты
+//     не держат соединения в ожидании друг друга
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ МАССОВОЙ ВСТАВКИ
+var (
+	// Число параллельных COPY на все импорты сразу (BULK_WORKERS); 1 — одна транзакция
+	bulkWorkers = 1
+	// С какого размера пачки вставлять через COPY
+	bulkCopyThreshold = 100
+	// Слоты соединений для частей, общие для всех одновременных импортов
+	bulkCopySlots chan struct{}
+)
+
+// Сколько ждать соединение для части, прежде чем копировать одной транзакцией
+const bulkConnWait = 200 * time.Millisecond
+
+// Колонки COPY: id и created_at заполняются заранее в allocateGoalIDs
+var goalCopyColumns = []string{"id", "goal", "timeline", "salary_target", "currency", "tags", "created_at"}
+
+// ИНИЦИАЛИЗАЦИЯ МАССОВОЙ ВСТАВКИ
+func initBulkInsert() {
+	bulkWorkers = getEnvInt("BULK_WORKERS", 1)
+	if bulkWorkers < 1 {
+		bulkWorkers = 1
+	}
+	bulkCopyThreshold = getEnvInt("BULK_COPY_THRESHOLD", 100)
+	if bulkCopyThreshold < 1 {
+		bulkCopyThreshold = 100
+	}
+	bulkCopySlots = make(chan struct{}, bulkWorkers)
+	logger.InfoLogger.Printf("📦 Массовая вставка: COPY от %d строк, параллельно до %d", bulkCopyThreshold, bulkWorkers)
+}
+
+// Приёмник COPY: транзакция или соединение
+type goalCopier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// ФУНКЦИЯ: copyGoals
+// НАЗНАЧЕНИЕ: Вставляет пачку целей через COPY с проверкой лимита MAX_GOALS_PER_USER
+// Основная транзакция держит блокировку лимита, пока не закончат все части
+func copyGoals(ctx context.Context, goals []Goal) error {
+	staged := false
+	err := pgx.BeginFunc(ctx, dbPool, func(tx pgx.Tx) error {
+		if maxGoalsPerUser > 0 {
+			if err := checkGoalQuota(ctx, tx, len(goals)); err != nil {
+				return err
+			}
+		}
+		if err := allocateGoalIDs(ctx, tx, goals); err != nil {
+			return err
+		}
+
+		// Основная транзакция уже держит своё соединение, части берут только свободные
+		stat := dbPool.Stat()
+		slots := acquireBulkSlots(min(len(goals), int(stat.MaxConns()-stat.AcquiredConns())))
+		defer releaseBulkSlots(slots)
+		if slots < 2 {
+			return copyGoalRows(ctx, tx, goals)
+		}
+
+		conns, err := acquireChunkConns(ctx, slots)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.InfoLogger.Printf("📦 Нет свободных соединений для параллельного COPY (%v), копируем одной транзакцией", err)
+			return copyGoalRows(ctx, tx, goals)
+		}
+
+		staged = true
+		if err := stageGoalsParallel(ctx, goals, conns); err != nil {
+			return err
+		}
+		return moveStagedGoals(ctx, tx, goals)
+	})
+
+	// Промежуточные строки не нужны ни после переноса, ни после отката
+	if staged {
+		removeStagedGoals(goals[0].ID)
+	}
+	return err
+}
+
+// Берём до n слотов семафора, не дожидаясь занятых; возвращает число взятых
+func acquireBulkSlots(n int) int {
+	taken := 0
+	for taken < n {
+		select {
+		case bulkCopySlots <- struct{}{}:
+			taken++
+		default:
+			return taken
+		}
+	}
+	return taken
+}
+
+func releaseBulkSlots(n int) {
+	for range n {
+		<-bulkCopySlots
+	}
+}
+
+// ФУНКЦИЯ: acquireChunkConns
+// НАЗНАЧЕНИЕ: Берёт из пула n соединений для частей; каждое ждём не дольше bulkConnWait.
+// При ошибке уже взятые соединения возвращаются в пул
+func acquireChunkConns(ctx context.Context, n int) ([]*pgxpool.Conn, error) {
+	conns := make([]*pgxpool.Conn, 0, n)
+	for range n {
+		acquireCtx, cancel := context.WithTimeout(ctx, bulkConnWait)
+		conn, err := dbPool.Acquire(acquireCtx)
+		cancel()
+		if err != nil {
+			for _, acquired := range conns {
+				acquired.Release()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// ФУНКЦИЯ: allocateGoalIDs
+// НАЗНАЧЕНИЕ: Выделяет ID из последовательности таблицы и проставляет created_at,
+// если он не задан (импорт администратором сохраняет свой)
+func allocateGoalIDs(ctx context.Context, tx pgx.Tx, goals []Goal) error {
+	rows, err := tx.Query(ctx, "SELECT nextval(pg_get_serial_sequence($1, 'id')), NOW() FROM generate_series(1, $2)",
+		goalsTable, len(goals))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	i := 0
+	for rows.Next() {
+		var now time.Time
+		if err := rows.Scan(&goals[i].ID, &now); err != nil {
+			return err
+		}
+		if goals[i].CreatedAt.IsZero() {
+			goals[i].CreatedAt = now
+		}
+		i++
+	}
+	return rows.Err()
+}
+
+// Копирование части целей одним COPY
+func copyGoalRows(ctx context.Context, q goalCopier, goals []Goal) error {
+	_, err := q.CopyFrom(ctx, pgx.Identifier(strings.Split(goalsTable, ".")), goalCopyColumns,
+		pgx.CopyFromSlice(len(goals), func(i int) ([]any, error) {
+			g := goals[i]
+			return []any{g.ID, g.Goal, g.Timeline, g.SalaryTarget, g.Currency, g.Tags, g.CreatedAt}, nil
+		}))
+	return err
+}
+
+// ФУНКЦИЯ: stageGoalsParallel
+// НАЗНАЧЕНИЕ: Делит цели на len(conns) частей и параллельно копирует их в {goals_staging}.
+// Номер пачки — ID её первой цели: он выделен из последовательности и не повторяется
+func stageGoalsParallel(ctx context.Context, goals []Goal, conns []*pgxpool.Conn) error {
+	defer func() {
+		for _, conn := range conns {
+			conn.Release()
+		}
+	}()
+	chunkSize := (len(goals) + len(conns) - 1) / len(conns)
+	batch := goals[0].ID
+	table := pgx.Identifier(strings.Split(goalsStagingTable(), "."))
+	columns := append([]string{"batch_id"}, goalCopyColumns...)
+
+	// Первая ошибка отменяет остальные части
+	copyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i, conn := range conns {
+		chunk := goals[min(i*chunkSize, len(goals)):min((i+1)*chunkSize, len(goals))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := conn.CopyFrom(copyCtx, table, columns, pgx.CopyFromSlice(len(chunk), func(i int) ([]any, error) {
+				g := chunk[i]
+				return []any{batch, g.ID, g.Goal, g.Timeline, g.SalaryTarget, g.Currency, g.Tags, g.CreatedAt}, nil
+			}))
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// ФУНКЦИЯ: moveStagedGoals
+// НАЗНАЧЕНИЕ: Переносит пачку из {goals_staging} в таблицу целей в основной транзакции
+func moveStagedGoals(ctx context.Context, tx pgx.Tx, goals []Goal) error {
+	columns := strings.Join(goalCopyColumns, ", ")
+	tag, err := tx.Exec(ctx, withTables("INSERT INTO {goals} ("+columns+") SELECT "+columns+
+		" FROM {goals_staging} WHERE batch_id = $1"), goals[0].ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != int64(len(goals)) {
+		return fmt.Errorf("в промежуточной таблице %d строк пачки вместо %d", tag.RowsAffected(), len(goals))
+	}
+	return nil
+}
+
+// Удаление промежуточных строк пачки. Если удалить не вышло, строки остаются только
+// в {goals_staging}: номер пачки больше не повторится, и в таблицу целей они не попадут
+func removeStagedGoals(batch int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := dbPool.Exec(ctx, withTables("DELETE FROM {goals_staging} WHERE batch_id = $1"), batch); err != nil {
+		logger.LogError(err, "Не удалось очистить промежуточную таблицу пачки целей")
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// ТЕСТ: слоты параллельного COPY общие для всех импортов и берутся без ожидания
+func TestAcquireBulkSlots(t *testing.T) {
+	defer func(slots chan struct{}) { bulkCopySlots = slots }(bulkCopySlots)
+	bulkCopySlots = make(chan struct{}, 4)
+
+	first := acquireBulkSlots(3)
+	second := acquireBulkSlots(3) // Второй импорт получает только оставшийся слот
+	third := acquireBulkSlots(3)  // Свободных нет — копирует одной транзакцией
+	if first != 3 || second != 1 || third != 0 {
+		t.Errorf("Expected 3, 1 and 0 slots, got %d, %d and %d", first, second, third)
+	}
+
+	releaseBulkSlots(first)
+	if got := acquireBulkSlots(5); got != 3 {
+		t.Errorf("Expected released slots to be reused, got %d", got)
+	}
+
+	bulkCopySlots = nil
+	if got := acquireBulkSlots(2); got != 0 {
+		t.Errorf("Expected no slots before initBulkInsert, got %d", got)
+	}
+}
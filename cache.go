@@ -0,0 +1,196 @@
+// ФАЙЛ: cache.go
+// НАЗНАЧЕНИЕ: Кэш последнего успешного списка целей для чтения при недоступной БД
+// ОСОБЕННОСТИ:
+//   - Включается переменной окружения ENABLE_READ_CACHE
+//   - Сбрасывается после каждой успешной записи
+//   - Поколение кэша не даёт чтению, начатому до записи, сохранить устаревший список
+//   - Отдаётся только когда чтение из БД завершилось ошибкой
+//   - Заголовки Cache-Control/ETag для кэширования ответов прокси и браузерами
+//   - ETag отдельной цели для условного обновления (If-Match в PUT /goals/{id})
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ КЭША
+var (
+	// Включён ли кэш для чтения
+	readCacheEnabled bool
+	// Последний успешно прочитанный список целей
+	cachedGoals []Goal
+	// Есть ли в кэше актуальные данные
+	cachedGoalsValid bool
+	// Мьютекс для потокобезопасности
+	readCacheMutex sync.RWMutex
+	// max-age для Cache-Control (0 = no-cache)
+	responseCacheMaxAge int
+	// Поколение кэша: растёт при каждом сбросе
+	cacheGeneration atomic.Uint64
+)
+
+// ИНИЦИАЛИЗАЦИЯ КЭША
+func initReadCache() {
+	readCacheEnabled = getEnvBool("ENABLE_READ_CACHE", false)
+	if readCacheEnabled {
+		logger.InfoLogger.Println("🗃️ Кэш чтения целей включен (ENABLE_READ_CACHE)")
+	}
+
+	responseCacheMaxAge = getEnvInt("CACHE_MAX_AGE", 0)
+	if responseCacheMaxAge < 0 {
+		logger.InfoLogger.Printf("⚠️ CACHE_MAX_AGE не может быть отрицательным, используем 0")
+		responseCacheMaxAge = 0
+	}
+}
+
+// Сохраняем успешно прочитанный список.
+// generation — значение cacheGeneration до начала чтения: если с тех пор
+// кэш сбрасывали, список мог устареть, и мы его не сохраняем
+func storeCachedGoals(goals []Goal, generation uint64) {
+	if !readCacheEnabled {
+		return
+	}
+
+	readCacheMutex.Lock()
+	defer readCacheMutex.Unlock()
+
+	if cacheGeneration.Load() != generation {
+		return
+	}
+
+	// Копируем срез, чтобы дальнейшие изменения не затронули кэш
+	cachedGoals = append([]Goal(nil), goals...)
+	cachedGoalsValid = true
+}
+
+// Получаем список из кэша
+func loadCachedGoals() ([]Goal, bool) {
+	if !readCacheEnabled {
+		return nil, false
+	}
+
+	readCacheMutex.RLock()
+	defer readCacheMutex.RUnlock()
+
+	return cachedGoals, cachedGoalsValid
+}
+
+// Сбрасываем кэш после успешной записи
+func invalidateCachedGoals() {
+	readCacheMutex.Lock()
+	defer readCacheMutex.Unlock()
+
+	// Поколение растёт до очистки: чтение, начатое раньше, уже не сохранит результат
+	cacheGeneration.Add(1)
+	cachedGoals = nil
+	cachedGoalsValid = false
+
+	// Любая запись сбрасывает кэш — заодно будим long polling
+	// и сбрасываем список сроков
+	invalidateCachedTimelines()
+	notifyGoalsChanged()
+}
+
+// ФУНКЦИЯ: serveCachedGoals
+// НАЗНАЧЕНИЕ: Отдаёт закэшированный список, если чтение из БД не удалось
+// Возвращает false, если кэш выключен или пуст
+func serveCachedGoals(w http.ResponseWriter, r *http.Request) bool {
+	goals, ok := loadCachedGoals()
+	if !ok {
+		return false
+	}
+
+	logger.InfoLogger.Printf("🗃️ БД недоступна, отдаём закэшированный список целей (%d шт.)", len(goals))
+
+	// Warning 111 сообщает клиенту, что данные могут быть устаревшими
+	w.Header().Set("Warning", `111 - "Database unavailable, serving cached data"`)
+	writeJSON(w, r, http.StatusOK, goalListPayload(goals, true))
+	return true
+}
+
+// ФУНКЦИЯ: writeCacheableJSON
+// НАЗНАЧЕНИЕ: Отправляет JSON с заголовками Cache-Control, Vary и ETag
+// Если клиент прислал совпадающий If-None-Match, отвечает 304 без тела
+func writeCacheableJSON(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	// Кодируем заранее, чтобы посчитать ETag по итоговому телу
+	// (с отступами и без — разные тела, значит и разные ETag)
+	body, err := encodeJSON(r, payload)
+	if err != nil {
+		logger.LogError(err, "Ошибка кодирования JSON в writeCacheableJSON")
+		http.Error(w, "Encode error", http.StatusInternalServerError)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		return
+	}
+
+	etag := computeETag(body)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept-Encoding")
+	if responseCacheMaxAge > 0 {
+		w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(responseCacheMaxAge))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	// Условный запрос: данные не изменились
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	logger.LogRequest(r.Method, r.URL.Path, http.StatusOK)
+}
+
+// Вычисляем сильный ETag по содержимому ответа
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Проверяем заголовок If-None-Match (может содержать список или "*")
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ФУНКЦИЯ: goalETag
+// НАЗНАЧЕНИЕ: Сильный ETag отдельной цели
+// Считается по полям в snake_case и created_at в UTC, поэтому не зависит
+// от JSON_NAMING, ?pretty и часового пояса соединения с БД
+func goalETag(goal Goal) string {
+	goal.CreatedAt = goal.CreatedAt.UTC()
+	body, _ := json.Marshal(goalJSON(goal)) // Ошибки нет: все поля кодируемые
+	return computeETag(body)
+}
+
+// Проверяем заголовок If-Match: "*" или точное совпадение одного из ETag.
+// В отличие от If-None-Match сравнение строгое: слабые ETag (W/) не подходят
+func ifMatchSatisfied(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+// ФАЙЛ: check.go
+// НАЗНАЧЕНИЕ: Самопроверка конфигурации (флаг -check)
+// ОСОБЕННОСТИ:
+//   - Сервер не запускается и трафик не принимается
+//   - Проверяются подключение к БД, настройки алертинга, TLS и обязательные переменные
+//   - Итог печатается в stdout, код выхода 0 — всё в порядке, 1 — есть ошибки
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// СТРУКТУРА РЕЗУЛЬТАТА ОДНОЙ ПРОВЕРКИ
+type checkResult struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// ФУНКЦИЯ: runSelfCheck
+// НАЗНАЧЕНИЕ: Выполняет все проверки, печатает сводку и возвращает код выхода
+func runSelfCheck() int {
+	results := []checkResult{
+		checkRequiredEnv(),
+		checkPort(),
+		checkTLSConfig(),
+		checkAlertConfig(),
+		checkDatabase(),
+	}
+
+	failed := 0
+	fmt.Println("Проверка конфигурации:")
+	for _, result := range results {
+		status := "OK  "
+		if !result.OK {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("  [%s] %s: %s\n", status, result.Name, result.Detail)
+	}
+
+	if failed > 0 {
+		fmt.Printf("Найдено ошибок: %d\n", failed)
+		return 1
+	}
+	fmt.Println("Конфигурация в порядке")
+	return 0
+}
+
+// Строка подключения должна быть задана явно — локальная база годится только для разработки
+func checkRequiredEnv() checkResult {
+	result := checkResult{Name: "переменные окружения"}
+	if os.Getenv("DATABASE_URL") == "" && os.Getenv("DATABASE_URL_FILE") == "" {
+		result.Detail = "не задан ни DATABASE_URL, ни DATABASE_URL_FILE"
+		return result
+	}
+	result.OK, result.Detail = true, "строка подключения задана"
+	return result
+}
+
+// PORT необязателен, но если задан — должен быть корректным номером порта
+func checkPort() checkResult {
+	result := checkResult{Name: "PORT"}
+	port := os.Getenv("PORT")
+	if port == "" {
+		result.OK, result.Detail = true, "не задан, будет использован 8080"
+		return result
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		result.Detail = fmt.Sprintf("некорректное значение %q", port)
+		return result
+	}
+	result.OK, result.Detail = true, port
+	return result
+}
+
+// TLS: либо оба файла, либо ни одного; файлы должны быть доступны для чтения
+func checkTLSConfig() checkResult {
+	result := checkResult{Name: "TLS"}
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+
+	switch {
+	case certFile == "" && keyFile == "":
+		result.OK, result.Detail = true, "не настроен, сервер будет работать по HTTP"
+		return result
+	case certFile == "" || keyFile == "":
+		result.Detail = "нужны оба параметра TLS_CERT_FILE и TLS_KEY_FILE"
+		return result
+	}
+
+	for _, path := range []string{certFile, keyFile} {
+		if _, err := os.ReadFile(path); err != nil {
+			result.Detail = fmt.Sprintf("не удалось прочитать %s: %v", path, err)
+			return result
+		}
+	}
+	result.OK, result.Detail = true, "сертификат и ключ доступны"
+	return result
+}
+
+// Алертинг: токен бота и чат задаются вместе
+func checkAlertConfig() checkResult {
+	result := checkResult{Name: "алертинг"}
+	token, chatIDs := os.Getenv("TELEGRAM_BOT_TOKEN"), parseChatIDs(os.Getenv("TELEGRAM_CHAT_ID"))
+	webhookURL := os.Getenv("ALERT_WEBHOOK_URL")
+
+	var channels []string
+	if token != "" && len(chatIDs) > 0 {
+		channels = append(channels, fmt.Sprintf("Telegram (чатов: %d)", len(chatIDs)))
+	}
+	if webhookURL != "" {
+		if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			result.Detail = fmt.Sprintf("некорректный ALERT_WEBHOOK_URL %q", webhookURL)
+			return result
+		}
+		channels = append(channels, "webhook")
+	}
+
+	switch {
+	case (token == "") != (len(chatIDs) == 0):
+		result.Detail = "нужны оба параметра TELEGRAM_BOT_TOKEN и TELEGRAM_CHAT_ID"
+	case len(channels) == 0:
+		result.OK, result.Detail = true, "отключен"
+	default:
+		thresholds := parseContextThresholds(os.Getenv("ALERT_CONTEXT_THRESHOLDS"))
+		result.OK, result.Detail = true, fmt.Sprintf("%s, отдельных порогов: %d", strings.Join(channels, ", "), len(thresholds))
+	}
+	return result
+}
+
+// Подключение к БД теми же настройками, что и при запуске сервера
+func checkDatabase() checkResult {
+	result := checkResult{Name: "база данных"}
+
+	url, err := resolveDatabaseURL()
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := newDBPool(ctx, url)
+	if err != nil {
+		result.Detail = fmt.Sprintf("%s: %v", maskDBURL(url), err)
+		return result
+	}
+	pool.Close()
+
+	result.OK, result.Detail = true, "подключение к "+maskDBURL(url)+" установлено"
+	return result
+}
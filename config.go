@@ -0,0 +1,65 @@
+// ФАЙЛ: config.go
+// НАЗНАЧЕНИЕ: Чтение настроек приложения из переменных окружения
+// ОСОБЕННОСТИ:
+//   - Значения по умолчанию для всех параметров
+//   - Некорректные значения логируются и заменяются значением по умолчанию
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ФУНКЦИЯ: getEnvBool
+// НАЗНАЧЕНИЕ: Читает булев флаг ("true", "1", "yes" и т.п.)
+func getEnvBool(name string, defaultValue bool) bool {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return defaultValue
+	}
+
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+
+	logger.InfoLogger.Printf("⚠️ Некорректное значение %s=%q, используем %t", name, value, defaultValue)
+	return defaultValue
+}
+
+// ФУНКЦИЯ: getEnvInt
+// НАЗНАЧЕНИЕ: Читает целое число
+func getEnvInt(name string, defaultValue int) int {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logger.InfoLogger.Printf("⚠️ Некорректное значение %s=%q, используем %d", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// ФУНКЦИЯ: getEnvDuration
+// НАЗНАЧЕНИЕ: Читает длительность в формате Go ("30s", "5m", "1h")
+func getEnvDuration(name string, defaultValue time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		logger.InfoLogger.Printf("⚠️ Некорректное значение %s=%q, используем %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
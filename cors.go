@@ -0,0 +1,125 @@
+// ФАЙЛ: cors.go
+// НАЗНАЧЕНИЕ: CORS для браузерных клиентов (SPA на другом домене)
+// ОСОБЕННОСТИ:
+//   - Выключен, пока не задан CORS_ALLOWED_ORIGINS (через запятую, "*" — любой источник)
+//   - Предварительный запрос (OPTIONS с Access-Control-Request-Method) получает 204
+//     и не доходит до обработчиков; CORS_MAX_AGE — сколько браузер кэширует этот ответ
+//   - CORS_EXPOSE_HEADERS — заголовки ответа, которые может прочитать скрипт
+//     (по умолчанию X-Request-ID, ETag, Retry-After и Warning)
+//   - Cookies не разрешаются: API авторизуется заголовком Authorization
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ CORS
+var (
+	// Разрешённые источники (scheme://host[:port]); пусто — CORS выключен
+	corsAllowedOrigins []string
+	// Время кэширования ответа на предварительный запрос (CORS_MAX_AGE, 0 — не отправлять)
+	corsMaxAge = 10 * time.Minute
+	// Заголовки ответа, доступные скрипту (CORS_EXPOSE_HEADERS, пустое значение — не отправлять)
+	corsExposeHeaders = defaultCORSExposeHeaders
+)
+
+// Заголовки, которые API отдаёт клиенту: идентификатор запроса, версия списка,
+// время до повтора (429/503) и предупреждение о пропущенных строках
+const defaultCORSExposeHeaders = "X-Request-ID, ETag, Retry-After, Warning"
+
+// Методы и заголовки запроса, которые разрешаются в ответе на предварительный запрос
+const (
+	corsAllowMethods = "GET, POST, PUT, PATCH, DELETE"
+	corsAllowHeaders = "Authorization, Content-Type, Idempotency-Key, If-Match, If-None-Match, X-Nonce, X-Request-ID, X-Timestamp"
+)
+
+// ИНИЦИАЛИЗАЦИЯ CORS
+func initCORS() {
+	corsAllowedOrigins = nil
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			corsAllowedOrigins = append(corsAllowedOrigins, origin)
+		}
+	}
+
+	corsMaxAge = getEnvDuration("CORS_MAX_AGE", 10*time.Minute)
+	if corsMaxAge < 0 {
+		corsMaxAge = 0
+	}
+
+	corsExposeHeaders = defaultCORSExposeHeaders
+	if headers, ok := os.LookupEnv("CORS_EXPOSE_HEADERS"); ok {
+		corsExposeHeaders = strings.Join(parseHeaderList(headers), ", ")
+	}
+
+	if len(corsAllowedOrigins) > 0 {
+		logger.InfoLogger.Printf("🌐 CORS: источники %s, кэш предварительных запросов %s, доступные заголовки: %q",
+			strings.Join(corsAllowedOrigins, ", "), corsMaxAge, corsExposeHeaders)
+	}
+}
+
+// Разбираем список заголовков через запятую, пустые пропускаем
+func parseHeaderList(raw string) []string {
+	var headers []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			headers = append(headers, name)
+		}
+	}
+	return headers
+}
+
+// Значение Access-Control-Allow-Origin для источника; "" — источник не разрешён
+func corsAllowOrigin(origin string) string {
+	if slices.Contains(corsAllowedOrigins, "*") {
+		return "*"
+	}
+	if slices.Contains(corsAllowedOrigins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// MIDDLEWARE: CORS
+// Запросы без Origin и с неразрешённого источника проходят без CORS-заголовков:
+// браузер сам не отдаст такой ответ скрипту
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(corsAllowedOrigins) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		allowOrigin := corsAllowOrigin(origin)
+		if origin == "" || allowOrigin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		header.Set("Access-Control-Allow-Origin", allowOrigin)
+
+		// Предварительный запрос: отвечаем сами, до ограничений частоты и маршрутов
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			if corsMaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if corsExposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
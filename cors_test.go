@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Запрос с заголовком Origin через corsMiddleware
+func serveCORS(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(method, "/goals", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// ТЕСТ: разрешённый источник видит X-Request-ID и ETag, предварительный запрос кэшируется на CORS_MAX_AGE
+func TestCORSMiddleware(t *testing.T) {
+	t.Cleanup(initCORS) // Выполнится после восстановления окружения
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com/, https://admin.example.com")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	initCORS()
+
+	recorder := serveCORS("GET", "https://app.example.com", nil)
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected allowed origin to be echoed, got %q", got)
+	}
+	if got := recorder.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID, ETag, Retry-After, Warning" {
+		t.Errorf("Expected default exposed headers, got %q", got)
+	}
+
+	recorder = serveCORS("OPTIONS", "https://admin.example.com", map[string]string{"Access-Control-Request-Method": "PUT"})
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected preflight status %d, got %d", http.StatusNoContent, recorder.Code)
+	}
+	if got := recorder.Header().Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("Expected Access-Control-Max-Age 3600, got %q", got)
+	}
+
+	recorder = serveCORS("GET", "https://evil.example.com", nil)
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Access-Control-Allow-Origin for unknown origin, got %q", got)
+	}
+
+	// Свой список заголовков; пустой отключает Access-Control-Expose-Headers
+	t.Setenv("CORS_EXPOSE_HEADERS", " X-Request-ID ,ETag,, X-Total-Count")
+	initCORS()
+	recorder = serveCORS("GET", "https://app.example.com", nil)
+	if got := recorder.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID, ETag, X-Total-Count" {
+		t.Errorf("Expected configured exposed headers, got %q", got)
+	}
+
+	t.Setenv("CORS_EXPOSE_HEADERS", "")
+	initCORS()
+	recorder = serveCORS("GET", "https://app.example.com", nil)
+	if _, present := recorder.Header()["Access-Control-Expose-Headers"]; present {
+		t.Errorf("Expected no Access-Control-Expose-Headers with empty CORS_EXPOSE_HEADERS")
+	}
+}
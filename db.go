@@ -0,0 +1,275 @@
+// ФАЙЛ: db.go
+// НАЗНАЧЕНИЕ: Пул соединений с PostgreSQL и вспомогательные функции для запросов
+// ОСОБЕННОСТИ:
+//   - Соединения пересоздаются до того, как их оборвёт сервер (Heroku Postgres)
+//   - Повтор запроса один раз при обрыве соединения
+//   - Настройки пула из переменных окружения
+//   - Прогрев пула при старте (DB_WARMUP_CONNS), чтобы первые запросы не ждали подключения
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ БД
+var (
+	// Пул соединений, общий для всех обработчиков
+	dbPool *pgxpool.Pool
+	// Таблица целей (GOALS_TABLE). Несколько экземпляров могут делить одну БД,
+	// если у каждого своя таблица или схема
+	goalsTable = "goals"
+)
+
+// Допустимое имя таблицы: идентификатор в нижнем регистре, опционально со схемой.
+// Имя подставляется в SQL напрямую, поэтому ничего сверх этого не пропускаем.
+// Длина ограничена, чтобы производные имена (_archive, _staging, _schema_migrations)
+// уложились в 63 символа PostgreSQL
+var tableNamePattern = regexp.MustCompile(`^([a-z_][a-z0-9_]{0,39}\.)?[a-z_][a-z0-9_]{0,39}$`)
+
+// ФУНКЦИЯ: initGoalsTable
+// НАЗНАЧЕНИЕ: Читает имя таблицы целей из GOALS_TABLE
+// Некорректное имя — фатальная ошибка: молча писать в чужую таблицу "goals" хуже, чем не запуститься
+func initGoalsTable() {
+	name := strings.TrimSpace(os.Getenv("GOALS_TABLE"))
+	if name == "" {
+		return
+	}
+	if !tableNamePattern.MatchString(name) {
+		log.Fatalf("❌ Некорректное имя таблицы GOALS_TABLE=%q: допустимы a-z, 0-9, _ и префикс схемы", name)
+	}
+
+	goalsTable = name
+	logger.InfoLogger.Printf("🗂️ Таблица целей: %s", goalsTable)
+}
+
+// ФУНКЦИЯ: goalsArchiveTable
+// НАЗНАЧЕНИЕ: Таблица архива выполненных целей (рядом с таблицей целей)
+func goalsArchiveTable() string {
+	return goalsTable + "_archive"
+}
+
+// ФУНКЦИЯ: goalsStagingTable
+// НАЗНАЧЕНИЕ: Нежурналируемая таблица для параллельного COPY больших пачек (см. bulk.go)
+func goalsStagingTable() string {
+	return goalsTable + "_staging"
+}
+
+// ФУНКЦИЯ: schemaMigrationsTable
+// НАЗНАЧЕНИЕ: Таблица версий схемы. У каждой таблицы целей своя история миграций,
+// иначе второй экземпляр в той же БД решит, что его таблицы уже созданы
+func schemaMigrationsTable() string {
+	if goalsTable == "goals" {
+		return "schema_migrations"
+	}
+	return goalsTable + "_schema_migrations"
+}
+
+// ФУНКЦИЯ: withTables
+// НАЗНАЧЕНИЕ: Подставляет настроенные имена таблиц вместо {goals}, {goals_archive} и {goals_staging}
+func withTables(query string) string {
+	return strings.NewReplacer("{goals}", goalsTable, "{goals_archive}", goalsArchiveTable(),
+		"{goals_staging}", goalsStagingTable()).Replace(query)
+}
+
+// ФУНКЦИЯ: newDBPool
+// НАЗНАЧЕНИЕ: Создаёт пул соединений и проверяет подключение
+func newDBPool(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пересоздаём соединения раньше, чем их закроет сервер,
+	// иначе запрос может попасть на уже оборванное соединение
+	config.MaxConnLifetime = getEnvDuration("DB_MAX_CONN_LIFETIME", 30*time.Minute)
+	config.MaxConnIdleTime = getEnvDuration("DB_MAX_CONN_IDLE_TIME", 5*time.Minute)
+	// По умолчанию — значение из строки подключения (pool_min_conns) или 0
+	config.MinConns = int32(getEnvInt("DB_MIN_CONNS", int(config.MinConns)))
+	if config.MinConns < 0 || config.MinConns > config.MaxConns {
+		logger.InfoLogger.Printf("⚠️ DB_MIN_CONNS=%d вне диапазона 0..%d, используем 0", config.MinConns, config.MaxConns)
+		config.MinConns = 0
+	}
+
+	// Сессия в UTC: NOW(), приведение к тексту и date_trunc не зависят от настроек сервера.
+	// Явно заданный в строке подключения timezone сохраняется
+	if _, ok := config.ConnConfig.RuntimeParams["timezone"]; !ok {
+		config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// pgxpool подключается лениво, поэтому проверяем соединение явно
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("🏊 Пул соединений создан (MinConns=%d, MaxConns=%d, MaxConnLifetime=%s, MaxConnIdleTime=%s)",
+		config.MinConns, config.MaxConns, config.MaxConnLifetime, config.MaxConnIdleTime)
+	return pool, nil
+}
+
+// ФУНКЦИЯ: warmUpPool
+// НАЗНАЧЕНИЕ: Заранее открывает count соединений и проверяет каждое ping'ом
+// Соединения захватываются одновременно (иначе пул вернёт одно и то же) и
+// отпускаются в пул все вместе. Возвращает число прогретых соединений
+func warmUpPool(ctx context.Context, pool *pgxpool.Pool, count int) (int, error) {
+	count = min(count, int(pool.Config().MaxConns))
+
+	conns := make([]*pgxpool.Conn, 0, count)
+	defer func() {
+		for _, conn := range conns {
+			conn.Release()
+		}
+	}()
+
+	for len(conns) < count {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return len(conns), err
+		}
+		conns = append(conns, conn)
+		if err := conn.Ping(ctx); err != nil {
+			return len(conns) - 1, err
+		}
+	}
+	return len(conns), nil
+}
+
+// ФУНКЦИЯ: isUnixSocketURL
+// НАЗНАЧЕНИЕ: Определяет, что строка подключения указывает на Unix-сокет
+// Путь к сокету задаётся параметром host=/var/run/postgresql в URL
+// (postgres://user@/db?host=/var/run/postgresql) или в формате "ключ=значение".
+// Разбор доверяем pgconn, чтобы поддерживать ровно те формы, что понимает драйвер
+func isUnixSocketURL(url string) bool {
+	config, err := pgconn.ParseConfig(url)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(config.Host, "/")
+}
+
+// ФУНКЦИЯ: retryableQuery
+// НАЗНАЧЕНИЕ: Выполняет читающий запрос и повторяет его один раз, если соединение оборвалось
+// Чтение безопасно повторить, даже если сервер успел его выполнить. Логические ошибки
+// (синтаксис, ограничения, отмена запроса клиентом) не повторяются.
+// Пул при повторе выдаёт другое (живое) соединение
+func retryableQuery(operation string, fn func() error) error {
+	return retryOnce(operation, fn, isConnClosedError)
+}
+
+// ФУНКЦИЯ: withConnRetry
+// НАЗНАЧЕНИЕ: Выполняет изменяющий запрос и повторяет его один раз,
+// только если он точно не дошёл до сервера (pgconn.SafeToRetry).
+// При обрыве посреди запроса повтор мог бы, например, создать цель дважды
+func withConnRetry(operation string, fn func() error) error {
+	return retryOnce(operation, fn, pgconn.SafeToRetry)
+}
+
+// Один повтор fn, если ошибка подходит под retryable
+func retryOnce(operation string, fn func() error, retryable func(error) bool) error {
+	err := fn()
+	if err == nil || !retryable(err) {
+		return err
+	}
+
+	// Соединение закрыто из-за отмены запроса (клиент отключился, истёк таймаут):
+	// повтор с тем же контекстом сразу завершится той же ошибкой
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	logger.InfoLogger.Printf("🔁 Соединение с БД оборвалось в %s, повторяем запрос: %v", operation, err)
+	return fn()
+}
+
+// Проверяем, вызвана ли ошибка обрывом соединения (а не логикой запроса)
+func isConnClosedError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// pgx сам сообщает, что запрос не был отправлен на сервер
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	message := err.Error()
+	return strings.Contains(message, "conn closed") || strings.Contains(message, "connection reset")
+}
+
+// Проверяем, недоступна ли БД (ошибка подключения или обрыв соединения)
+func isDBUnavailable(err error) bool {
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || isConnClosedError(err)
+}
+
+// Статус для запросов, клиент которых отключился до ответа (как в nginx).
+// Ответ уже никто не прочитает, код нужен для логов и метрик
+const statusClientClosedRequest = 499
+
+// Коды SQLSTATE, которые обработчики различают
+const (
+	pgUniqueViolation = "23505" // Нарушение уникальности (дубликат записи)
+	pgQueryCanceled   = "57014" // Запрос отменён по statement_timeout
+)
+
+// ФУНКЦИЯ: classifyDBError
+// НАЗНАЧЕНИЕ: HTTP-статус и безопасное для клиента сообщение по ошибке БД
+//   - 503 — БД недоступна (подключение, обрыв, нехватка ресурсов на сервере)
+//   - 409 — нарушено ограничение целостности (дубликат, внешний ключ, CHECK)
+//   - 504 — истёк таймаут запроса
+//   - 499 — клиент отключился, запрос к БД отменён вместе с r.Context()
+//   - 500 — всё остальное, с сообщением fallback от обработчика
+//
+// Текст исходной ошибки клиенту не отдаётся: он попадает только в лог
+func classifyDBError(err error, fallback string) (int, string) {
+	var pgErr *pgconn.PgError
+	isPgErr := errors.As(err, &pgErr)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, "Клиент закрыл соединение"
+	case isDBUnavailable(err),
+		isPgErr && (strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53")):
+		return http.StatusServiceUnavailable, "Ошибка подключения к БД"
+	case isPgErr && pgErr.Code == pgUniqueViolation:
+		return http.StatusConflict, "Такая цель уже существует"
+	case isPgErr && strings.HasPrefix(pgErr.Code, "23"):
+		return http.StatusConflict, "Нарушено ограничение целостности данных"
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err),
+		isPgErr && pgErr.Code == pgQueryCanceled:
+		return http.StatusGatewayTimeout, "Превышено время ожидания ответа БД"
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// ФУНКЦИЯ: writeDBError
+// НАЗНАЧЕНИЕ: Отвечает клиенту статусом и сообщением из classifyDBError
+func writeDBError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	status, message := classifyDBError(err, fallback)
+	http.Error(w, message, status)
+	logger.LogRequest(r.Method, r.URL.Path, status)
+}
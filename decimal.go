@@ -0,0 +1,113 @@
+// ФАЙЛ: decimal.go
+// НАЗНАЧЕНИЕ: Десятичное число без потери точности для денежных значений
+// ОСОБЕННОСТИ:
+//   - Хранится как строка в десятичной записи ("1500", "1499.99")
+//   - В JSON выводится числом, на входе принимает число или строку с числом
+//   - В PostgreSQL читается и пишется как NUMERIC через pgtype.Numeric,
+//     поэтому копейки не искажаются округлением float
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Допустимая запись: необязательный минус, цифры, необязательная дробная часть
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// ТИП: Decimal
+// Пустое значение означает ноль (поле не передано)
+type Decimal string
+
+// ФУНКЦИЯ: parseDecimal
+// НАЗНАЧЕНИЕ: Проверяет запись числа и возвращает Decimal
+// Ведущие нули целой части отбрасываются ("0150" → "150", "-00.5" → "-0.5"):
+// MarshalJSON выводит значение как есть, а JSON не допускает чисел с ведущим нулём
+func parseDecimal(value string) (Decimal, bool) {
+	value = strings.TrimSpace(value)
+	if !decimalPattern.MatchString(value) {
+		return "", false
+	}
+
+	sign, digits := "", value
+	if rest, negative := strings.CutPrefix(value, "-"); negative {
+		sign, digits = "-", rest
+	}
+	integer, fraction, hasFraction := strings.Cut(digits, ".")
+	integer = strings.TrimLeft(integer, "0")
+	if integer == "" {
+		integer = "0"
+	}
+	if hasFraction {
+		return Decimal(sign + integer + "." + fraction), true
+	}
+	return Decimal(sign + integer), true
+}
+
+// Строковое представление (пустое значение — "0")
+func (d Decimal) String() string {
+	if d == "" {
+		return "0"
+	}
+	return string(d)
+}
+
+// Отрицательное ли число ("-0" и "-0.00" отрицательными не считаются)
+func (d Decimal) IsNegative() bool {
+	return strings.HasPrefix(string(d), "-") && strings.Trim(string(d), "-0.") != ""
+}
+
+// MarshalJSON выводит число без кавычек, чтобы клиенты с целыми значениями не заметили разницы
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON принимает 1500, 1499.99 или "1499.99"
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	if raw == "null" {
+		*d = ""
+		return nil
+	}
+	if unquoted, ok := strings.CutPrefix(raw, `"`); ok {
+		raw = strings.TrimSuffix(unquoted, `"`)
+	}
+
+	value, ok := parseDecimal(raw)
+	if !ok {
+		return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeOf(*d), Field: "salary_target_rub_per_hour"}
+	}
+	*d = value
+	return nil
+}
+
+// ScanNumeric читает NUMERIC из PostgreSQL (pgtype.NumericScanner)
+func (d *Decimal) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*d = ""
+		return nil
+	}
+	// NaN и бесконечность NUMERIC допускает, но в JSON их не записать
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return errors.New("NaN и бесконечность не поддерживаются")
+	}
+	text, err := v.Value()
+	if err != nil {
+		return err
+	}
+	*d = Decimal(text.(string))
+	return nil
+}
+
+// NumericValue передаёт значение в PostgreSQL как NUMERIC (pgtype.NumericValuer)
+func (d Decimal) NumericValue() (pgtype.Numeric, error) {
+	var n pgtype.Numeric
+	err := n.Scan(d.String())
+	return n, err
+}
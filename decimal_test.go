@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ТЕСТ: Decimal сохраняет точность при разборе JSON и обмене с pgtype.Numeric
+func TestDecimalRoundTrip(t *testing.T) {
+	cases := map[string]string{
+		`1500`:      "1500",
+		`1499.99`:   "1499.99",
+		`"1499.99"`: "1499.99",
+		`0.10`:      "0.10",
+		`-3`:        "-3",
+		`"0150"`:    "150",
+		`"-00.5"`:   "-0.5",
+		`"000"`:     "0",
+		`"007.50"`:  "7.50",
+	}
+
+	for input, expected := range cases {
+		var d Decimal
+		if err := json.Unmarshal([]byte(input), &d); err != nil {
+			t.Errorf("%s: unexpected error %v", input, err)
+			continue
+		}
+
+		numeric, err := d.NumericValue()
+		if err != nil {
+			t.Errorf("%s: NumericValue failed: %v", input, err)
+			continue
+		}
+		var back Decimal
+		if err := back.ScanNumeric(numeric); err != nil {
+			t.Errorf("%s: ScanNumeric failed: %v", input, err)
+			continue
+		}
+
+		out, _ := json.Marshal(back)
+		if string(out) != expected {
+			t.Errorf("%s: expected %s, got %s", input, expected, out)
+		}
+	}
+
+	for _, input := range []string{`"много"`, `true`, `"1e5"`, `"1,5"`} {
+		var d Decimal
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("%s: expected error, got %q", input, d)
+		}
+	}
+}
+
+// ТЕСТ: Отрицательные значения
+func TestDecimalIsNegative(t *testing.T) {
+	cases := map[Decimal]bool{"-1": true, "-0.01": true, "-0": false, "-0.00": false, "0": false, "": false, "5": false}
+	for value, expected := range cases {
+		if got := value.IsNegative(); got != expected {
+			t.Errorf("%q: expected %v, got %v", value, expected, got)
+		}
+	}
+}
+
+// ТЕСТ: NaN и бесконечность из NUMERIC не читаются (в JSON их не записать)
+func TestDecimalScanNumericRejectsNaN(t *testing.T) {
+	for _, value := range []pgtype.Numeric{
+		{NaN: true, Valid: true},
+		{InfinityModifier: pgtype.Infinity, Valid: true},
+		{InfinityModifier: pgtype.NegativeInfinity, Valid: true},
+	} {
+		var d Decimal
+		if err := d.ScanNumeric(value); err == nil {
+			t.Errorf("Expected error for %+v, got %q", value, d)
+		}
+	}
+}
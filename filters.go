@@ -0,0 +1,128 @@
+// ФАЙЛ: filters.go
+// НАЗНАЧЕНИЕ: Фильтры списка целей из query-параметров
+// ОСОБЕННОСТИ:
+//   - Общие для GET /goals, GET /goals/ndjson и GET /goals/count
+//   - ?tag= ищет метку в массиве tags (WHERE $1 = ANY(tags))
+//   - Все значения передаются в SQL только через параметры ($1, $2...)
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// СТРУКТУРА ФИЛЬТРА СПИСКА ЦЕЛЕЙ
+type goalFilter struct {
+	MinSalary     *Decimal   // ?min_salary= — минимальная целевая зарплата
+	Query         string     // ?q= — поиск подстроки в тексте цели и сроке
+	CreatedAfter  *time.Time // ?created_after= — созданные не раньше (RFC3339, включительно)
+	CreatedBefore *time.Time // ?created_before= — созданные раньше (RFC3339, не включительно)
+	Tag           string     // ?tag= — цели с этой меткой
+}
+
+// СТРУКТУРА ОШИБКИ ФИЛЬТРА
+type filterError struct {
+	Param   string
+	Message string
+}
+
+func (e *filterError) Error() string {
+	return "параметр " + e.Param + ": " + e.Message
+}
+
+// ФУНКЦИЯ: parseGoalFilter
+// НАЗНАЧЕНИЕ: Читает фильтры из query-параметров запроса
+func parseGoalFilter(r *http.Request) (goalFilter, error) {
+	var f goalFilter
+	query := r.URL.Query()
+
+	if value := strings.TrimSpace(query.Get("min_salary")); value != "" {
+		minSalary, ok := parseDecimal(value)
+		if !ok {
+			return f, &filterError{Param: "min_salary", Message: "должен быть числом"}
+		}
+		f.MinSalary = &minSalary
+	}
+
+	f.Query = strings.TrimSpace(query.Get("q"))
+
+	if value := strings.TrimSpace(query.Get("tag")); value != "" {
+		tag := normalizeTag(value)
+		if !isValidTag(tag) {
+			return f, &filterError{Param: "tag", Message: tagRulesMessage}
+		}
+		f.Tag = tag
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"created_after", &f.CreatedAfter},
+		{"created_before", &f.CreatedBefore},
+	} {
+		value := strings.TrimSpace(query.Get(param.name))
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return f, &filterError{Param: param.name, Message: "должен быть временем в формате RFC3339"}
+		}
+		*param.dst = &parsed
+	}
+
+	return f, nil
+}
+
+// Пустой ли фильтр (запрос всего списка)
+func (f goalFilter) isEmpty() bool {
+	return f.MinSalary == nil && f.Query == "" && f.CreatedAfter == nil && f.CreatedBefore == nil && f.Tag == ""
+}
+
+// ФУНКЦИЯ: whereClause
+// НАЗНАЧЕНИЕ: Строит " WHERE ..." и список параметров для SQL-запроса
+func (f goalFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.MinSalary != nil {
+		args = append(args, *f.MinSalary)
+		conditions = append(conditions, "salary_target >= $"+strconv.Itoa(len(args)))
+	}
+	if f.Query != "" {
+		// Экранируем спецсимволы LIKE, чтобы % и _ искались буквально
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(f.Query) + "%"
+		args = append(args, pattern)
+		n := strconv.Itoa(len(args))
+		conditions = append(conditions, "(goal ILIKE $"+n+" OR timeline ILIKE $"+n+")")
+	}
+
+	if f.CreatedAfter != nil {
+		args = append(args, *f.CreatedAfter)
+		conditions = append(conditions, "created_at >= $"+strconv.Itoa(len(args)))
+	}
+	if f.CreatedBefore != nil {
+		args = append(args, *f.CreatedBefore)
+		conditions = append(conditions, "created_at < $"+strconv.Itoa(len(args)))
+	}
+	if f.Tag != "" {
+		args = append(args, f.Tag)
+		conditions = append(conditions, "$"+strconv.Itoa(len(args))+" = ANY(tags)")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ФУНКЦИЯ: writeFilterError
+// НАЗНАЧЕНИЕ: Отвечает 400 на некорректный фильтр
+func writeFilterError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, "Неверный фильтр: "+err.Error(), http.StatusBadRequest)
+	logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+}
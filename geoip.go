@@ -0,0 +1,144 @@
+// ФАЙЛ: geoip.go
+// НАЗНАЧЕНИЕ: Определение страны клиента по IP для журналов
+// ОСОБЕННОСТИ:
+//   - База MaxMind (mmdb) подключается через GEOIP_DB, без неё всё работает как раньше
+//   - Результаты кешируются по IP, чтобы не читать базу на каждый запрос
+//   - Источник данных скрыт за интерфейсом countryLookup (в тестах — заглушка)
+//   - Запросы из стран BLOCKED_COUNTRIES отклоняются в securityMiddleware
+
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// ИНТЕРФЕЙС ОПРЕДЕЛЕНИЯ СТРАНЫ
+// Возвращает ISO-код страны ("RU", "DE") или пустую строку, если страна неизвестна
+type countryLookup interface {
+	Country(ip string) (string, error)
+}
+
+// Максимум IP в кеше стран; при переполнении кеш очищается целиком
+const maxGeoCacheEntries = 10000
+
+var (
+	// Источник стран (nil — GeoIP не настроен)
+	geoLookup countryLookup
+	// Кеш IP → код страны (пустая строка тоже кешируется)
+	geoCache      = make(map[string]string)
+	geoCacheMutex sync.Mutex
+	// Запрещённые страны (BLOCKED_COUNTRIES: ISO-коды через запятую)
+	blockedCountries = make(map[string]bool)
+)
+
+// Поиск по базе MaxMind
+type mmdbLookup struct {
+	reader *geoip2.Reader
+}
+
+func (l mmdbLookup) Country(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", nil
+	}
+	record, err := l.reader.Country(parsed)
+	if err != nil {
+		return "", err
+	}
+	return record.Country.IsoCode, nil
+}
+
+// ИНИЦИАЛИЗАЦИЯ GEOIP
+// Если GEOIP_DB не задан или файл не открывается, страна просто не попадает в логи
+func initGeoIP() {
+	blockedCountries = parseCountryList(os.Getenv("BLOCKED_COUNTRIES"))
+
+	path := strings.TrimSpace(os.Getenv("GEOIP_DB"))
+	if path == "" {
+		if len(blockedCountries) > 0 {
+			logger.InfoLogger.Printf("⚠️ BLOCKED_COUNTRIES задан, но GEOIP_DB не настроен — блокировка по странам не работает")
+		}
+		return
+	}
+
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		logger.ErrorLogger.Printf("⚠️ Не удалось открыть базу GeoIP %s (%v), страна клиента не определяется", path, err)
+		return
+	}
+	setGeoLookup(mmdbLookup{reader: reader})
+	logger.InfoLogger.Printf("🌍 GeoIP включён: %s (%s)", path, reader.Metadata().DatabaseType)
+	if len(blockedCountries) > 0 {
+		logger.InfoLogger.Printf("🚫 Запрещённые страны: %d", len(blockedCountries))
+	}
+}
+
+// Разбор списка ISO-кодов стран: регистр и пробелы не важны, пустые элементы пропускаются
+func parseCountryList(raw string) map[string]bool {
+	countries := make(map[string]bool)
+	for _, code := range strings.Split(raw, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" {
+			countries[code] = true
+		}
+	}
+	return countries
+}
+
+// ФУНКЦИЯ: isBlockedCountry
+// НАЗНАЧЕНИЕ: Возвращает страну IP, если она в BLOCKED_COUNTRIES
+// Без GeoIP страна неизвестна, и запрос всегда пропускается
+func isBlockedCountry(ip string) (string, bool) {
+	if len(blockedCountries) == 0 {
+		return "", false
+	}
+	country := clientCountry(ip)
+	return country, country != "" && blockedCountries[country]
+}
+
+// Замена источника стран со сбросом кеша
+func setGeoLookup(lookup countryLookup) {
+	geoCacheMutex.Lock()
+	defer geoCacheMutex.Unlock()
+	geoLookup = lookup
+	geoCache = make(map[string]string)
+}
+
+// ФУНКЦИЯ: clientCountry
+// НАЗНАЧЕНИЕ: Код страны для IP или пустая строка, если GeoIP не настроен или страна неизвестна
+func clientCountry(ip string) string {
+	geoCacheMutex.Lock()
+	defer geoCacheMutex.Unlock()
+
+	if geoLookup == nil {
+		return ""
+	}
+	if country, ok := geoCache[ip]; ok {
+		return country
+	}
+
+	country, err := geoLookup.Country(ip)
+	if err != nil {
+		// Ошибку не кешируем: возможно, следующий поиск будет удачным
+		logger.LogError(err, "Ошибка поиска страны для IP "+ip)
+		return ""
+	}
+	if len(geoCache) >= maxGeoCacheEntries {
+		geoCache = make(map[string]string)
+	}
+	geoCache[ip] = country
+	return country
+}
+
+// Суффикс со страной для строк журнала; пустой, если страна неизвестна
+func countryLogSuffix(ip string) string {
+	if country := clientCountry(ip); country != "" {
+		return " | COUNTRY: " + country
+	}
+	return ""
+}
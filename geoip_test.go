@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// Заглушка GeoIP: фиксированные страны и счётчик обращений
+type fakeCountryLookup struct {
+	countries map[string]string
+	calls     int
+	err       error
+}
+
+func (f *fakeCountryLookup) Country(ip string) (string, error) {
+	f.calls++
+	return f.countries[ip], f.err
+}
+
+// ТЕСТ: Страна кешируется по IP, ошибки не кешируются
+func TestClientCountryCache(t *testing.T) {
+	lookup := &fakeCountryLookup{countries: map[string]string{"203.0.113.5": "DE"}}
+	setGeoLookup(lookup)
+	defer setGeoLookup(nil)
+
+	for i := 0; i < 3; i++ {
+		if country := clientCountry("203.0.113.5"); country != "DE" {
+			t.Fatalf("Expected DE, got %q", country)
+		}
+	}
+	if lookup.calls != 1 {
+		t.Errorf("Expected 1 lookup thanks to caching, got %d", lookup.calls)
+	}
+	if suffix := countryLogSuffix("203.0.113.5"); suffix != " | COUNTRY: DE" {
+		t.Errorf("Unexpected log suffix %q", suffix)
+	}
+
+	lookup.err = errors.New("broken db")
+	clientCountry("198.51.100.1")
+	clientCountry("198.51.100.1")
+	if lookup.calls != 3 {
+		t.Errorf("Expected failed lookups not to be cached, got %d calls", lookup.calls)
+	}
+}
+
+// ТЕСТ: Без GeoIP страна не определяется
+func TestClientCountryDisabled(t *testing.T) {
+	setGeoLookup(nil)
+	if country := clientCountry("203.0.113.5"); country != "" {
+		t.Errorf("Expected empty country without GeoIP, got %q", country)
+	}
+	if suffix := countryLogSuffix("203.0.113.5"); suffix != "" {
+		t.Errorf("Expected empty log suffix without GeoIP, got %q", suffix)
+	}
+}
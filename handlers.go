@@ -1,8 +1,8 @@
 // ФАЙЛ: handlers.go
 // НАЗНАЧЕНИЕ: Обработчики HTTP-запросов для CRUD-операций
 // ОСОБЕННОСТИ:
-//   - Использование глобальной переменной dbURL для переключения баз
-//   - Таймауты подключения к БД
+//   - Данные читаются и пишутся через хранилище goalStore (см. store.go)
+//   - Таймауты запросов к БД
 //   - Полное логирование всех этапов
 
 package main
@@ -11,11 +11,11 @@ package main
 import (
 	"context"       // Для контекста с таймаутами
 	"encoding/json" // Для работы с JSON
+	"errors"        // Для проверки errGoalNotFound
+	"fmt"           // Для сообщений с параметрами
 	"net/http"      // Для HTTP-обработки
 	"strconv"       // Для преобразования ID (используется в update/delete)
 	"time"          // Для работы со временем (поле created_at)
-
-	"github.com/jackc/pgx/v5" // PostgreSQL драйвер
 )
 
 // СТРУКТУРА ДАННЫХ ЦЕЛИ
@@ -24,67 +24,233 @@ type Goal struct {
 	ID           int       `json:"id"`                         // Уникальный ID (SERIAL в БД)
 	Goal         string    `json:"goal"`                       // Текст цели
 	Timeline     string    `json:"timeline"`                   // Срок выполнения
-	SalaryTarget int       `json:"salary_target_rub_per_hour"` // Целевая зарплата
+	SalaryTarget Decimal   `json:"salary_target_rub_per_hour"` // Целевая зарплата (NUMERIC, без потери копеек)
+	Currency     string    `json:"currency"`                   // Валюта зарплаты (ISO 4217, по умолчанию RUB)
+	Tags         []string  `json:"tags"`                       // Метки-категории (career, health...), пустой список — без меток
 	CreatedAt    time.Time `json:"created_at"`                 // Время создания
 }
 
+// ФУНКЦИЯ: listGoalsQuery
+// НАЗНАЧЕНИЕ: SQL-запрос списка целей с фильтрами
+// Общий для GET /goals и GET /goals/ndjson, чтобы выгрузки совпадали со списком
+func listGoalsQuery(f goalFilter) (string, []interface{}) {
+	where, args := f.whereClause()
+	return "SELECT " + goalColumns + " FROM " + goalsTable + where + " ORDER BY created_at ASC", args
+}
+
 // ОБРАБОТЧИК: GET /goals
-// Получение всех целей из базы данных registeHandlers
+// Получение всех целей из базы данных
+// С ?wait=N и If-None-Match ждёт до N секунд, пока список не изменится (long polling)
 func getGoalsHandler(w http.ResponseWriter, r *http.Request) {
-	http.Handle("/test-panic", alertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		panic("Тестовая паника для проверки алертинга")
-	})))
-	// // ШАГ 1: ЛОГИРУЕМ НАЧАЛО ОБРАБОТКИ
+	// ШАГ 1: ЛОГИРУЕМ НАЧАЛО ОБРАБОТКИ
 	// Временный статус 0, будет обновлён позже
 	logger.LogRequest(r.Method, r.URL.Path, 0)
 
-	// ШАГ 2: ПОДКЛЮЧЕНИЕ К БД С ТАЙМАУТОМ 5 СЕКУНД
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel() // Гарантируем отмену контекста
-
-	conn, err := pgx.Connect(context.Background(), dbURL)
+	// ШАГ 2: РАЗБОР ФИЛЬТРОВ И ?wait=
+	filter, err := parseGoalFilter(r)
+	if err != nil {
+		writeFilterError(w, r, err)
+		return
+	}
+	wait, err := parseWait(r)
 	if err != nil {
-		// ЛОГИРУЕМ ОШИБКУ ПОДКЛЮЧЕНИЯ
-		logger.LogError(err, "Подключение к БД в getGoalsHandler")
-		// ОТПРАВЛЯЕМ ОТВЕТ 500
-		http.Error(w, "Database connection error", http.StatusInternalServerError)
-		// ЛОГИРУЕМ ФАКТИЧЕСКИЙ СТАТУС
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		writeFilterError(w, r, err)
 		return
 	}
-	defer conn.Close(ctx) // Гарантируем закрытие соединения
 
-	// ШАГ 3: ВЫПОЛНЕНИЕ SQL-ЗАПРОСА
-	// Сортируем по времени создания (старые записи первыми)
-	rows, err := conn.Query(ctx,
-		"SELECT id, goal, timeline, salary_target, created_at FROM goals ORDER BY created_at ASC")
+	// Подписываемся до чтения, чтобы не пропустить запись между SELECT и ожиданием
+	changed := goalsChanged()
+	// По той же причине поколение кэша запоминаем до SELECT
+	generation := cacheGeneration.Load()
+
+	// ШАГ 3: ЧТЕНИЕ СПИСКА
+	goals, err := queryGoals(r.Context(), filter)
+	err = warnSkippedRows(w, err)
 	if err != nil {
-		logger.LogError(err, "Ошибка выполнения SELECT в getGoalsHandler")
-		http.Error(w, "Query error", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close() // Закрываем курсор после использования
-
-	// ШАГ 4: СБОР ДАННЫХ В СТРУКТУРЫ
-	var goals []Goal
-	for rows.Next() { // Перебираем все строки результата
-		var g Goal
-		// Сканируем данные из строки в структуру
-		if err := rows.Scan(&g.ID, &g.Goal, &g.Timeline, &g.SalaryTarget, &g.CreatedAt); err != nil {
-			logger.LogError(err, "Ошибка сканирования строки в getGoalsHandler")
-			http.Error(w, "Scan error", http.StatusInternalServerError)
-			logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		logger.LogError(err, "Ошибка чтения списка в getGoalsHandler")
+		// ПРОБУЕМ ОТДАТЬ ПОСЛЕДНИЙ УСПЕШНЫЙ СПИСОК ИЗ КЭША
+		// (в кэше лежит только полный список, поэтому для фильтров он не подходит;
+		// отключившемуся клиенту отвечать незачем)
+		if filter.isEmpty() && r.Context().Err() == nil && serveCachedGoals(w, r) {
 			return
 		}
-		goals = append(goals, g) // Добавляем в срез
+		writeDBError(w, r, err, "Query error")
+		return
+	}
+
+	// Запоминаем успешный результат на случай недоступности БД
+	if filter.isEmpty() {
+		storeCachedGoals(goals, generation)
+	}
+
+	// ШАГ 4: LONG POLLING — У КЛИЕНТА УЖЕ АКТУАЛЬНЫЙ СПИСОК
+	if wait > 0 && goalsUnchangedForClient(r, goals) {
+		if !waitForGoalsChange(r, changed, wait) {
+			logger.LogRequest(r.Method, r.URL.Path, statusClientClosedRequest)
+			return
+		}
+
+		goals, err = queryGoals(r.Context(), filter)
+		err = warnSkippedRows(w, err)
+		if err != nil {
+			logger.LogError(err, "Ошибка повторного чтения списка в getGoalsHandler")
+			writeDBError(w, r, err, "Query error")
+			return
+		}
+
+		// После ожидания всегда отдаём текущий список, а не 304
+		r.Header.Del("If-None-Match")
 	}
 
 	// ШАГ 5: ОТПРАВКА УСПЕШНОГО ОТВЕТА
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(goals) // Кодируем срез в JSON
-	// ЛОГИРУЕМ ФАКТИЧЕСКИЙ СТАТУС 200
+	// С заголовками кэширования и поддержкой If-None-Match (304)
+	writeCacheableJSON(w, r, goalListPayload(goals, false))
+}
+
+// ФУНКЦИЯ: queryGoals
+// НАЗНАЧЕНИЕ: Читает список целей с фильтрами (таймаут 5 секунд)
+func queryGoals(parent context.Context, filter goalFilter) ([]Goal, error) {
+	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	defer cancel() // Гарантируем отмену контекста
+
+	// Сортируем по времени создания (старые записи первыми)
+	return goalStore.List(ctx, filter)
+}
+
+// Пропущенные при чтении строки — не ошибка запроса: клиент получает
+// остальные цели и заголовок Warning с числом пропущенных строк
+func warnSkippedRows(w http.ResponseWriter, err error) error {
+	var skipped *skippedRowsError
+	if !errors.As(err, &skipped) {
+		return err
+	}
+	w.Header().Set("Warning", fmt.Sprintf(`199 - "%d row(s) skipped due to read errors"`, skipped.Count))
+	return nil
+}
+
+// Совпадает ли ETag текущего списка с If-None-Match клиента
+func goalsUnchangedForClient(r *http.Request, goals []Goal) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	body, err := encodeJSON(r, goalListPayload(goals, false))
+	if err != nil {
+		return false
+	}
+	return etagMatches(ifNoneMatch, computeETag(body))
+}
+
+// ОБРАБОТЧИК: GET /goals/count
+// Количество целей с теми же фильтрами, что и у списка
+func countGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: РАЗБОР ФИЛЬТРОВ
+	filter, err := parseGoalFilter(r)
+	if err != nil {
+		writeFilterError(w, r, err)
+		return
+	}
+
+	// ШАГ 3: ПОДСЧЁТ В БД
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	count, err := goalStore.Count(ctx, filter)
+	if err != nil {
+		logger.LogError(err, "Ошибка выполнения COUNT в countGoalsHandler")
+		writeDBError(w, r, err, "Query error")
+		return
+	}
+
+	// ШАГ 4: ОТПРАВКА ОТВЕТА
+	writeJSON(w, r, http.StatusOK, map[string]int64{"count": count})
+}
+
+// Сколько строк NDJSON отправлять клиенту за один Flush
+const ndjsonFlushEvery = 100
+
+// ОБРАБОТЧИК: GET /goals/ndjson
+// Потоковая выгрузка целей в формате JSON Lines (одна цель — одна строка)
+func exportGoalsNDJSONHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: РАЗБОР ФИЛЬТРОВ (те же, что у GET /goals)
+	filter, err := parseGoalFilter(r)
+	if err != nil {
+		writeFilterError(w, r, err)
+		return
+	}
+
+	// ШАГ 3: ПОТОКОВОЕ ЧТЕНИЕ И ЗАПИСЬ СТРОК
+	// Без фиксированного таймаута: большая выгрузка может идти долго,
+	// а отключение клиента отменит r.Context() и прервёт запрос
+	ctx := r.Context()
+
+	// Заголовки отправляются с первой строкой (или после пустого результата),
+	// чтобы ошибка запроса до начала выгрузки ещё могла стать ответом 500
+	started := false
+	start := func() {
+		started = true
+		// Снимаем WriteTimeout сервера: выгрузка может идти дольше обычного ответа
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logger.LogError(err, "Не удалось снять таймаут записи для NDJSON-выгрузки")
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w) // Encode добавляет перевод строки после каждого объекта
+	written := 0
+	err = goalStore.Each(ctx, filter, func(g Goal) error {
+		if !started {
+			start()
+		}
+		if err := encoder.Encode(g); err != nil {
+			return fmt.Errorf("ошибка записи NDJSON (клиент отключился?): %w", err)
+		}
+
+		written++
+		if flusher != nil && written%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	// Заголовки к этому моменту обычно отправлены, поэтому о пропущенных строках — только в лог
+	var skipped *skippedRowsError
+	if errors.As(err, &skipped) {
+		logger.InfoLogger.Printf("⚠️ NDJSON-выгрузка: %v", skipped)
+		err = nil
+	}
+	if err != nil {
+		logger.LogError(err, "Ошибка выгрузки в exportGoalsNDJSONHandler")
+		if !started {
+			writeDBError(w, r, err, "Query error")
+		}
+		// Заголовки уже отправлены, поэтому просто обрываем поток
+		return
+	}
+	if !started {
+		start()
+	}
+
+	logger.InfoLogger.Printf("📤 NDJSON-выгрузка завершена: %d целей", written)
 	logger.LogRequest(r.Method, r.URL.Path, http.StatusOK)
 }
 
@@ -102,42 +268,195 @@ func createGoalHandler(w http.ResponseWriter, r *http.Request) {
 
 	// ШАГ 2: ДЕКОДИРОВАНИЕ JSON ИЗ ТЕЛА ЗАПРОСА
 	var newGoal Goal
-	if err := json.NewDecoder(r.Body).Decode(&newGoal); err != nil {
+	if err := decodeGoalBody(w, r, &newGoal); err != nil {
 		logger.LogError(err, "Ошибка декодирования JSON в createGoalHandler")
-		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// created_at из тела сохраняется только для администратора (перенос данных
+	// из другой системы); обычные клиенты не могут задним числом менять дату создания
+	if !newGoal.CreatedAt.IsZero() && !isAdminRequest(r) {
+		logger.InfoLogger.Printf("ℹ️ created_at из тела проигнорирован: нужен токен администратора (IP %s)", getIP(r))
+		newGoal.CreatedAt = time.Time{}
+	}
+
+	// Проверяем поля до обращения к БД (после подстановки значений по умолчанию)
+	applyNewGoalDefaults(&newGoal)
+	applyGoalDefaults(&newGoal)
+	if errs := validateGoal(newGoal); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// ШАГ 3: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// ШАГ 4: ВСТАВКА ЗАПИСИ В БАЗУ (с проверкой лимита MAX_GOALS_PER_USER)
+	err := goalStore.Create(ctx, &newGoal)
+	if errors.Is(err, errGoalLimitReached) {
+		logger.LogError(err, "Лимит целей в createGoalHandler")
+		http.Error(w, fmt.Sprintf("Достигнут лимит количества целей (%d). Удалите ненужные цели и повторите запрос", maxGoalsPerUser), http.StatusConflict)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.LogError(err, "Ошибка вставки в БД в createGoalHandler")
+		writeDBError(w, r, err, "Ошибка записи в БД")
+		return
+	}
+
+	invalidateCachedGoals()
+
+	// ШАГ 5: ОТПРАВКА СОЗДАННОЙ ЗАПИСИ (ETag — для If-Match при изменении)
+	w.Header().Set("ETag", goalETag(newGoal))
+	writeJSON(w, r, http.StatusCreated, newGoal) // 201 Created
+}
+
+// ФУНКЦИЯ: duplicateGoalHandler
+// НАЗНАЧЕНИЕ: Создаёт копию существующей цели (POST /goals/{id}/duplicate)
+// Копия получает новый ID, текущее время создания и суффикс " (copy)" в тексте цели
+func duplicateGoalHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: ИЗВЛЕЧЕНИЕ ID ИЗ URL (/goals/{id}/duplicate)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		logger.LogError(err, "Неверный ID в duplicateGoalHandler")
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
 		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
 		return
 	}
 
-	// ШАГ 3: ПОДКЛЮЧЕНИЕ К БД
+	// ШАГ 3: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
-	conn, err := pgx.Connect(ctx, dbURL)
+
+	// ШАГ 4: КОПИРОВАНИЕ ЗАПИСИ
+	goal, err := goalStore.Duplicate(ctx, id)
+	if errors.Is(err, errGoalNotFound) {
+		errMsg := "Запись не найдена"
+		logger.LogError(nil, errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errGoalLimitReached) {
+		logger.LogError(err, "Лимит целей в duplicateGoalHandler")
+		http.Error(w, fmt.Sprintf("Достигнут лимит количества целей (%d). Удалите ненужные цели и повторите запрос", maxGoalsPerUser), http.StatusConflict)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusConflict)
+		return
+	}
 	if err != nil {
-		logger.LogError(err, "Подключение к БД в createGoalHandler")
-		http.Error(w, "Ошибка подключения к БД", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		logger.LogError(err, "Ошибка копирования в БД в duplicateGoalHandler")
+		writeDBError(w, r, err, "Ошибка записи в БД")
 		return
 	}
-	defer conn.Close(ctx)
 
-	// ШАГ 4: ВСТАВКА ЗАПИСИ В БАЗУ
-	// NOW() автоматически устанавливает текущее время
-	// RETURNING id возвращает сгенерированный ID
-	query := `INSERT INTO goals (goal, timeline, salary_target, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id`
-	err = conn.QueryRow(ctx, query, newGoal.Goal, newGoal.Timeline, newGoal.SalaryTarget).Scan(&newGoal.ID)
+	invalidateCachedGoals()
+
+	// ШАГ 5: ОТПРАВКА СОЗДАННОЙ КОПИИ
+	w.Header().Set("ETag", goalETag(goal))
+	writeJSON(w, r, http.StatusCreated, goal) // 201 Created
+}
+
+// ФУНКЦИЯ: toggleGoalHandler
+// НАЗНАЧЕНИЕ: Переключает отметку о выполнении цели (POST /goals/{id}/toggle)
+// Клиенту не нужно знать текущее состояние: в ответе приходит новое
+func toggleGoalHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: ИЗВЛЕЧЕНИЕ ID ИЗ URL (/goals/{id}/toggle)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		logger.LogError(err, "Ошибка вставки в БД в createGoalHandler")
-		http.Error(w, "Ошибка записи в БД", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		logger.LogError(err, "Неверный ID в toggleGoalHandler")
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+
+	// ШАГ 3: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// ШАГ 4: ПЕРЕКЛЮЧЕНИЕ В БД
+	state, err := goalStore.ToggleCompleted(ctx, id)
+	if errors.Is(err, errGoalNotFound) {
+		errMsg := "Запись не найдена"
+		logger.LogError(nil, errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.LogError(err, "Ошибка переключения в БД в toggleGoalHandler")
+		writeDBError(w, r, err, "Ошибка обновления в БД")
+		return
+	}
+
+	invalidateCachedGoals()
+
+	// ШАГ 5: ОТПРАВКА НОВОГО СОСТОЯНИЯ
+	writeJSON(w, r, http.StatusOK, state)
+}
+
+// ОБРАБОТЧИК: GET /goals/{id}
+// Одна цель с её ETag: его передают в If-Match при изменении (PUT /goals/{id})
+func getGoalHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ИЗВЛЕЧЕНИЕ ID ИЗ URL
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		logger.LogError(err, "Неверный ID в getGoalHandler")
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+
+	// ШАГ 2: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// ШАГ 3: ЧТЕНИЕ ЗАПИСИ
+	goal, err := goalStore.Get(ctx, id)
+	if errors.Is(err, errGoalNotFound) {
+		errMsg := "Запись не найдена"
+		logger.LogError(nil, errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.LogError(err, "Ошибка чтения из БД в getGoalHandler")
+		writeDBError(w, r, err, "Query error")
 		return
 	}
 
-	// ШАГ 5: ОТПРАВКА СОЗДАННОЙ ЗАПИСИ
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusCreated) // 201 Created
-	json.NewEncoder(w).Encode(newGoal)
-	logger.LogRequest(r.Method, r.URL.Path, http.StatusCreated)
+	// ШАГ 4: ОТВЕТ С ETag (совпавший If-None-Match — 304 без тела)
+	etag := goalETag(goal)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotModified)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, goal)
 }
 
 // ОБРАБОТЧИК: PUT /goals/{id}
@@ -153,9 +472,8 @@ func updateGoalHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ШАГ 2: ИЗВЛЕЧЕНИЕ ID ИЗ URL
-	// Пример: /goals/11 → "11"
-	idStr := r.URL.Path[len("/goals/"):]
-	id, err := strconv.Atoi(idStr) // Преобразуем строку в число
+	// Пример: /goals/11 → "11" (шаблон маршрута /goals/{id})
+	id, err := strconv.Atoi(r.PathValue("id")) // Преобразуем строку в число
 	if err != nil {
 		logger.LogError(err, "Неверный ID в updateGoalHandler")
 		http.Error(w, "Неверный ID", http.StatusBadRequest)
@@ -165,49 +483,136 @@ func updateGoalHandler(w http.ResponseWriter, r *http.Request) {
 
 	// ШАГ 3: ДЕКОДИРОВАНИЕ JSON
 	var updatedGoal Goal
-	if err := json.NewDecoder(r.Body).Decode(&updatedGoal); err != nil {
+	if err := decodeGoalBody(w, r, &updatedGoal); err != nil {
 		logger.LogError(err, "Ошибка декодирования JSON в updateGoalHandler")
-		http.Error(w, "Неверный JSON", http.StatusBadRequest)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// created_at неизменяем для обычных клиентов: значение из тела игнорируется,
+	// исправить его может только администратор через PATCH /goals/{id}
+	updatedGoal.CreatedAt = time.Time{}
+
+	applyGoalDefaults(&updatedGoal)
+	if errs := validateGoal(updatedGoal); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
 		return
 	}
 
-	// ШАГ 4: ПОДКЛЮЧЕНИЕ К БД
+	// ШАГ 4: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
-	conn, err := pgx.Connect(ctx, dbURL)
+
+	// ШАГ 5: ОБНОВЛЕНИЕ ЗАПИСИ
+	// С If-Match цель обновляется, только если не изменилась с тех пор, как клиент получил её ETag
+	var precondition func(current Goal) error
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		precondition = func(current Goal) error {
+			if !ifMatchSatisfied(ifMatch, goalETag(current)) {
+				return errGoalChanged
+			}
+			return nil
+		}
+	}
+	updatedGoal, err = goalStore.Update(ctx, id, updatedGoal, precondition)
+
+	// ШАГ 6: ПРОВЕРКА, БЫЛА ЛИ ЗАПИСЬ НАЙДЕНА И НЕ ИЗМЕНЕНА
+	if errors.Is(err, errGoalChanged) {
+		logger.InfoLogger.Printf("✋ Цель %d изменилась после чтения (If-Match не совпал), обновление отклонено", id)
+		http.Error(w, "Цель изменилась с момента чтения: получите её заново и повторите изменение", http.StatusPreconditionFailed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, errGoalNotFound) {
+		errMsg := "Запись не найдена"
+		logger.LogError(nil, errMsg) // Бизнес-ошибка (nil вместо err)
+		http.Error(w, errMsg, http.StatusNotFound)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		logger.LogError(err, "Подключение к БД в updateGoalHandler")
-		http.Error(w, "Ошибка подключения к БД", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		logger.LogError(err, "Ошибка обновления в БД в updateGoalHandler")
+		writeDBError(w, r, err, "Ошибка обновления в БД")
 		return
 	}
-	defer conn.Close(ctx)
 
-	// ШАГ 5: ОБНОВЛЕНИЕ ЗАПИСИ
-	// WHERE id = $4 использует параметризованный запрос для безопасности
-	query := `UPDATE goals SET goal = $1, timeline = $2, salary_target = $3 WHERE id = $4`
-	result, err := conn.Exec(ctx, query, updatedGoal.Goal, updatedGoal.Timeline, updatedGoal.SalaryTarget, id)
+	invalidateCachedGoals()
+
+	// ШАГ 7: ОТПРАВКА ОБНОВЛЁННОЙ ЗАПИСИ (ETag — для следующего If-Match)
+	w.Header().Set("ETag", goalETag(updatedGoal))
+	writeJSON(w, r, http.StatusOK, updatedGoal)
+}
+
+// СТРУКТУРА ЗАПРОСА АДМИНИСТРАТИВНОГО ИСПРАВЛЕНИЯ ЦЕЛИ
+type adminGoalPatch struct {
+	CreatedAt *time.Time `json:"created_at"` // Новое время создания (RFC3339)
+}
+
+// ОБРАБОТЧИК: PATCH /goals/{id}
+// Исправление created_at (например, после миграции данных). Только для администратора
+func adminPatchGoalHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: ПРОВЕРКА ПРАВ АДМИНИСТРАТОРА
+	if !isAdminRequest(r) {
+		logSecurityEvent("ADMIN_PATCH_FORBIDDEN", getIP(r), r.URL.Path)
+		http.Error(w, "Изменение created_at доступно только администратору", http.StatusForbidden)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusForbidden)
+		return
+	}
+
+	// ШАГ 3: ИЗВЛЕЧЕНИЕ ID ИЗ URL
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		logger.LogError(err, "Ошибка обновления в БД в updateGoalHandler")
-		http.Error(w, "Ошибка обновления в БД", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		logger.LogError(err, "Неверный ID в adminPatchGoalHandler")
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
 		return
 	}
 
-	// ШАГ 6: ПРОВЕРКА, БЫЛА ЛИ ЗАПИСЬ НАЙДЕНА
-	if result.RowsAffected() == 0 {
+	// ШАГ 4: ДЕКОДИРОВАНИЕ JSON
+	var patch adminGoalPatch
+	if err := decodeJSONBody(w, r, &patch); err != nil {
+		logger.LogError(err, "Ошибка декодирования JSON в adminPatchGoalHandler")
+		writeDecodeError(w, r, err)
+		return
+	}
+	if patch.CreatedAt == nil {
+		writeValidationErrors(w, r, []FieldError{{Field: "created_at", Code: ValidationCodeRequired}})
+		return
+	}
+
+	// ШАГ 5: ОБНОВЛЕНИЕ created_at
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	goal, err := goalStore.SetCreatedAt(ctx, id, *patch.CreatedAt)
+	if errors.Is(err, errGoalNotFound) {
 		errMsg := "Запись не найдена"
-		logger.LogError(nil, errMsg) // Бизнес-ошибка (nil вместо err)
+		logger.LogError(nil, errMsg)
 		http.Error(w, errMsg, http.StatusNotFound)
 		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		logger.LogError(err, "Ошибка обновления created_at в adminPatchGoalHandler")
+		writeDBError(w, r, err, "Ошибка обновления в БД")
+		return
+	}
 
-	// ШАГ 7: ОТПРАВКА ОБНОВЛЁННОЙ ЗАПИСИ
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	json.NewEncoder(w).Encode(updatedGoal)
-	logger.LogRequest(r.Method, r.URL.Path, http.StatusOK)
+	invalidateCachedGoals()
+	logSecurityEvent("ADMIN_CREATED_AT_CHANGED", getIP(r), r.URL.Path)
+
+	// ШАГ 6: ОТПРАВКА ИСПРАВЛЕННОЙ ЗАПИСИ
+	w.Header().Set("ETag", goalETag(goal))
+	writeJSON(w, r, http.StatusOK, goal)
 }
 
 // ОБРАБОТЧИК: DELETE /goals/{id}
@@ -223,8 +628,7 @@ func deleteGoalHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ШАГ 2: ИЗВЛЕЧЕНИЕ ID ИЗ URL
-	idStr := r.URL.Path[len("/goals/"):]
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		logger.LogError(err, "Неверный ID в deleteGoalHandler")
 		http.Error(w, "Неверный ID", http.StatusBadRequest)
@@ -232,39 +636,92 @@ func deleteGoalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ШАГ 3: ПОДКЛЮЧЕНИЕ К БД
+	// ШАГ 3: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
-	conn, err := pgx.Connect(ctx, dbURL)
-	if err != nil {
-		logger.LogError(err, "Подключение к БД в deleteGoalHandler")
-		http.Error(w, "Ошибка подключения к БД", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
-		return
-	}
-	defer conn.Close(ctx)
 
 	// ШАГ 4: УДАЛЕНИЕ ЗАПИСИ
-	// Используем $1 для защиты от SQL-инъекций
-	result, err := conn.Exec(ctx, "DELETE FROM goals WHERE id = $1", id)
-	if err != nil {
-		logger.LogError(err, "Ошибка удаления в БД в deleteGoalHandler")
-		http.Error(w, "Ошибка удаления из БД", http.StatusInternalServerError)
-		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
-		return
-	}
+	err = goalStore.Delete(ctx, id)
 
 	// ШАГ 5: ПРОВЕРКА, БЫЛА ЛИ ЗАПИСЬ НАЙДЕНА
-	if result.RowsAffected() == 0 {
+	if errors.Is(err, errGoalNotFound) {
 		errMsg := "Запись не найдена"
 		logger.LogError(nil, errMsg)
 		http.Error(w, errMsg, http.StatusNotFound)
 		logger.LogRequest(r.Method, r.URL.Path, http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		logger.LogError(err, "Ошибка удаления в БД в deleteGoalHandler")
+		writeDBError(w, r, err, "Ошибка удаления из БД")
+		return
+	}
+
+	invalidateCachedGoals()
 
 	// ШАГ 6: УСПЕШНОЕ УДАЛЕНИЕ
 	// 204 No Content — стандарт для успешного удаления без тела ответа
 	w.WriteHeader(http.StatusNoContent)
 	logger.LogRequest(r.Method, r.URL.Path, http.StatusNoContent)
 }
+
+// Максимальное количество ID в одном пакетном удалении
+const maxBatchDeleteIDs = 100
+
+// СТРУКТУРА ЗАПРОСА ПАКЕТНОГО УДАЛЕНИЯ
+type batchDeleteRequest struct {
+	IDs []int `json:"ids"` // Список ID целей для удаления
+}
+
+// ОБРАБОТЧИК: POST /goals/delete
+// Удаление нескольких целей одним SQL-запросом
+func batchDeleteGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: ДЕКОДИРОВАНИЕ JSON
+	var req batchDeleteRequest
+	if err := decodeJSONBody(w, r, &req); err != nil {
+		logger.LogError(err, "Ошибка декодирования JSON в batchDeleteGoalsHandler")
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// ШАГ 3: ВАЛИДАЦИЯ СПИСКА ID
+	if len(req.IDs) == 0 {
+		http.Error(w, "Список ids не должен быть пустым", http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > maxBatchDeleteIDs {
+		http.Error(w, "Слишком много ids в одном запросе (максимум "+strconv.Itoa(maxBatchDeleteIDs)+")", http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+
+	// ШАГ 4: КОНТЕКСТ С ТАЙМАУТОМ ДЛЯ ЗАПРОСА
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// ШАГ 5: УДАЛЕНИЕ ВСЕХ ЗАПИСЕЙ ОДНИМ ЗАПРОСОМ
+	deleted, err := goalStore.DeleteMany(ctx, req.IDs)
+	if err != nil {
+		logger.LogError(err, "Ошибка пакетного удаления в batchDeleteGoalsHandler")
+		writeDBError(w, r, err, "Ошибка удаления из БД")
+		return
+	}
+
+	if deleted > 0 {
+		invalidateCachedGoals()
+	}
+
+	// ШАГ 6: ОТПРАВКА КОЛИЧЕСТВА ФАКТИЧЕСКИ УДАЛЁННЫХ ЗАПИСЕЙ
+	// Несуществующие ID просто не попадают в счётчик
+	writeJSON(w, r, http.StatusOK, map[string]int64{"deleted": deleted})
+}
@@ -1,3 +1,5 @@
+//go:build integration
+
 package main
 
 import (
@@ -7,10 +9,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func TestMain(m *testing.M) {
@@ -33,8 +38,8 @@ func TestMain(m *testing.M) {
 
 	logger.InfoLogger.Println("✅ Тестовая БД подключена")
 
-	// Удаляем таблицу если она существует
-	_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS goals")
+	// Удаляем таблицы если они существуют
+	_, _ = conn.Exec(ctx, "DROP TABLE IF EXISTS goals, goals_archive, goals_staging, schema_migrations")
 
 	// Создаем таблицу goals с ТОЧНОЙ структурой из основного приложения
 	_, err = conn.Exec(ctx, `
@@ -52,6 +57,20 @@ func TestMain(m *testing.M) {
 	}
 	logger.InfoLogger.Println("✅ Таблица goals создана с точной структурой из приложения")
 
+	// Создаём пул соединений, через который работают обработчики
+	dbPool, err = newDBPool(ctx, dbURL)
+	if err != nil {
+		logger.LogError(err, "❌ Не удалось создать пул соединений")
+		os.Exit(1)
+	}
+	defer dbPool.Close()
+
+	// Доводим схему до актуальной версии, как при запуске приложения
+	if err := runMigrations(ctx); err != nil {
+		logger.LogError(err, "❌ Не удалось применить миграции")
+		os.Exit(1)
+	}
+
 	// Запускаем тесты
 	code := m.Run()
 
@@ -66,7 +85,7 @@ func TestCreateGoal(t *testing.T) {
 	goal := Goal{
 		Goal:         "Test Goal",
 		Timeline:     "Test Timeline",
-		SalaryTarget: 1000,
+		SalaryTarget: "1000",
 	}
 	jsonData, _ := json.Marshal(goal)
 
@@ -111,11 +130,12 @@ func TestUpdateNonExistentGoal(t *testing.T) {
 	goal := Goal{
 		Goal:         "Updated Goal",
 		Timeline:     "Updated Timeline",
-		SalaryTarget: 2000,
+		SalaryTarget: "2000",
 	}
 	jsonData, _ := json.Marshal(goal)
 
 	req := httptest.NewRequest("PUT", "/goals/999999", bytes.NewBuffer(jsonData))
+	req.SetPathValue("id", "999999")
 	req.Header.Set("Content-Type", "application/json")
 
 	recorder := httptest.NewRecorder()
@@ -129,6 +149,7 @@ func TestUpdateNonExistentGoal(t *testing.T) {
 // ТЕСТ: Удаление несуществующей цели
 func TestDeleteNonExistentGoal(t *testing.T) {
 	req := httptest.NewRequest("DELETE", "/goals/999999", nil)
+	req.SetPathValue("id", "999999")
 	recorder := httptest.NewRecorder()
 
 	deleteGoalHandler(recorder, req)
@@ -144,7 +165,7 @@ func TestDeleteExistingGoal(t *testing.T) {
 	goal := Goal{
 		Goal:         "Goal to delete",
 		Timeline:     "Timeline to delete",
-		SalaryTarget: 3000,
+		SalaryTarget: "3000",
 	}
 	jsonData, _ := json.Marshal(goal)
 
@@ -166,6 +187,7 @@ func TestDeleteExistingGoal(t *testing.T) {
 
 	// Теперь удаляем цель
 	deleteReq := httptest.NewRequest("DELETE", "/goals/"+string(rune('0'+createdGoal.ID)), nil)
+	deleteReq.SetPathValue("id", strconv.Itoa(createdGoal.ID))
 	deleteRecorder := httptest.NewRecorder()
 	deleteGoalHandler(deleteRecorder, deleteReq)
 
@@ -181,7 +203,7 @@ func TestUpdateExistingGoal(t *testing.T) {
 	goal := Goal{
 		Goal:         "Original Goal",
 		Timeline:     "Original Timeline",
-		SalaryTarget: 4000,
+		SalaryTarget: "4000",
 	}
 	jsonData, _ := json.Marshal(goal)
 
@@ -205,11 +227,12 @@ func TestUpdateExistingGoal(t *testing.T) {
 	updatedGoal := Goal{
 		Goal:         "Updated Goal",
 		Timeline:     "Updated Timeline",
-		SalaryTarget: 5000,
+		SalaryTarget: "5000",
 	}
 	updateData, _ := json.Marshal(updatedGoal)
 
 	updateReq := httptest.NewRequest("PUT", "/goals/"+string(rune('0'+createdGoal.ID)), bytes.NewBuffer(updateData))
+	updateReq.SetPathValue("id", strconv.Itoa(createdGoal.ID))
 	updateReq.Header.Set("Content-Type", "application/json")
 	updateRecorder := httptest.NewRecorder()
 	updateGoalHandler(updateRecorder, updateReq)
@@ -218,3 +241,780 @@ func TestUpdateExistingGoal(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, updateRecorder.Code)
 	}
 }
+
+// ТЕСТ: Пакетное удаление, когда часть ID не существует
+func TestBatchDeletePartialMatch(t *testing.T) {
+	// Сначала создаем цель
+	goal := Goal{
+		Goal:         "Goal for batch delete",
+		Timeline:     "Timeline for batch delete",
+		SalaryTarget: "6000",
+	}
+	jsonData, _ := json.Marshal(goal)
+
+	createReq := httptest.NewRequest("POST", "/goals", bytes.NewBuffer(jsonData))
+	createReq.Header.Set("Content-Type", "application/json")
+	createRecorder := httptest.NewRecorder()
+	createGoalHandler(createRecorder, createReq)
+
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create goal for batch delete test")
+	}
+
+	var createdGoal Goal
+	if err := json.Unmarshal(createRecorder.Body.Bytes(), &createdGoal); err != nil {
+		t.Fatalf("Failed to parse created goal: %v", err)
+	}
+
+	// Удаляем существующую и несуществующую цели одним запросом
+	body, _ := json.Marshal(batchDeleteRequest{IDs: []int{createdGoal.ID, 999999}})
+	req := httptest.NewRequest("POST", "/goals/delete", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	batchDeleteGoalsHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var result map[string]int
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result["deleted"] != 1 {
+		t.Errorf("Expected 1 deleted goal, got %d", result["deleted"])
+	}
+}
+
+// ТЕСТ: Пакетное удаление с пустым списком ID
+func TestBatchDeleteEmptyIDs(t *testing.T) {
+	req := httptest.NewRequest("POST", "/goals/delete", bytes.NewBufferString(`{"ids": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	batchDeleteGoalsHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+// ТЕСТ: Условный запрос списка с ETag
+func TestGetGoalsNotModified(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeCacheableJSON(recorder, httptest.NewRequest("GET", "/goals", nil), []Goal{{ID: 1, Goal: "Cached"}})
+
+	etag := recorder.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected ETag header to be set")
+	}
+	if recorder.Header().Get("Cache-Control") == "" {
+		t.Errorf("Expected Cache-Control header to be set")
+	}
+
+	// Повторный запрос с тем же ETag должен вернуть 304
+	req := httptest.NewRequest("GET", "/goals", nil)
+	req.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	writeCacheableJSON(recorder, req, []Goal{{ID: 1, Goal: "Cached"}})
+
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d", http.StatusNotModified, recorder.Code)
+	}
+}
+
+// ТЕСТ: Ошибки валидации с кодами для каждого поля
+func TestCreateGoalValidationErrors(t *testing.T) {
+	req := httptest.NewRequest("POST", "/goals", bytes.NewBufferString(`{"timeline": "2026", "salary_target_rub_per_hour": -5}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	createGoalHandler(recorder, req)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, recorder.Code)
+	}
+
+	var body struct {
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse validation errors: %v", err)
+	}
+
+	expected := []FieldError{
+		{Field: "goal", Code: ValidationCodeRequired},
+		{Field: "salary_target_rub_per_hour", Code: ValidationCodeNegative},
+	}
+	if len(body.Errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), body.Errors)
+	}
+	for i, e := range expected {
+		if body.Errors[i] != e {
+			t.Errorf("Expected error %v, got %v", e, body.Errors[i])
+		}
+	}
+}
+
+// ТЕСТ: Понятные ошибки декодирования тела запроса
+func TestDecodeJSONBodyErrors(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"пустое тело", "application/json", "", http.StatusBadRequest},
+		{"неизвестное поле", "application/json", `{"goal": "x", "unknown": 1}`, http.StatusBadRequest},
+		{"неверный тип", "application/json", `{"salary_target_rub_per_hour": "много"}`, http.StatusBadRequest},
+		{"два объекта", "application/json", `{"goal": "x"}{"goal": "y"}`, http.StatusBadRequest},
+		{"не JSON", "application/x-www-form-urlencoded", "goal=x", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/goals", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		recorder := httptest.NewRecorder()
+		createGoalHandler(recorder, req)
+
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, recorder.Code)
+		}
+	}
+}
+
+// Вспомогательная функция: количество целей через GET /goals/count
+func countGoals(t *testing.T, query string) int {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/goals/count"+query, nil)
+	recorder := httptest.NewRecorder()
+	countGoalsHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var result map[string]int
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse count: %v", err)
+	}
+	return result["count"]
+}
+
+// ТЕСТ: Счётчик целей меняется при создании и удалении
+func TestCountGoalsTracksInsertsAndDeletes(t *testing.T) {
+	before := countGoals(t, "")
+	beforeFiltered := countGoals(t, "?q=count-marker&min_salary=7000")
+
+	goal := Goal{
+		Goal:         "Goal count-marker",
+		Timeline:     "Timeline for count",
+		SalaryTarget: "7000",
+	}
+	jsonData, _ := json.Marshal(goal)
+
+	createReq := httptest.NewRequest("POST", "/goals", bytes.NewBuffer(jsonData))
+	createReq.Header.Set("Content-Type", "application/json")
+	createRecorder := httptest.NewRecorder()
+	createGoalHandler(createRecorder, createReq)
+
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create goal for count test")
+	}
+
+	var createdGoal Goal
+	if err := json.Unmarshal(createRecorder.Body.Bytes(), &createdGoal); err != nil {
+		t.Fatalf("Failed to parse created goal: %v", err)
+	}
+
+	if got := countGoals(t, ""); got != before+1 {
+		t.Errorf("Expected count %d after insert, got %d", before+1, got)
+	}
+	if got := countGoals(t, "?q=count-marker&min_salary=7000"); got != beforeFiltered+1 {
+		t.Errorf("Expected filtered count %d after insert, got %d", beforeFiltered+1, got)
+	}
+
+	deleteReq := httptest.NewRequest("DELETE", "/goals/"+strconv.Itoa(createdGoal.ID), nil)
+	deleteReq.SetPathValue("id", strconv.Itoa(createdGoal.ID))
+	deleteRecorder := httptest.NewRecorder()
+	deleteGoalHandler(deleteRecorder, deleteReq)
+
+	if got := countGoals(t, ""); got != before {
+		t.Errorf("Expected count %d after delete, got %d", before, got)
+	}
+}
+
+// ТЕСТ: Некорректный фильтр min_salary
+func TestCountGoalsInvalidFilter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/goals/count?min_salary=abc", nil)
+	recorder := httptest.NewRecorder()
+	countGoalsHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+// ТЕСТ: Копирование существующей цели и 404 для несуществующей
+func TestDuplicateGoal(t *testing.T) {
+	goal := Goal{
+		Goal:         "Goal to duplicate",
+		Timeline:     "Timeline to duplicate",
+		SalaryTarget: "4500",
+	}
+	jsonData, _ := json.Marshal(goal)
+
+	createReq := httptest.NewRequest("POST", "/goals", bytes.NewBuffer(jsonData))
+	createReq.Header.Set("Content-Type", "application/json")
+	createRecorder := httptest.NewRecorder()
+	createGoalHandler(createRecorder, createReq)
+
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create goal for duplicate test")
+	}
+
+	var source Goal
+	if err := json.Unmarshal(createRecorder.Body.Bytes(), &source); err != nil {
+		t.Fatalf("Failed to parse created goal: %v", err)
+	}
+
+	dupReq := httptest.NewRequest("POST", "/goals/"+strconv.Itoa(source.ID)+"/duplicate", nil)
+	dupReq.SetPathValue("id", strconv.Itoa(source.ID))
+	dupRecorder := httptest.NewRecorder()
+	duplicateGoalHandler(dupRecorder, dupReq)
+
+	if dupRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, dupRecorder.Code)
+	}
+
+	var copied Goal
+	if err := json.Unmarshal(dupRecorder.Body.Bytes(), &copied); err != nil {
+		t.Fatalf("Failed to parse duplicated goal: %v", err)
+	}
+	if copied.ID == source.ID {
+		t.Errorf("Expected a new ID, got the source ID %d", copied.ID)
+	}
+	if copied.Goal != source.Goal+" (copy)" {
+		t.Errorf("Expected goal %q, got %q", source.Goal+" (copy)", copied.Goal)
+	}
+	if copied.Timeline != source.Timeline || copied.SalaryTarget != source.SalaryTarget {
+		t.Errorf("Expected copied fields to match source, got %+v", copied)
+	}
+
+	// Несуществующая исходная цель
+	missingReq := httptest.NewRequest("POST", "/goals/999999/duplicate", nil)
+	missingReq.SetPathValue("id", "999999")
+	missingRecorder := httptest.NewRecorder()
+	duplicateGoalHandler(missingRecorder, missingReq)
+
+	if missingRecorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, missingRecorder.Code)
+	}
+}
+
+// ТЕСТ: При исчерпанном лимите создание цели возвращает 409
+func TestCreateGoalLimitReached(t *testing.T) {
+	previous := maxGoalsPerUser
+	defer func() { maxGoalsPerUser = previous }()
+
+	// Лимит равен текущему количеству — следующая цель уже не помещается
+	maxGoalsPerUser = countGoals(t, "")
+	if maxGoalsPerUser == 0 {
+		// Лимит 0 означает «без ограничений», поэтому на пустой таблице проверять нечего
+		t.Skip("Table is empty, limit cannot be reached")
+	}
+
+	jsonData, _ := json.Marshal(Goal{Goal: "Over the limit", Timeline: "Never", SalaryTarget: "1"})
+	req := httptest.NewRequest("POST", "/goals", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	createGoalHandler(recorder, req)
+
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+	}
+
+	// Копирование существующей цели тоже упирается в лимит
+	var sourceID int
+	if err := dbPool.QueryRow(context.Background(), "SELECT id FROM "+goalsTable+" LIMIT 1").Scan(&sourceID); err != nil {
+		t.Fatalf("Failed to pick a goal to duplicate: %v", err)
+	}
+	dupReq := httptest.NewRequest("POST", "/goals/"+strconv.Itoa(sourceID)+"/duplicate", nil)
+	dupReq.SetPathValue("id", strconv.Itoa(sourceID))
+	dupRecorder := httptest.NewRecorder()
+	duplicateGoalHandler(dupRecorder, dupReq)
+
+	if dupRecorder.Code != http.StatusConflict {
+		t.Errorf("Expected duplicate status %d, got %d", http.StatusConflict, dupRecorder.Code)
+	}
+	if got := countGoals(t, ""); got != maxGoalsPerUser {
+		t.Errorf("Expected count to stay at %d, got %d", maxGoalsPerUser, got)
+	}
+}
+
+// ТЕСТ: Фильтр по диапазону created_at вместе с поиском по тексту
+func TestGetGoalsCreatedRange(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Три цели: до диапазона, внутри и ровно на правой границе (не включается)
+	offsets := []time.Duration{-24 * time.Hour, 12 * time.Hour, 48 * time.Hour}
+	var ids []int
+	for _, offset := range offsets {
+		var id int
+		err := dbPool.QueryRow(ctx,
+			`INSERT INTO goals (goal, timeline, salary_target, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+			"Goal range-marker", "Timeline", 100, base.Add(offset)).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to insert goal: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	defer dbPool.Exec(ctx, "DELETE FROM goals WHERE id = ANY($1)", ids)
+
+	query := "?q=range-marker&created_after=" + base.Format(time.RFC3339) +
+		"&created_before=" + base.Add(48*time.Hour).Format(time.RFC3339)
+
+	req := httptest.NewRequest("GET", "/goals"+query, nil)
+	recorder := httptest.NewRecorder()
+	getGoalsHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var goals []Goal
+	if err := json.Unmarshal(recorder.Body.Bytes(), &goals); err != nil {
+		t.Fatalf("Failed to parse goals: %v", err)
+	}
+	if len(goals) != 1 || goals[0].ID != ids[1] {
+		t.Errorf("Expected only goal %d in range, got %+v", ids[1], goals)
+	}
+
+	if got := countGoals(t, query); got != 1 {
+		t.Errorf("Expected count 1 in range, got %d", got)
+	}
+
+	// Нераспознаваемое время — 400
+	badReq := httptest.NewRequest("GET", "/goals?created_after=yesterday", nil)
+	badRecorder := httptest.NewRecorder()
+	getGoalsHandler(badRecorder, badReq)
+
+	if badRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, badRecorder.Code)
+	}
+}
+
+// ТЕСТ: Архивация переносит только давно выполненные цели
+func TestArchiveCompletedGoals(t *testing.T) {
+	ctx := context.Background()
+	cutoff := time.Now().AddDate(0, 0, -30)
+
+	insert := func(completedAt *time.Time) int {
+		var id int
+		err := dbPool.QueryRow(ctx,
+			`INSERT INTO goals (goal, timeline, salary_target, completed_at) VALUES ('Goal archive-marker', 'Timeline', 1, $1) RETURNING id`,
+			completedAt).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to insert goal: %v", err)
+		}
+		return id
+	}
+
+	old := cutoff.Add(-time.Hour)
+	recent := cutoff.Add(time.Hour)
+	oldID, recentID, openID := insert(&old), insert(&recent), insert(nil)
+	defer dbPool.Exec(ctx, "DELETE FROM goals WHERE id = ANY($1)", []int{oldID, recentID, openID})
+	defer dbPool.Exec(ctx, "DELETE FROM goals_archive WHERE id = ANY($1)", []int{oldID, recentID, openID})
+
+	archived, err := archiveCompletedGoals(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("Expected 1 archived goal, got %d", archived)
+	}
+
+	var inArchive, inGoals int
+	dbPool.QueryRow(ctx, "SELECT COUNT(*) FROM goals_archive WHERE id = $1", oldID).Scan(&inArchive)
+	dbPool.QueryRow(ctx, "SELECT COUNT(*) FROM goals WHERE id = ANY($1)", []int{oldID, recentID, openID}).Scan(&inGoals)
+	if inArchive != 1 {
+		t.Errorf("Expected goal %d to be in goals_archive", oldID)
+	}
+	if inGoals != 2 {
+		t.Errorf("Expected 2 goals to stay in goals, got %d", inGoals)
+	}
+}
+
+// ТЕСТ: Нарушение уникальности при создании даёт 409, а не 500
+func TestCreateGoalUniqueViolation(t *testing.T) {
+	ctx := context.Background()
+
+	// Частичный индекс затрагивает только цели этого теста
+	_, err := dbPool.Exec(ctx, `CREATE UNIQUE INDEX goals_unique_marker ON goals (goal) WHERE goal LIKE 'unique-marker%'`)
+	if err != nil {
+		t.Fatalf("Failed to create unique index: %v", err)
+	}
+	defer dbPool.Exec(ctx, "DROP INDEX IF EXISTS goals_unique_marker")
+	defer dbPool.Exec(ctx, "DELETE FROM goals WHERE goal LIKE 'unique-marker%'")
+
+	create := func() int {
+		jsonData, _ := json.Marshal(Goal{Goal: "unique-marker goal", Timeline: "2026", SalaryTarget: "100"})
+		req := httptest.NewRequest("POST", "/goals", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		createGoalHandler(recorder, req)
+		return recorder.Code
+	}
+
+	if code := create(); code != http.StatusCreated {
+		t.Fatalf("Expected first insert to return %d, got %d", http.StatusCreated, code)
+	}
+	if code := create(); code != http.StatusConflict {
+		t.Errorf("Expected duplicate insert to return %d, got %d", http.StatusConflict, code)
+	}
+}
+
+// ТЕСТ: SQL статистики и сроков в pgGoalStore (обработчики проверяются на in-memory сервере)
+func TestPgGoalStoreStatsAndTimelines(t *testing.T) {
+	ctx := context.Background()
+	var ids []int
+	for _, row := range []struct{ salary, currency, timeline string }{
+		{"100", "RUB", "2030"}, {"300", "RUB", "2026"}, {"10", "USD", "2030"},
+	} {
+		var id int
+		err := dbPool.QueryRow(ctx, withTables(`INSERT INTO {goals} (goal, timeline, salary_target, currency)
+			VALUES ('stats', $1, $2, $3) RETURNING id`), row.timeline, row.salary, row.currency).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to insert goal: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	defer dbPool.Exec(ctx, "DELETE FROM "+goalsTable+" WHERE id = ANY($1)", ids)
+	// Цели других тестов изменили бы средние, поэтому в таблице остаются только эти три
+	dbPool.Exec(ctx, "DELETE FROM "+goalsTable+" WHERE NOT (id = ANY($1))", ids)
+	dbPool.Exec(ctx, "UPDATE "+goalsTable+" SET completed_at = NOW() WHERE id = $1", ids[0])
+
+	stats, err := pgGoalStore{}.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Count != 3 || stats.CompletedCount != 1 || stats.OpenCount != 2 || len(stats.ByCurrency) != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	rub, usd := stats.ByCurrency[0], stats.ByCurrency[1]
+	if rub.Currency != "RUB" || rub.Count != 2 || rub.AvgSalary.String() != "200.00" || rub.MaxSalary.String() != "300" {
+		t.Errorf("Unexpected RUB stats: %+v", rub)
+	}
+	if usd.Currency != "USD" || usd.Count != 1 || usd.AvgSalary.String() != "10.00" {
+		t.Errorf("Unexpected USD stats: %+v", usd)
+	}
+
+	timelines, err := pgGoalStore{}.Timelines(ctx)
+	if err != nil || len(timelines) != 2 || timelines[0] != "2026" || timelines[1] != "2030" {
+		t.Errorf("Expected timelines [2026 2030], got %v (%v)", timelines, err)
+	}
+}
+
+// ТЕСТ: created_at из тела сохраняется только для администратора
+func TestCreateGoalExplicitCreatedAt(t *testing.T) {
+	defer func(previous string) { adminToken = previous }(adminToken)
+	adminToken = "secret"
+
+	original := time.Date(2019, 5, 17, 10, 30, 0, 0, time.UTC)
+	create := func(createdAt time.Time, token string) (*httptest.ResponseRecorder, Goal) {
+		jsonData, _ := json.Marshal(Goal{Goal: "Imported goal", Timeline: "2019", CreatedAt: createdAt})
+		req := httptest.NewRequest("POST", "/goals", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		createGoalHandler(recorder, req)
+
+		var created Goal
+		json.Unmarshal(recorder.Body.Bytes(), &created)
+		return recorder, created
+	}
+
+	recorder, imported := create(original, "secret")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, recorder.Code)
+	}
+	if !imported.CreatedAt.Equal(original) {
+		t.Errorf("Expected created_at %s for admin import, got %s", original, imported.CreatedAt)
+	}
+
+	recorder, regular := create(original, "")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, recorder.Code)
+	}
+	if regular.CreatedAt.Equal(original) {
+		t.Errorf("Expected created_at from a regular client to be ignored")
+	}
+
+	recorder, _ = create(time.Now().Add(24*time.Hour), "secret")
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for future created_at, got %d", http.StatusUnprocessableEntity, recorder.Code)
+	}
+}
+
+// ТЕСТ: Прогрев пула открывает запрошенное число соединений (не больше MaxConns)
+func TestWarmUpPool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	warmed, err := warmUpPool(ctx, dbPool, 3)
+	if err != nil {
+		t.Fatalf("warm-up failed: %v", err)
+	}
+	if warmed != 3 {
+		t.Errorf("Expected 3 warmed connections, got %d", warmed)
+	}
+	if idle := dbPool.Stat().IdleConns(); idle < 3 {
+		t.Errorf("Expected at least 3 idle connections after warm-up, got %d", idle)
+	}
+
+	maxConns := int(dbPool.Config().MaxConns)
+	warmed, err = warmUpPool(ctx, dbPool, maxConns+5)
+	if err != nil {
+		t.Fatalf("warm-up failed: %v", err)
+	}
+	if warmed != maxConns {
+		t.Errorf("Expected warm-up capped at MaxConns=%d, got %d", maxConns, warmed)
+	}
+}
+
+// ТЕСТ: Строка, которую не удалось прочитать, пропускается с заголовком Warning (STRICT_SCAN=false)
+func TestGetGoalsSkipsUnreadableRows(t *testing.T) {
+	ctx := context.Background()
+	var goodID, badID int
+	dbPool.QueryRow(ctx, "INSERT INTO goals (goal, timeline, salary_target) VALUES ('readable', 't', 1) RETURNING id").Scan(&goodID)
+	dbPool.QueryRow(ctx, "INSERT INTO goals (goal, timeline, salary_target) VALUES ('corrupt', 't', 'NaN') RETURNING id").Scan(&badID)
+	defer dbPool.Exec(ctx, "DELETE FROM goals WHERE id = ANY($1)", []int{goodID, badID})
+	invalidateCachedGoals()
+
+	defer func(previous bool) { strictScan = previous }(strictScan)
+
+	strictScan = false
+	recorder := httptest.NewRecorder()
+	getGoalsHandler(recorder, httptest.NewRequest("GET", "/goals", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if warning := recorder.Header().Get("Warning"); warning == "" {
+		t.Error("Expected Warning header about skipped rows")
+	}
+	var goals []Goal
+	json.Unmarshal(recorder.Body.Bytes(), &goals)
+	found := false
+	for _, g := range goals {
+		if g.ID == badID {
+			t.Errorf("Expected corrupt row %d to be skipped", badID)
+		}
+		found = found || g.ID == goodID
+	}
+	if !found {
+		t.Errorf("Expected readable row %d in the response", goodID)
+	}
+
+	// Строгий режим: ошибка чтения прерывает запрос
+	strictScan = true
+	invalidateCachedGoals()
+	recorder = httptest.NewRecorder()
+	getGoalsHandler(recorder, httptest.NewRequest("GET", "/goals", nil))
+	if recorder.Code == http.StatusOK {
+		t.Errorf("Expected an error status in strict mode, got %d", recorder.Code)
+	}
+}
+
+// ТЕСТ: Записи журнала алертов читаются через GET /admin/alerts, новые сверху
+func TestAlertAuditHandler(t *testing.T) {
+	ctx := context.Background()
+	if err := createAlertAuditTable(ctx, dbPool); err != nil {
+		t.Fatalf("Failed to create %s: %v", alertAuditTable, err)
+	}
+	defer func(pool *pgxpool.Pool) { alertAuditPool = pool }(alertAuditPool)
+	alertAuditPool = dbPool
+
+	alert := Alert{Severity: alertSeverityWarning, Context: "audit-test", IP: "203.0.113.9", Count: 5, Timestamp: time.Now()}
+	for _, delivered := range []bool{true, false} {
+		d := alertDelivery{Channel: "webhook", Context: alert.Context, IP: alert.IP, Count: alert.Count,
+			Severity: alert.Severity, AlertedAt: alert.Timestamp, Delivered: delivered, Attempts: 1}
+		if err := insertAlertDelivery(ctx, dbPool, d); err != nil {
+			t.Fatalf("Failed to insert delivery: %v", err)
+		}
+	}
+	defer dbPool.Exec(ctx, "DELETE FROM "+alertAuditTable+" WHERE context = 'audit-test'")
+
+	recorder := httptest.NewRecorder()
+	alertAuditHandler(recorder, httptest.NewRequest("GET", "/admin/alerts?limit=1", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var page struct {
+		Alerts []alertDelivery `json:"alerts"`
+		Limit  int             `json:"limit"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &page)
+	if page.Limit != 1 || len(page.Alerts) != 1 || page.Alerts[0].Delivered || page.Alerts[0].Context != "audit-test" {
+		t.Errorf("Expected newest (failed) delivery first, got %+v", page)
+	}
+}
+
+// ТЕСТ: created_at из БД отдаётся в UTC с суффиксом Z, даже если TimeZone сессии другой
+func TestCreatedAtIsUTC(t *testing.T) {
+	ctx := context.Background()
+	conn, err := dbPool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "SET TIME ZONE 'Asia/Vladivostok'"); err != nil {
+		t.Fatalf("Failed to set session time zone: %v", err)
+	}
+	defer conn.Exec(ctx, "SET TIME ZONE 'UTC'")
+
+	var goal Goal
+	err = scanGoal(conn.QueryRow(ctx, withTables(`INSERT INTO {goals} (goal, timeline) VALUES ('tz test', 't')
+		RETURNING `+goalColumns)), &goal)
+	if err != nil {
+		t.Fatalf("Failed to insert goal: %v", err)
+	}
+	defer dbPool.Exec(ctx, "DELETE FROM "+goalsTable+" WHERE id = $1", goal.ID)
+
+	data, _ := json.Marshal(goal)
+	var raw map[string]interface{}
+	json.Unmarshal(data, &raw)
+	createdAt, _ := raw["created_at"].(string)
+	if !strings.HasSuffix(createdAt, "Z") {
+		t.Errorf("Expected created_at with Z suffix, got %q", createdAt)
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, createdAt); err != nil || time.Since(parsed).Abs() > time.Minute {
+		t.Errorf("Expected created_at close to now, got %q (%v)", createdAt, err)
+	}
+}
+
+// Пачка целей для массовой вставки
+func bulkTestGoals(n int) []Goal {
+	goals := make([]Goal, n)
+	for i := range goals {
+		goals[i] = Goal{Goal: "Bulk goal " + strconv.Itoa(i), Timeline: "Bulk", SalaryTarget: "100", Currency: "RUB", Tags: []string{}}
+	}
+	return goals
+}
+
+// ТЕСТ: COPY (в том числе параллельный) заполняет ID и created_at, как построчная вставка
+func TestInsertGoalsCopy(t *testing.T) {
+	defer func(workers, threshold int, slots chan struct{}) {
+		bulkWorkers, bulkCopyThreshold, bulkCopySlots = workers, threshold, slots
+	}(bulkWorkers, bulkCopyThreshold, bulkCopySlots)
+	bulkCopyThreshold = 1
+
+	for _, workers := range []int{1, 3} {
+		bulkWorkers, bulkCopySlots = workers, make(chan struct{}, workers)
+		goals := bulkTestGoals(10)
+		if err := insertGoals(context.Background(), goals); err != nil {
+			t.Fatalf("workers=%d: insertGoals failed: %v", workers, err)
+		}
+
+		ids := make([]int, len(goals))
+		for i, goal := range goals {
+			if goal.ID == 0 || goal.CreatedAt.IsZero() {
+				t.Errorf("workers=%d: goal %d not filled: %+v", workers, i, goal)
+			}
+			ids[i] = goal.ID
+		}
+
+		var stored int
+		if err := dbPool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+goalsTable+" WHERE id = ANY($1)", ids).Scan(&stored); err != nil {
+			t.Fatalf("Failed to count copied goals: %v", err)
+		}
+		if stored != len(goals) {
+			t.Errorf("workers=%d: expected %d stored goals, got %d", workers, len(goals), stored)
+		}
+		dbPool.Exec(context.Background(), "DELETE FROM "+goalsTable+" WHERE id = ANY($1)", ids)
+	}
+
+	// Сбой одной части: в таблицу целей не попадает ничего, промежуточная таблица очищена
+	bulkWorkers, bulkCopySlots = 3, make(chan struct{}, 3)
+	goals := bulkTestGoals(10)
+	goals[len(goals)-1].Goal = "broken \x00 goal" // PostgreSQL не принимает нулевой байт в TEXT
+	if err := insertGoals(context.Background(), goals); err == nil {
+		t.Fatal("Expected insertGoals to fail on the broken chunk")
+	}
+	var stored, staged int
+	dbPool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+goalsTable+" WHERE timeline = 'Bulk'").Scan(&stored)
+	dbPool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+goalsStagingTable()).Scan(&staged)
+	if stored != 0 || staged != 0 {
+		t.Errorf("Expected no goals from a failed batch, got %d stored and %d staged", stored, staged)
+	}
+}
+
+// БЕНЧМАРК: Импорт 10 000 целей построчно и через COPY
+func BenchmarkInsertGoals10k(b *testing.B) {
+	defer func(workers, threshold int, slots chan struct{}) {
+		bulkWorkers, bulkCopyThreshold, bulkCopySlots = workers, threshold, slots
+	}(bulkWorkers, bulkCopyThreshold, bulkCopySlots)
+
+	cases := []struct {
+		name      string
+		threshold int
+		workers   int
+	}{
+		{"row-by-row", 1 << 30, 1},
+		{"copy", 1, 1},
+		{"copy-4-workers", 1, 4},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			bulkCopyThreshold, bulkWorkers, bulkCopySlots = tc.threshold, tc.workers, make(chan struct{}, tc.workers)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				goals := bulkTestGoals(10000)
+				dbPool.Exec(context.Background(), "DELETE FROM "+goalsTable+" WHERE timeline = 'Bulk'")
+				b.StartTimer()
+
+				if err := insertGoals(context.Background(), goals); err != nil {
+					b.Fatalf("insertGoals failed: %v", err)
+				}
+			}
+			b.StopTimer()
+			dbPool.Exec(context.Background(), "DELETE FROM "+goalsTable+" WHERE timeline = 'Bulk'")
+		})
+	}
+}
+
+// ТЕСТ: If-Match в PUT сверяется с ETag строки в БД
+func TestUpdateGoalIfMatchPostgres(t *testing.T) {
+	var goal Goal
+	err := scanGoal(dbPool.QueryRow(context.Background(), withTables(`INSERT INTO {goals} (goal, timeline) VALUES ('if-match', 't')
+		RETURNING `+goalColumns)), &goal)
+	if err != nil {
+		t.Fatalf("Failed to insert goal: %v", err)
+	}
+	defer dbPool.Exec(context.Background(), "DELETE FROM "+goalsTable+" WHERE id = $1", goal.ID)
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(Goal{Goal: "if-match updated", Timeline: "t"})
+		req := httptest.NewRequest("PUT", "/goals/"+strconv.Itoa(goal.ID), bytes.NewBuffer(data))
+		req.SetPathValue("id", strconv.Itoa(goal.ID))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		recorder := httptest.NewRecorder()
+		updateGoalHandler(recorder, req)
+		return recorder
+	}
+
+	if recorder := update(`"stale"`); recorder.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status %d for mismatching ETag, got %d", http.StatusPreconditionFailed, recorder.Code)
+	}
+	recorder := update(goalETag(goal))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d for matching ETag, got %d: %s", http.StatusOK, recorder.Code, recorder.Body)
+	}
+	var updated Goal
+	json.Unmarshal(recorder.Body.Bytes(), &updated)
+	if recorder.Header().Get("ETag") != goalETag(updated) || updated.ID != goal.ID {
+		t.Errorf("Expected updated goal with its ETag, got %s (ETag %q)", recorder.Body, recorder.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,116 @@
+// ФАЙЛ: health.go
+// НАЗНАЧЕНИЕ: Полная проверка состояния сервера для дежурного (GET /health/full)
+// ОСОБЕННОСТИ:
+//   - Проверяются БД (и реплика), каналы алертов, запись в файлы логов и состояние лимитера
+//   - Ответ — статус каждой подсистемы и общий 200 (всё в порядке) или 503
+//   - Проверки тяжелее обычного health-check, поэтому доступ только администратору
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Статусы проверок
+const (
+	healthStatusOK      = "ok"
+	healthStatusFail    = "fail"
+	healthStatusSkipped = "skipped" // Проверка для подсистемы не предусмотрена или она не настроена
+)
+
+// Таймаут одной проверки
+const healthCheckTimeout = 3 * time.Second
+
+// РЕЗУЛЬТАТ ПРОВЕРКИ ПОДСИСТЕМЫ
+type healthCheck struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// ОТВЕТ GET /health/full
+type fullHealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks"`
+}
+
+// Результат проверки по ошибке
+func healthResult(err error) healthCheck {
+	if err != nil {
+		return healthCheck{Status: healthStatusFail, Error: err.Error()}
+	}
+	return healthCheck{Status: healthStatusOK}
+}
+
+// ОБРАБОТЧИК: GET /health/full
+func fullHealthHandler(w http.ResponseWriter, r *http.Request) {
+	report := fullHealthReport{Status: healthStatusOK, Checks: make(map[string]healthCheck)}
+
+	// ШАГ 1: БАЗА ДАННЫХ
+	report.Checks["database"] = checkPool(r.Context(), dbPool != nil, func(ctx context.Context) error { return dbPool.Ping(ctx) })
+	if readPool != nil {
+		report.Checks["database_replica"] = checkPool(r.Context(), true, func(ctx context.Context) error { return readPool.Ping(ctx) })
+	}
+
+	// ШАГ 2: КАНАЛЫ АЛЕРТОВ
+	for _, alerter := range alerters {
+		checker, ok := alerter.(alerterChecker)
+		if !ok {
+			report.Checks["alerts:"+alerter.Name()] = healthCheck{Status: healthStatusSkipped}
+			continue
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		report.Checks["alerts:"+alerter.Name()] = healthResult(checker.Check(ctx))
+		cancel()
+	}
+
+	// ШАГ 3: ФАЙЛЫ ЛОГОВ
+	report.Checks["app_log"] = healthResult(checkLogWritable(appLogPath))
+	report.Checks["security_log"] = healthResult(checkLogWritable(securityLogPath))
+
+	// ШАГ 4: СОСТОЯНИЕ ЛИМИТЕРА (информационно, без ошибки)
+	countMutex.Lock()
+	report.Checks["rate_limiter"] = healthCheck{Status: healthStatusOK, Details: map[string]interface{}{
+		"disabled":    securityDisabled,
+		"limit":       requestLimit,
+		"tracked_ips": len(requestCounts),
+		"blocked_ips": len(blockedIPs),
+	}}
+	countMutex.Unlock()
+
+	// ШАГ 5: ОБЩИЙ СТАТУС
+	status := http.StatusOK
+	for _, check := range report.Checks {
+		if check.Status == healthStatusFail {
+			report.Status = healthStatusFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, r, status, report)
+}
+
+// Проверка пула: ping с таймаутом; configured=false — пул не создан
+func checkPool(ctx context.Context, configured bool, ping func(context.Context) error) healthCheck {
+	if !configured {
+		return healthCheck{Status: healthStatusFail, Error: "пул соединений не создан"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := healthResult(ping(ctx))
+	result.Details = map[string]interface{}{"latency_ms": time.Since(start).Milliseconds()}
+	return result
+}
+
+// Можно ли дописывать в файл лога (файл не создаётся, если его нет)
+func checkLogWritable(path string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
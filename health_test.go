@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ТЕСТ: GET /health/full — статус каждой подсистемы и общий 503 при отказе одной из них
+func TestFullHealthHandler(t *testing.T) {
+	securityLogger = log.New(io.Discard, "", 0)
+
+	// Telegram: getMe отвечает 200 только для правильного токена
+	telegram := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botgood/getMe" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer telegram.Close()
+
+	dir := t.TempDir()
+	appLog := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(appLog, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(api, app, sec, token string, list []Alerter, pool, replica *pgxpool.Pool) {
+		telegramAPIURL, appLogPath, securityLogPath, adminToken, alerters, dbPool, readPool = api, app, sec, token, list, pool, replica
+	}(telegramAPIURL, appLogPath, securityLogPath, adminToken, alerters, dbPool, readPool)
+
+	telegramAPIURL = telegram.URL
+	appLogPath = appLog
+	securityLogPath = filepath.Join(dir, "missing", "security.log")
+	adminToken = "secret"
+	alerters = []Alerter{&telegramAlerter{token: "good", chatID: "1"}, &telegramAlerter{token: "bad", chatID: "2"}}
+	dbPool, readPool = nil, nil
+
+	handler := adminMiddleware(http.HandlerFunc(fullHealthHandler))
+
+	// Без токена проверки не запускаются
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health/full", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without admin token, got %d", recorder.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/health/full", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var report fullHealthReport
+	if err := json.NewDecoder(recorder.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Status != healthStatusFail {
+		t.Errorf("expected overall status %q, got %q", healthStatusFail, report.Status)
+	}
+
+	expected := map[string]string{
+		"database":          healthStatusFail,
+		"alerts:telegram:1": healthStatusOK,
+		"alerts:telegram:2": healthStatusFail,
+		"app_log":           healthStatusOK,
+		"security_log":      healthStatusFail,
+		"rate_limiter":      healthStatusOK,
+	}
+	for name, status := range expected {
+		if got := report.Checks[name].Status; got != status {
+			t.Errorf("check %s: expected %q, got %q (%s)", name, status, got, report.Checks[name].Error)
+		}
+	}
+	if _, ok := report.Checks["database_replica"]; ok {
+		t.Error("expected no replica check without READ_DATABASE_URL")
+	}
+}
@@ -0,0 +1,203 @@
+// ФАЙЛ: idempotency.go
+// НАЗНАЧЕНИЕ: Повторная отправка POST-запроса с тем же Idempotency-Key не создаёт дубликатов
+// ОСОБЕННОСТИ:
+//   - Ответ на первый запрос сохраняется и возвращается на повторы (заголовок Idempotent-Replayed)
+//   - Пока первый запрос обрабатывается, повтор с тем же ключом получает 409
+//   - Ключ привязан к телу запроса (SHA-256): тот же ключ с другим телом получает 422
+//   - После паники или ошибки сервера (5xx) ключ освобождается, запрос можно повторить
+//   - Ключи хранятся IDEMPOTENCY_KEY_TTL; просроченные удаляет фоновая очистка
+//     раз в IDEMPOTENCY_CLEANUP_INTERVAL, иначе хранилище росло бы бесконечно
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ ИДЕМПОТЕНТНОСТИ
+var (
+	// Сколько хранить ключ и сохранённый ответ (IDEMPOTENCY_KEY_TTL)
+	idempotencyKeyTTL = 24 * time.Hour
+	// Как часто удалять просроченные ключи (IDEMPOTENCY_CLEANUP_INTERVAL)
+	idempotencyCleanupInterval = 10 * time.Minute
+	// Сохранённые ответы: метод + путь + ключ → ответ
+	idempotencyKeys  = make(map[string]*idempotentResponse)
+	idempotencyMutex sync.Mutex
+)
+
+// Максимальная длина Idempotency-Key
+const maxIdempotencyKeyLength = 255
+
+// СОХРАНЁННЫЙ ОТВЕТ НА ЗАПРОС С КЛЮЧОМ
+type idempotentResponse struct {
+	CreatedAt   time.Time
+	Fingerprint [sha256.Size]byte // Хэш тела первого запроса
+	Done        bool              // false — первый запрос ещё обрабатывается
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// ИНИЦИАЛИЗАЦИЯ ИДЕМПОТЕНТНОСТИ
+func initIdempotency() {
+	idempotencyKeyTTL = getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	if idempotencyKeyTTL <= 0 {
+		logger.InfoLogger.Printf("⚠️ IDEMPOTENCY_KEY_TTL должен быть больше нуля, используем %s", 24*time.Hour)
+		idempotencyKeyTTL = 24 * time.Hour
+	}
+	// Нулевой интервал уронил бы time.NewTicker в фоновой горутине очистки
+	idempotencyCleanupInterval = getEnvDuration("IDEMPOTENCY_CLEANUP_INTERVAL", 10*time.Minute)
+	if idempotencyCleanupInterval <= 0 {
+		logger.InfoLogger.Printf("⚠️ IDEMPOTENCY_CLEANUP_INTERVAL должен быть больше нуля, используем %s", 10*time.Minute)
+		idempotencyCleanupInterval = 10 * time.Minute
+	}
+	logger.InfoLogger.Printf("🔑 Ключи идемпотентности хранятся %s, очистка каждые %s",
+		idempotencyKeyTTL, idempotencyCleanupInterval)
+
+	startBackground("idempotency-cleanup", cleanIdempotencyKeys)
+}
+
+// MIDDLEWARE: Idempotency-Key для POST-запросов
+// Сохраняются только ответы без ошибки сервера: после 5xx или паники клиент может повторить запрос
+func idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeJSONError(w, r, http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key длиннее 255 символов")
+			return
+		}
+		storeKey := r.Method + " " + r.URL.Path + " " + key
+
+		// ШАГ 1: ОТПЕЧАТОК ТЕЛА (тело читается целиком и подставляется обратно для обработчика)
+		fingerprint, err := fingerprintBody(w, r)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeJSONError(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "Тело запроса слишком большое")
+				return
+			}
+			writeJSONError(w, r, http.StatusBadRequest, "body_read_error", "Не удалось прочитать тело запроса")
+			return
+		}
+
+		// ШАГ 2: Повтор — отдаём сохранённый ответ, 422 при другом теле или 409, если первый ещё в работе
+		idempotencyMutex.Lock()
+		if saved, exists := idempotencyKeys[storeKey]; exists && time.Since(saved.CreatedAt) < idempotencyKeyTTL {
+			idempotencyMutex.Unlock()
+			if saved.Fingerprint != fingerprint {
+				writeJSONError(w, r, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key уже использован с другим телом запроса")
+				return
+			}
+			if !saved.Done {
+				writeJSONError(w, r, http.StatusConflict, "idempotency_in_progress", "Запрос с этим Idempotency-Key ещё обрабатывается")
+				return
+			}
+			for name, values := range saved.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(saved.Status)
+			w.Write(saved.Body)
+			logger.LogRequest(r.Method, r.URL.Path, saved.Status)
+			return
+		}
+		saved := &idempotentResponse{CreatedAt: time.Now(), Fingerprint: fingerprint}
+		idempotencyKeys[storeKey] = saved
+		idempotencyMutex.Unlock()
+
+		// ШАГ 3: Первый запрос — выполняем и запоминаем ответ
+		// Итог записывается в defer: при панике обработчика ключ освобождается,
+		// иначе повторы получали бы 409 до конца IDEMPOTENCY_KEY_TTL
+		recorder := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			idempotencyMutex.Lock()
+			defer idempotencyMutex.Unlock()
+			if !completed || recorder.status >= http.StatusInternalServerError {
+				delete(idempotencyKeys, storeKey)
+				return
+			}
+			saved.Done = true
+			saved.Status = recorder.status
+			saved.Header = w.Header().Clone()
+			saved.Body = recorder.body.Bytes()
+		}()
+		next.ServeHTTP(recorder, r)
+		completed = true
+	})
+}
+
+// ФУНКЦИЯ: fingerprintBody
+// НАЗНАЧЕНИЕ: Считает SHA-256 тела и подставляет прочитанное тело обратно в запрос
+// Размер ограничен самым большим допустимым телом (импорт CSV), чтобы не читать в память что угодно
+func fingerprintBody(w http.ResponseWriter, r *http.Request) ([sha256.Size]byte, error) {
+	if r.Body == nil {
+		return sha256.Sum256(nil), nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, max(importMaxBytes, maxRequestBodyBytes)))
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return sha256.Sum256(body), nil
+}
+
+// СТРУКТУРА: ResponseWriter, который копирует статус и тело ответа
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	rw.body.Write(p)
+	return rw.ResponseWriter.Write(p)
+}
+
+// Периодически удаляем просроченные ключи
+func cleanIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if pruned := pruneIdempotencyKeys(now); pruned > 0 {
+				logger.InfoLogger.Printf("🧹 Удалено просроченных ключей идемпотентности: %d", pruned)
+			}
+		}
+	}
+}
+
+// Один проход очистки; возвращает количество удалённых ключей
+func pruneIdempotencyKeys(now time.Time) int {
+	idempotencyMutex.Lock()
+	defer idempotencyMutex.Unlock()
+
+	pruned := 0
+	for key, saved := range idempotencyKeys {
+		if now.Sub(saved.CreatedAt) >= idempotencyKeyTTL {
+			delete(idempotencyKeys, key)
+			pruned++
+		}
+	}
+	return pruned
+}
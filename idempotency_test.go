@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Повтор POST с тем же Idempotency-Key не создаёт вторую цель, а просроченные ключи удаляются
+func TestIdempotencyKey(t *testing.T) {
+	server := newTestServer(t)
+	idempotencyMutex.Lock()
+	idempotencyKeys = make(map[string]*idempotentResponse)
+	idempotencyMutex.Unlock()
+
+	post := func(key string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("POST", server.URL+"/goals", bytes.NewBufferString(`{"goal":"Once","timeline":"2026"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		return resp, body.Bytes()
+	}
+
+	first, firstBody := post("key-1")
+	second, secondBody := post("key-1")
+	if first.StatusCode != http.StatusCreated || second.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected both responses to be %d, got %d and %d", http.StatusCreated, first.StatusCode, second.StatusCode)
+	}
+	if !bytes.Equal(firstBody, secondBody) {
+		t.Errorf("Expected replayed body %s, got %s", firstBody, secondBody)
+	}
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected Idempotent-Replayed header on repeated request")
+	}
+	if count, _ := goalStore.Count(t.Context(), goalFilter{}); count != 1 {
+		t.Errorf("Expected 1 goal after repeated request, got %d", count)
+	}
+
+	post("key-2")
+	if count, _ := goalStore.Count(t.Context(), goalFilter{}); count != 2 {
+		t.Errorf("Expected a new key to create a goal, got %d goals", count)
+	}
+
+	if pruned := pruneIdempotencyKeys(time.Now()); pruned != 0 {
+		t.Errorf("Expected fresh keys to be kept, pruned %d", pruned)
+	}
+	if pruned := pruneIdempotencyKeys(time.Now().Add(idempotencyKeyTTL)); pruned != 2 {
+		t.Errorf("Expected 2 expired keys to be pruned, got %d", pruned)
+	}
+}
+
+// ТЕСТ: неположительные IDEMPOTENCY_KEY_TTL и IDEMPOTENCY_CLEANUP_INTERVAL заменяются значениями по умолчанию
+func TestInitIdempotencyRejectsNonPositive(t *testing.T) {
+	defer func(ttl, interval time.Duration) {
+		idempotencyKeyTTL, idempotencyCleanupInterval = ttl, interval
+	}(idempotencyKeyTTL, idempotencyCleanupInterval)
+	t.Cleanup(stopBackgroundTasks)
+
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "-1h")
+	t.Setenv("IDEMPOTENCY_CLEANUP_INTERVAL", "0s")
+	initIdempotency()
+
+	if idempotencyKeyTTL != 24*time.Hour {
+		t.Errorf("Expected default TTL 24h, got %s", idempotencyKeyTTL)
+	}
+	if idempotencyCleanupInterval != 10*time.Minute {
+		t.Errorf("Expected default cleanup interval 10m, got %s", idempotencyCleanupInterval)
+	}
+}
+
+// ТЕСТ: тот же ключ с другим телом получает 422, а паника обработчика освобождает ключ
+func TestIdempotencyKeyBodyAndPanic(t *testing.T) {
+	idempotencyMutex.Lock()
+	idempotencyKeys = make(map[string]*idempotentResponse)
+	idempotencyMutex.Unlock()
+
+	panicking := true
+	handler := idempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panicking {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	post := func(body string) (recorder *httptest.ResponseRecorder, panicked bool) {
+		req := httptest.NewRequest("POST", "/goals", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "key-1")
+		recorder = httptest.NewRecorder()
+		defer func() { panicked = recover() != nil }()
+		handler.ServeHTTP(recorder, req)
+		return recorder, false
+	}
+
+	if _, panicked := post(`{"goal":"a"}`); !panicked {
+		t.Fatal("Expected handler panic to propagate")
+	}
+	panicking = false
+	if recorder, _ := post(`{"goal":"a"}`); recorder.Code != http.StatusCreated {
+		t.Errorf("Expected retry after panic to run the handler (%d), got %d", http.StatusCreated, recorder.Code)
+	}
+	if recorder, _ := post(`{"goal":"b"}`); recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected %d for reused key with another body, got %d", http.StatusUnprocessableEntity, recorder.Code)
+	}
+	if recorder, _ := post(`{"goal":"a"}`); recorder.Code != http.StatusCreated || recorder.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected replay for the same body, got %d", recorder.Code)
+	}
+}
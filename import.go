@@ -0,0 +1,209 @@
+// ФАЙЛ: import.go
+// НАЗНАЧЕНИЕ: Загрузка целей из CSV (POST /goals/import)
+// ОСОБЕННОСТИ:
+//   - Файл передаётся в multipart/form-data в поле "file", размер ограничен IMPORT_MAX_BYTES
+//   - Первая строка — заголовок с именами полей как в JSON: goal, timeline,
+//     salary_target_rub_per_hour (или salary_target), currency, created_at
+//   - Каждая строка проверяется так же, как при создании цели; ошибочные попадают
+//     в отчёт с номерами строк, корректные вставляются по принципу «все или ни одной»
+//   - Большие файлы вставляются через COPY, в том числе параллельно (BULK_WORKERS, см. bulk.go)
+//   - created_at сохраняется только для администратора (как в POST /goals)
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Поле формы с CSV-файлом
+const importFileField = "file"
+
+// Максимальный размер загружаемого файла (IMPORT_MAX_BYTES, по умолчанию 5 МБ)
+var importMaxBytes int64 = 5 << 20
+
+// Колонки CSV: имя в заголовке → поле цели
+var importColumnAliases = map[string]string{
+	"goal":                       "goal",
+	"timeline":                   "timeline",
+	"salary_target_rub_per_hour": "salary_target_rub_per_hour",
+	"salary_target":              "salary_target_rub_per_hour",
+	"currency":                   "currency",
+	"created_at":                 "created_at",
+}
+
+// ОШИБОЧНАЯ СТРОКА В ОТЧЁТЕ ИМПОРТА
+type importFailure struct {
+	Line    int          `json:"line"`              // Номер строки в файле (заголовок — строка 1)
+	Message string       `json:"message,omitempty"` // Строку не удалось разобрать как CSV
+	Errors  []FieldError `json:"errors,omitempty"`  // Ошибки валидации полей
+}
+
+// ОТЧЁТ ИМПОРТА
+type importSummary struct {
+	Imported int             `json:"imported"`
+	Failed   []importFailure `json:"failed"`
+}
+
+// ОБРАБОТЧИК: POST /goals/import
+func importGoalsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ФАЙЛ ИЗ ФОРМЫ (с ограничением размера)
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes)
+	file, _, err := r.FormFile(importFileField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Файл больше %d байт", importMaxBytes), http.StatusRequestEntityTooLarge)
+			logger.LogRequest(r.Method, r.URL.Path, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Ожидается multipart/form-data с CSV-файлом в поле "+importFileField, http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	defer r.MultipartForm.RemoveAll()
+
+	// ШАГ 2: РАЗБОР И ПРОВЕРКА СТРОК
+	goals, failures, err := parseGoalsCSV(file, isAdminRequest(r))
+	if err != nil {
+		logger.LogError(err, "Ошибка разбора CSV в importGoalsCSVHandler")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusBadRequest)
+		return
+	}
+
+	// ШАГ 3: ВСТАВКА КОРРЕКТНЫХ СТРОК: ВСЕ ИЛИ НИ ОДНОЙ (с проверкой лимита MAX_GOALS_PER_USER)
+	if len(goals) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		err := goalStore.CreateMany(ctx, goals)
+		if errors.Is(err, errGoalLimitReached) {
+			logger.LogError(err, "Лимит целей в importGoalsCSVHandler")
+			http.Error(w, fmt.Sprintf("Импорт превысит лимит количества целей (%d), ни одна строка не загружена", maxGoalsPerUser), http.StatusConflict)
+			logger.LogRequest(r.Method, r.URL.Path, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.LogError(err, "Ошибка вставки в БД в importGoalsCSVHandler")
+			writeDBError(w, r, err, "Ошибка записи в БД")
+			return
+		}
+		invalidateCachedGoals()
+	}
+
+	logger.InfoLogger.Printf("📥 Импорт CSV: загружено %d, с ошибками %d", len(goals), len(failures))
+
+	// ШАГ 4: ОТЧЁТ
+	writeJSON(w, r, http.StatusOK, importSummary{Imported: len(goals), Failed: failures})
+}
+
+// ФУНКЦИЯ: parseGoalsCSV
+// НАЗНАЧЕНИЕ: Читает цели из CSV; возвращает корректные цели и ошибочные строки
+// Ошибка возвращается только для файла целиком (нет заголовка, неизвестная колонка)
+func parseGoalsCSV(input io.Reader, allowCreatedAt bool) ([]Goal, []importFailure, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1 // Число колонок проверяем сами, чтобы сообщить номер строки
+	reader.TrimLeadingSpace = true
+
+	// ШАГ 1: ЗАГОЛОВОК
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("Файл пустой: ожидается строка заголовка")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("Неверный заголовок CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		// Excel сохраняет UTF-8 с BOM; camelCase-имена принимаем, как и в JSON
+		name = strings.ToLower(camelToSnake(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))))
+		field, ok := importColumnAliases[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("Неизвестная колонка %q", header[i])
+		}
+		if _, duplicate := columns[field]; duplicate {
+			return nil, nil, fmt.Errorf("Колонка %q указана дважды", header[i])
+		}
+		columns[field] = i
+	}
+	for _, required := range []string{"goal", "timeline"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("В заголовке нет обязательной колонки %q", required)
+		}
+	}
+
+	// ШАГ 2: СТРОКИ
+	goals := []Goal{}
+	failures := []importFailure{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			failures = append(failures, importFailure{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			failures = append(failures, importFailure{Line: line,
+				Message: fmt.Sprintf("ожидается колонок: %d, получено: %d", len(header), len(record))})
+			continue
+		}
+
+		goal, errs := goalFromCSVRecord(record, columns, allowCreatedAt)
+		if len(errs) > 0 {
+			failures = append(failures, importFailure{Line: line, Errors: errs})
+			continue
+		}
+		goals = append(goals, goal)
+	}
+	return goals, failures, nil
+}
+
+// Цель из строки CSV с теми же проверками, что и в POST /goals
+func goalFromCSVRecord(record []string, columns map[string]int, allowCreatedAt bool) (Goal, []FieldError) {
+	value := func(field string) string {
+		if i, ok := columns[field]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var errs []FieldError
+	goal := Goal{Goal: value("goal"), Timeline: value("timeline"), Currency: value("currency")}
+
+	if raw := value("salary_target_rub_per_hour"); raw != "" {
+		salary, ok := parseDecimal(raw)
+		if !ok {
+			errs = append(errs, FieldError{Field: "salary_target_rub_per_hour", Code: ValidationCodeInvalid})
+		}
+		goal.SalaryTarget = salary
+	}
+	if raw := value("created_at"); raw != "" && allowCreatedAt {
+		createdAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "created_at", Code: ValidationCodeInvalid})
+		}
+		goal.CreatedAt = createdAt
+	}
+
+	applyGoalDefaults(&goal)
+	return goal, append(errs, validateGoal(goal)...)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// ТЕСТ: Ошибочные строки CSV попадают в отчёт с номерами, корректные — в результат
+func TestParseGoalsCSVMalformedRows(t *testing.T) {
+	input := "\ufeffgoal,timeline,salaryTarget,currency\n" +
+		"Learn Go,2026,1500.50,\n" + // 2: корректная
+		"Only goal,2026\n" + // 3: не хватает колонок
+		",2026,100,RUB\n" + // 4: пустая цель
+		"Bad salary,2026,много,RUB\n" + // 5: зарплата не число
+		"\"Broken \"quote\",2026,1,RUB\n" + // 6: ошибка CSV
+		"Negative,2026,-5,XYZ\n" + // 7: две ошибки валидации
+		"\"Multi\nline\",2027,0,usd\n" // 8–9: корректная многострочная
+
+	goals, failures, err := parseGoalsCSV(strings.NewReader(input), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(goals) != 2 || goals[0].Goal != "Learn Go" || goals[0].SalaryTarget != "1500.50" || goals[0].Currency != defaultCurrency ||
+		goals[1].Goal != "Multi\nline" || goals[1].Currency != "USD" {
+		t.Errorf("Unexpected imported goals: %+v", goals)
+	}
+
+	expected := map[int][]string{
+		3: nil,
+		4: {"goal"},
+		5: {"salary_target_rub_per_hour"},
+		6: nil,
+		7: {"salary_target_rub_per_hour", "currency"},
+	}
+	if len(failures) != len(expected) {
+		t.Fatalf("Expected %d failures, got %+v", len(expected), failures)
+	}
+	for _, failure := range failures {
+		fields, ok := expected[failure.Line]
+		if !ok {
+			t.Errorf("Unexpected failure on line %d: %+v", failure.Line, failure)
+			continue
+		}
+		if fields == nil && failure.Message == "" {
+			t.Errorf("Line %d: expected CSV error message", failure.Line)
+		}
+		if len(failure.Errors) != len(fields) {
+			t.Errorf("Line %d: expected errors for %v, got %+v", failure.Line, fields, failure.Errors)
+			continue
+		}
+		for i, field := range fields {
+			if failure.Errors[i].Field != field {
+				t.Errorf("Line %d: expected error for %s, got %+v", failure.Line, field, failure.Errors[i])
+			}
+		}
+	}
+}
+
+// ТЕСТ: Ошибки заголовка отклоняют файл целиком
+func TestParseGoalsCSVHeaderErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"goal,deadline\nLearn Go,2026\n",
+		"goal,salary_target\nLearn Go,1\n",
+		"goal,timeline,salary_target,salary_target_rub_per_hour\n",
+	} {
+		if _, _, err := parseGoalsCSV(strings.NewReader(input), false); err == nil {
+			t.Errorf("Expected error for header %q", input)
+		}
+	}
+}
+
+// ТЕСТ: POST /goals/import загружает корректные строки и возвращает отчёт
+func TestImportGoalsCSVInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	upload := func(field, content string) (int, []byte) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile(field, "goals.csv")
+		part.Write([]byte(content))
+		form.Close()
+
+		resp, err := server.Client().Post(server.URL+"/goals/import", form.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var data bytes.Buffer
+		data.ReadFrom(resp.Body)
+		return resp.StatusCode, data.Bytes()
+	}
+
+	status, body := upload("file", "goal,timeline,salary_target_rub_per_hour,created_at\n"+
+		"Learn Go,2026,1500,2020-01-01T00:00:00Z\nLearn SQL,,100,\n")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d (%s)", status, body)
+	}
+	var summary importSummary
+	json.Unmarshal(body, &summary)
+	if summary.Imported != 1 || len(summary.Failed) != 1 || summary.Failed[0].Line != 3 {
+		t.Errorf("Unexpected summary: %s", body)
+	}
+
+	// Цель сохранена; created_at без прав администратора проигнорирован
+	_, body = doJSON(t, server, "GET", "/goals", nil)
+	var goals []Goal
+	json.Unmarshal(body, &goals)
+	if len(goals) != 1 || goals[0].Goal != "Learn Go" || goals[0].CreatedAt.Year() == 2020 {
+		t.Errorf("Unexpected goals after import: %s", body)
+	}
+
+	// Без файла в поле file — 400
+	if status, _ := upload("data", "goal,timeline\n"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without file field, got %d", status)
+	}
+
+	// Файл больше лимита — 413
+	defer func(previous int64) { importMaxBytes = previous }(importMaxBytes)
+	importMaxBytes = 64
+	if status, _ := upload("file", "goal,timeline\n"+strings.Repeat("Learn Go,2026\n", 20)); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for oversized file, got %d", status)
+	}
+}
@@ -5,43 +5,157 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"strconv"
+	"sync/atomic"
 )
 
 type AppLogger struct {
 	InfoLogger  *log.Logger
 	ErrorLogger *log.Logger
+	// Стек-трейсы пишутся только в файл, чтобы не попадать в консоль/сборщики логов
+	StackLogger *log.Logger
+	// Отладочные сообщения пишутся только при LOG_DEBUG=true
+	DebugLogger *log.Logger
+
+	// Файл журнала (nil — файл не открылся); Flush и Close работают с ним
+	file *os.File
+
+	// LOG_SAMPLE_RATE: успешные запросы пишутся 1 из N (1 — все); ошибки пишутся всегда
+	sampleRate    uint64
+	sampleCounter atomic.Uint64
 }
 
+// Файл журнала приложения
+var appLogPath = "app.log"
+
 func NewLogger() *AppLogger {
+	return newLoggerWithFile(appLogPath)
+}
+
+// ФУНКЦИЯ: newLoggerWithFile
+// НАЗНАЧЕНИЕ: Создаёт логгер, который дублирует консольный вывод в файл path
+// Если файл не открыть (файловая система только для чтения или переполнена),
+// логи идут только в консоль: API должно работать и без локального журнала
+func newLoggerWithFile(path string) *AppLogger {
 	// Создаем файл логов
-	logFile, err := os.OpenFile("app.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	var logFile io.Writer
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		log.Fatalf("❌ Ошибка создания файла логов: %v", err)
+		// Стеки без файла не сохраняются: в консоль они не пишутся намеренно
+		logFile = io.Discard
+	} else {
+		logFile = file
 	}
 
-	// Создаем MultiWriter: пишем И в файл, И в консоль
-	multiWriter := io.MultiWriter(logFile, os.Stdout)
+	// Создаем MultiWriter'ы: оба логгера пишут в общий файл (удобно grep'ать локально),
+	// но в консоли информационные сообщения идут в stdout, а ошибки — в stderr,
+	// чтобы сборщики логов в контейнерах могли обрабатывать ошибки отдельно
+	infoWriter := io.MultiWriter(logFile, os.Stdout)
+	errorWriter := io.MultiWriter(logFile, os.Stderr)
 
 	// Настраиваем логгеры
-	infoLogger := log.New(multiWriter, "INFO: ", log.Ldate|log.Ltime|log.LUTC)
-	errorLogger := log.New(multiWriter, "ERROR: ", log.Ldate|log.Ltime|log.LUTC|log.Lshortfile)
+	infoLogger := log.New(infoWriter, "INFO: ", log.Ldate|log.Ltime|log.LUTC)
+	errorLogger := log.New(errorWriter, "ERROR: ", log.Ldate|log.Ltime|log.LUTC|log.Lshortfile)
+	stackLogger := log.New(logFile, "STACK: ", log.Ldate|log.Ltime|log.LUTC)
+
+	// Логгер ещё не создан, поэтому LOG_DEBUG читается без getEnvBool
+	debugWriter := io.Discard
+	if enabled, _ := strconv.ParseBool(os.Getenv("LOG_DEBUG")); enabled {
+		debugWriter = infoWriter
+	}
+	debugLogger := log.New(debugWriter, "DEBUG: ", log.Ldate|log.Ltime|log.LUTC)
 
-	return &AppLogger{
+	if err != nil {
+		errorLogger.Printf("⚠️⚠️⚠️ Не удалось открыть файл логов %s (%v): логи пишутся только в stdout/stderr, стек-трейсы не сохраняются", path, err)
+	}
+
+	// Логгер ещё не создан, поэтому LOG_SAMPLE_RATE читается без getEnvInt
+	sampleRate := uint64(1)
+	if rate, parseErr := strconv.ParseUint(os.Getenv("LOG_SAMPLE_RATE"), 10, 64); parseErr == nil && rate > 1 {
+		sampleRate = rate
+	}
+
+	appLogger := &AppLogger{
 		InfoLogger:  infoLogger,
 		ErrorLogger: errorLogger,
+		StackLogger: stackLogger,
+		DebugLogger: debugLogger,
+		sampleRate:  sampleRate,
+	}
+	if err == nil {
+		appLogger.file = file
 	}
+	return appLogger
+}
+
+// МЕТОД: Flush
+// Сбрасывает записанное в файл журнала на диск
+func (l *AppLogger) Flush() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// МЕТОД: Close
+// Сбрасывает и закрывает файл журнала при остановке сервера.
+// Логгеры после этого пишут только в консоль: io.MultiWriter остановился бы
+// на ошибке записи в закрытый файл и не дошёл бы до stdout/stderr
+func (l *AppLogger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	l.InfoLogger.SetOutput(os.Stdout)
+	l.ErrorLogger.SetOutput(os.Stderr)
+	l.StackLogger.SetOutput(io.Discard)
+	if l.DebugLogger.Writer() != io.Discard {
+		l.DebugLogger.SetOutput(os.Stdout)
+	}
+
+	file := l.file
+	l.file = nil
+	syncErr := file.Sync()
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return syncErr
 }
 
 // МЕТОД ДЛЯ ЛОГИРОВАНИЯ ЗАПРОСОВ
+// При LOG_SAMPLE_RATE > 1 строки об успешных запросах прореживаются (см. shouldLogRequest)
 func (l *AppLogger) LogRequest(method, path string, status int) {
+	if !l.shouldLogRequest(status) {
+		return
+	}
 	l.InfoLogger.Printf("%s %s %d", method, path, status)
 }
 
+// МЕТОД: shouldLogRequest
+// Ответы не из 2xx пишутся всегда, 2xx и отметки начала запроса (status 0) — каждый N-й
+func (l *AppLogger) shouldLogRequest(status int) bool {
+	if l.sampleRate <= 1 || (status != 0 && (status < 200 || status > 299)) {
+		return true
+	}
+	return l.sampleCounter.Add(1)%l.sampleRate == 0
+}
+
 // МЕТОД ДЛЯ ЛОГИРОВАНИЯ ОШИБОК
+// Сообщение идёт в ErrorLogger, а стек вызовов — только в файл
 func (l *AppLogger) LogError(err error, context string) {
 	if err != nil {
-		l.ErrorLogger.Printf("%s: %v\n%s", context, err, debug.Stack())
+		l.ErrorLogger.Printf("%s: %v", context, err)
+		l.StackLogger.Printf("%s: %v\n%s", context, err, debug.Stack())
 	} else {
 		l.ErrorLogger.Printf("%s", context)
 	}
 }
+
+// МЕТОД ДЛЯ ЛОГИРОВАНИЯ ПАНИК
+// Стек паники (собранный в recover) пишется только в файл вместе с ID запроса
+func (l *AppLogger) LogPanic(requestID, route string, value interface{}, stack []byte) {
+	l.ErrorLogger.Printf("PANIC [request_id=%s] %s: %v", requestID, route, value)
+	if stack != nil {
+		l.StackLogger.Printf("PANIC [request_id=%s] %s: %v\n%s", requestID, route, value, stack)
+	}
+}
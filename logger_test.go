@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// ТЕСТ: Без доступного файла логов логгер создаётся и пишет только в консоль
+func TestNewLoggerFallback(t *testing.T) {
+	fallback := newLoggerWithFile(t.TempDir() + "/missing/app.log")
+	if fallback.InfoLogger == nil || fallback.ErrorLogger == nil || fallback.StackLogger == nil {
+		t.Fatalf("Expected all loggers to be created, got %+v", fallback)
+	}
+	if fallback.StackLogger.Writer() != io.Discard {
+		t.Errorf("Expected stack traces to be discarded without a log file")
+	}
+
+	path := t.TempDir() + "/app.log"
+	fileLogger := newLoggerWithFile(path)
+	fileLogger.StackLogger.Println("TEST_STACK")
+	if content, err := os.ReadFile(path); err != nil || !strings.Contains(string(content), "TEST_STACK") {
+		t.Errorf("Expected stack entry in %s, got %q (%v)", path, content, err)
+	}
+}
+
+// ТЕСТ: Close сбрасывает файл журнала, после него логи идут только в консоль
+func TestLoggerClose(t *testing.T) {
+	path := t.TempDir() + "/app.log"
+	fileLogger := newLoggerWithFile(path)
+	fileLogger.InfoLogger.Println("BEFORE_CLOSE")
+
+	if err := fileLogger.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := fileLogger.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	// Повторное закрытие (например, после log.Fatalf-пути) безопасно
+	if err := fileLogger.Close(); err != nil {
+		t.Errorf("Expected second Close to be a no-op, got %v", err)
+	}
+
+	fileLogger.InfoLogger.Println("AFTER_CLOSE")
+	fileLogger.StackLogger.Println("AFTER_CLOSE")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "BEFORE_CLOSE") || strings.Contains(string(content), "AFTER_CLOSE") {
+		t.Errorf("Expected only lines written before Close in the file, got %q", content)
+	}
+	if fileLogger.InfoLogger.Writer() != os.Stdout {
+		t.Errorf("Expected info logger to write to stdout after Close")
+	}
+}
+
+// ТЕСТ: LOG_SAMPLE_RATE пишет каждый N-й успешный запрос, ошибки — всегда
+func TestLogRequestSampling(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "3")
+	sampled := newLoggerWithFile(t.TempDir() + "/app.log")
+	var out bytes.Buffer
+	sampled.InfoLogger.SetOutput(&out)
+
+	for i := 0; i < 9; i++ {
+		sampled.LogRequest("GET", "/goals", http.StatusOK)
+	}
+	sampled.LogRequest("GET", "/goals", http.StatusNotFound)
+	sampled.LogRequest("POST", "/goals", http.StatusInternalServerError)
+	sampled.LogRequest("GET", "/goals", http.StatusNotModified)
+
+	if got := strings.Count(out.String(), " 200\n"); got != 3 {
+		t.Errorf("Expected 3 of 9 successful requests to be logged, got %d", got)
+	}
+	for _, status := range []string{" 404\n", " 500\n", " 304\n"} {
+		if !strings.Contains(out.String(), status) {
+			t.Errorf("Expected non-2xx response %q to always be logged", status)
+		}
+	}
+
+	// По умолчанию (LOG_SAMPLE_RATE не задан) пишется всё
+	t.Setenv("LOG_SAMPLE_RATE", "")
+	full := newLoggerWithFile(t.TempDir() + "/app.log")
+	out.Reset()
+	full.InfoLogger.SetOutput(&out)
+	for i := 0; i < 5; i++ {
+		full.LogRequest("GET", "/goals", http.StatusOK)
+	}
+	if got := strings.Count(out.String(), " 200\n"); got != 5 {
+		t.Errorf("Expected all 5 requests to be logged by default, got %d", got)
+	}
+}
@@ -0,0 +1,83 @@
+// ФАЙЛ: longpoll.go
+// НАЗНАЧЕНИЕ: Long polling для GET /goals (?wait=<секунды>)
+// ОСОБЕННОСТИ:
+//   - Если список не изменился (If-None-Match совпал с ETag), запрос ждёт изменения
+//   - Ожидание прерывается изменением, истечением ?wait= или отключением клиента
+//   - Уведомления об изменениях — внутри процесса: каждая запись закрывает канал,
+//     на котором ждут все висящие запросы
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Максимальное время ожидания (дольше держать соединение не даст WriteTimeout сервера)
+const maxLongPollWait = 25 * time.Second
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ УВЕДОМЛЕНИЙ
+var (
+	// Канал закрывается при изменении списка и сразу заменяется новым
+	goalsChangedCh = make(chan struct{})
+	goalsChangedMu sync.Mutex
+)
+
+// Канал, который закроется при следующем изменении списка целей
+func goalsChanged() <-chan struct{} {
+	goalsChangedMu.Lock()
+	defer goalsChangedMu.Unlock()
+	return goalsChangedCh
+}
+
+// Будим все запросы, ожидающие изменения списка
+func notifyGoalsChanged() {
+	goalsChangedMu.Lock()
+	defer goalsChangedMu.Unlock()
+	close(goalsChangedCh)
+	goalsChangedCh = make(chan struct{})
+}
+
+// ФУНКЦИЯ: parseWait
+// НАЗНАЧЕНИЕ: Читает ?wait= (целые секунды) и ограничивает его сверху
+// Ожидание не может пережить таймаут обработчика, иначе клиент получит 504
+func parseWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, &filterError{Param: "wait", Message: "должен быть неотрицательным целым числом секунд"}
+	}
+
+	wait := time.Duration(seconds) * time.Second
+	limit := maxLongPollWait
+	if handlerTimeout > 0 && handlerTimeout-time.Second < limit {
+		limit = handlerTimeout - time.Second
+	}
+	if wait > limit {
+		wait = limit
+	}
+	return wait, nil
+}
+
+// ФУНКЦИЯ: waitForGoalsChange
+// НАЗНАЧЕНИЕ: Ждёт изменения списка, истечения wait или отключения клиента
+// Возвращает false, если клиент ушёл и отвечать больше некому
+func waitForGoalsChange(r *http.Request, changed <-chan struct{}, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-changed:
+		return true
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Ожидание завершается при изменении списка и при отключении клиента
+func TestWaitForGoalsChange(t *testing.T) {
+	req := httptest.NewRequest("GET", "/goals?wait=5", nil)
+	changed := goalsChanged()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		notifyGoalsChanged()
+	}()
+
+	start := time.Now()
+	if !waitForGoalsChange(req, changed, 5*time.Second) {
+		t.Errorf("Expected wait to finish normally after a change")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected change to wake the waiter quickly, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if waitForGoalsChange(req.WithContext(ctx), goalsChanged(), 5*time.Second) {
+		t.Errorf("Expected wait to report a disconnected client")
+	}
+}
+
+// ТЕСТ: ?wait= ограничивается таймаутом обработчика
+func TestParseWait(t *testing.T) {
+	defer func(previous time.Duration) { handlerTimeout = previous }(handlerTimeout)
+	handlerTimeout = 10 * time.Second
+
+	cases := map[string]time.Duration{
+		"":         0,
+		"?wait=3":  3 * time.Second,
+		"?wait=60": 9 * time.Second,
+	}
+	for query, expected := range cases {
+		got, err := parseWait(httptest.NewRequest("GET", "/goals"+query, nil))
+		if err != nil || got != expected {
+			t.Errorf("%q: expected %s, got %s (err %v)", query, expected, got, err)
+		}
+	}
+
+	if _, err := parseWait(httptest.NewRequest("GET", "/goals?wait=-1", nil)); err == nil {
+		t.Errorf("Expected error for negative wait")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !integration
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// Тесты по умолчанию не требуют базы данных: обработчики работают
+// с in-memory хранилищем (newTestServer). Тесты против настоящего PostgreSQL
+// собираются с тегом integration: go test -tags integration ./...
+func TestMain(m *testing.M) {
+	logger = NewLogger()
+	os.Exit(m.Run())
+}
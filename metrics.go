@@ -5,8 +5,15 @@
 package main
 
 import (
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,46 +31,361 @@ var (
 		[]string{"method", "endpoint", "status"},
 	)
 
+	// СЧЁТЧИК ПАНИК В ОБРАБОТЧИКАХ (метка — нормализованный маршрут)
+	panicsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "panics_total",
+			Help: "Количество паник в обработчиках запросов",
+		},
+		[]string{"route"},
+	)
+
+	// БЛОКИРОВКИ IP ПОДСИСТЕМОЙ БЕЗОПАСНОСТИ (метка — причина блокировки)
+	rateLimitBlocks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_blocks_total",
+			Help: "Количество блокировок IP по причинам",
+		},
+		[]string{"reason"},
+	)
+
+	// ОТКЛОНЁННЫЕ ЗАПРОСЫ (429/403 из securityMiddleware, метка — причина)
+	rateLimitRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_rejections_total",
+			Help: "Количество запросов, отклонённых подсистемой безопасности, по причинам",
+		},
+		[]string{"reason"},
+	)
+
+	// КОЛИЧЕСТВО ЗАПРОСОВ В ОБРАБОТКЕ
+	requestsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Количество HTTP запросов, обрабатываемых в данный момент",
+		},
+	)
+
+	// ЗАПРОСЫ, ОТКЛОНЁННЫЕ ОГРАНИЧИТЕЛЕМ ПАРАЛЛЕЛЬНОСТИ (MAX_CONCURRENT_REQUESTS)
+	requestsShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_shed_total",
+			Help: "Количество запросов, отклонённых с 503 из-за превышения MAX_CONCURRENT_REQUESTS",
+		},
+		[]string{"method"},
+	)
+
+	// РАЗМЕР ТЕЛА ЗАПРОСА В БАЙТАХ (для планирования мощностей)
+	requestSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "Размер тела HTTP запроса в байтах",
+			Buckets: sizeBuckets,
+		},
+		[]string{"method", "endpoint"},
+	)
+
+	// РАЗМЕР ТЕЛА ОТВЕТА В БАЙТАХ
+	responseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "Размер тела HTTP ответа в байтах",
+			Buckets: sizeBuckets,
+		},
+		[]string{"method", "endpoint"},
+	)
+
+	// Границы корзин размера: от 64 байт до 16 МБ с шагом ×4
+	sizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
 	// ЗАМЕР ВРЕМЕНИ ОБРАБОТКИ
+	// Создаётся в initMetrics, потому что границы корзин настраиваются через окружение
+	requestDuration *prometheus.HistogramVec
+
+	// Границы корзин по умолчанию: от 5 мс до ~10 с с удвоением,
+	// чтобы медленные запросы к БД не сваливались в +Inf
+	defaultDurationBuckets = prometheus.ExponentialBuckets(0.005, 2, 12)
+
+	// Метрики регистрируются в глобальном реестре только один раз:
+	// повторная регистрация паникует (например, если initMetrics вызовут из тестов)
+	metricsOnce sync.Once
+)
+
+// ИНИЦИАЛИЗАЦИЯ МЕТРИК
+// Безопасна при повторном вызове: второй и последующие вызовы ничего не делают
+func initMetrics() {
+	metricsOnce.Do(registerMetrics)
+}
+
+// ФУНКЦИЯ: registerMetrics
+// НАЗНАЧЕНИЕ: Создаёт гистограмму и регистрирует все метрики в Prometheus
+func registerMetrics() {
 	requestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "http_request_duration_seconds",
 			Help:    "Время обработки запросов в секундах",
-			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+			Buckets: durationBuckets(),
 		},
 		[]string{"method", "endpoint"},
 	)
-)
 
-// ИНИЦИАЛИЗАЦИЯ МЕТРИК
-func initMetrics() {
 	prometheus.MustRegister(requestCount)
+	prometheus.MustRegister(requestsInFlight)
+	prometheus.MustRegister(requestsShed)
+	prometheus.MustRegister(panicsTotal)
+	prometheus.MustRegister(rateLimitBlocks)
+	prometheus.MustRegister(rateLimitRejections)
 	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(requestSize)
+	prometheus.MustRegister(responseSize)
 	log.Println("✅ Метрики зарегистрированы в Prometheus")
 }
 
+// ФУНКЦИЯ: resetMetrics
+// НАЗНАЧЕНИЕ: Обнуляет значения всех метрик (для тестов)
+// Метрики остаются зарегистрированными, сбрасываются только накопленные значения,
+// поэтому тест может проверять точные значения счётчиков, а не разницу "до/после"
+func resetMetrics() {
+	initMetrics()
+
+	requestCount.Reset()
+	panicsTotal.Reset()
+	rateLimitBlocks.Reset()
+	rateLimitRejections.Reset()
+	requestDuration.Reset()
+	requestSize.Reset()
+	responseSize.Reset()
+	requestsInFlight.Set(0)
+	requestsShed.Reset()
+	inFlightRequests.Store(0)
+}
+
+// ФУНКЦИЯ: durationBuckets
+// НАЗНАЧЕНИЕ: Читает границы корзин из METRICS_DURATION_BUCKETS ("0.01,0.1,1,5,10")
+// При отсутствии или ошибке разбора используются значения по умолчанию
+func durationBuckets() []float64 {
+	value := strings.TrimSpace(os.Getenv("METRICS_DURATION_BUCKETS"))
+	if value == "" {
+		return defaultDurationBuckets
+	}
+
+	var buckets []float64
+	for _, part := range strings.Split(value, ",") {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || bucket <= 0 {
+			log.Printf("⚠️ Некорректное значение METRICS_DURATION_BUCKETS=%q, используем корзины по умолчанию", value)
+			return defaultDurationBuckets
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	// Prometheus требует строго возрастающие границы
+	sort.Float64s(buckets)
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			log.Printf("⚠️ Повторяющиеся границы в METRICS_DURATION_BUCKETS=%q, используем корзины по умолчанию", value)
+			return defaultDurationBuckets
+		}
+	}
+	return buckets
+}
+
+// Счётчик запросов в обработке (дублирует gauge, чтобы его можно было прочитать при остановке)
+var inFlightRequests atomic.Int64
+
+// MIDDLEWARE ДЛЯ ПОДСЧЁТА ЗАПРОСОВ В ОБРАБОТКЕ
+// Оборачивает весь сервер, поэтому учитывает любые маршруты
+func inFlightMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlightRequests.Add(1)
+		requestsInFlight.Inc()
+		defer func() {
+			inFlightRequests.Add(-1)
+			requestsInFlight.Dec()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // MIDDLEWARE ДЛЯ СБОРА МЕТРИК
 func metricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		// Размер запроса берём из Content-Length; без него (chunked) считаем прочитанные байты
+		var body *countingReader
+		if r.ContentLength < 0 && r.Body != nil {
+			body = &countingReader{ReadCloser: r.Body}
+			r.Body = body
+		}
+		sized := &sizeRecorder{ResponseWriter: w}
+
 		// Выполняем основной обработчик
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(sized, r)
 
 		// Считаем время выполнения
-		duration := time.Since(start).Seconds()
+		elapsed := time.Since(start)
+		duration := elapsed.Seconds()
 
-		// Логируем для отладки
-		logger.InfoLogger.Printf("📊 METRIC: %s %s | %.3f сек", r.Method, r.URL.Path, duration)
+		// Логируем для отладки (прореживается вместе со строками запросов, LOG_SAMPLE_RATE;
+		// ответы не из 2xx пишутся всегда)
+		statusCode := sized.statusCode()
+		if logger.shouldLogRequest(statusCode) {
+			logger.InfoLogger.Printf("📊 METRIC: %s %s %d | %.3f сек", r.Method, r.URL.Path, statusCode, duration)
+		}
 
 		// Обновляем счётчики
-		requestCount.WithLabelValues(r.Method, r.URL.Path, "200").Inc()
-		requestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(duration)
+		// Метки — шаблон маршрута, а не сырой путь: /goals/1, /goals/2... → /goals/{id}
+		method, route := normalizeMethod(r.Method), normalizeRoute(r)
+		status := strconv.Itoa(statusCode)
+		requestCount.WithLabelValues(method, route, status).Inc()
+		requestDuration.WithLabelValues(method, route).Observe(duration)
+		requestBytes := r.ContentLength
+		if body != nil {
+			requestBytes = body.n
+		}
+		requestSize.WithLabelValues(method, route).Observe(float64(requestBytes))
+		responseSize.WithLabelValues(method, route).Observe(float64(sized.bytes))
+
+		// Те же метрики дублируем в StatsD (если настроен)
+		recordStatsDRequest(method, route, status, elapsed)
 	})
 }
 
+// СТРУКТУРА: ResponseWriter, который запоминает статус и считает байты тела ответа
+// Flush и Unwrap пробрасываются, чтобы потоковая выгрузка (NDJSON) и
+// http.ResponseController продолжали работать через обёртку
+type sizeRecorder struct {
+	http.ResponseWriter
+	status int // 0 — заголовки ещё не отправлены
+	bytes  int64
+}
+
+func (sr *sizeRecorder) WriteHeader(status int) {
+	// Информационные ответы (1xx) не окончательные, статус запроса придёт следующим
+	if sr.status == 0 && status >= http.StatusOK {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *sizeRecorder) Write(p []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK // Как net/http: Write без WriteHeader отвечает 200
+	}
+	n, err := sr.ResponseWriter.Write(p)
+	sr.bytes += int64(n)
+	return n, err
+}
+
+// Статус ответа; обработчик, который ничего не записал, отвечает 200
+func (sr *sizeRecorder) statusCode() int {
+	if sr.status == 0 {
+		return http.StatusOK
+	}
+	return sr.status
+}
+
+func (sr *sizeRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sr *sizeRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// СТРУКТУРА: тело запроса, которое считает прочитанные байты
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// Известные неизменяемые сегменты внутри префиксных маршрутов (например, /goals/delete).
+// Всё остальное, кроме числовых ID, схлопывается в {unknown}, чтобы число меток было ограничено
+var knownRouteSegments = map[string]bool{
+	"count":     true,
+	"delete":    true,
+	"duplicate": true,
+	"import":    true,
+	"ndjson":    true,
+	"stats":     true,
+	"timelines": true,
+	"toggle":    true,
+}
+
+// ФУНКЦИЯ: normalizeRoute
+// НАЗНАЧЕНИЕ: Превращает путь запроса в шаблон маршрута для меток метрик
+// Использует шаблоны, зарегистрированные в appMux: /goals/11 → /goals/{id}
+func normalizeRoute(r *http.Request) string {
+	_, pattern := appMux.Handler(r)
+	// Шаблоны с методом ("PUT /goals/{id}") уже содержат готовую метку
+	if method, path, found := strings.Cut(pattern, " "); found && method != "" {
+		return path
+	}
+
+	// Точное совпадение с зарегистрированным маршрутом
+	if pattern == r.URL.Path {
+		return pattern
+	}
+
+	// Корневой обработчик отвечает 404 на всё неизвестное
+	if pattern == "" || pattern == "/" {
+		return "other"
+	}
+
+	// Префиксный маршрут (/goals/): нормализуем хвост пути по сегментам
+	tail := strings.Trim(strings.TrimPrefix(r.URL.Path, pattern), "/")
+	if tail == "" {
+		return pattern
+	}
+	segments := strings.Split(tail, "/")
+	for i, segment := range segments {
+		switch {
+		case isNumericSegment(segment):
+			segments[i] = "{id}"
+		case knownRouteSegments[segment]:
+		default:
+			segments[i] = "{unknown}"
+		}
+	}
+	return pattern + strings.Join(segments, "/")
+}
+
+// Проверяем, состоит ли сегмент пути только из цифр
+func isNumericSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, c := range segment {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ФУНКЦИЯ: normalizeMethod
+// НАЗНАЧЕНИЕ: Ограничивает метку method стандартными HTTP-методами
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
 // РЕГИСТРАЦИЯ ENDPOINT ДЛЯ PROMETHEUS
 func registerMetricsEndpoint() {
-	http.Handle("/metrics", promhttp.Handler())
+	appMux.Handle("/metrics", promhttp.Handler())
 	logger.InfoLogger.Println("✅ Endpoint /metrics зарегистрирован")
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// ТЕСТ: Нормализация путей для меток метрик
+func TestNormalizeRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/", noop)
+	mux.Handle("/goals", noop)
+	mux.Handle("/goals/", noop)
+	mux.Handle("/metrics", noop)
+
+	defer func(previous *http.ServeMux) { appMux = previous }(appMux)
+	appMux = mux
+
+	cases := map[string]string{
+		"/goals":          "/goals",
+		"/goals/1":        "/goals/{id}",
+		"/goals/123456":   "/goals/{id}",
+		"/goals/delete":   "/goals/delete",
+		"/goals/abc":      "/goals/{unknown}",
+		"/metrics":        "/metrics",
+		"/wp-login.php":   "other",
+		"/":               "/",
+		"/goals/12/extra": "/goals/{id}/{unknown}",
+	}
+
+	for path, expected := range cases {
+		if got := normalizeRoute(httptest.NewRequest("GET", path, nil)); got != expected {
+			t.Errorf("normalizeRoute(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+// ТЕСТ: Повторная инициализация метрик не паникует, а сброс обнуляет значения
+func TestInitMetricsIdempotent(t *testing.T) {
+	initMetrics()
+	initMetrics()
+
+	requestCount.WithLabelValues("GET", "/goals", "200").Inc()
+	resetMetrics()
+
+	if got := testutil.ToFloat64(requestCount.WithLabelValues("GET", "/goals", "200")); got != 0 {
+		t.Errorf("Expected reset counter to be 0, got %v", got)
+	}
+}
+
+// ТЕСТ: Размеры запроса и ответа попадают в гистограммы с меткой шаблона маршрута
+func TestMetricsMiddlewareSizes(t *testing.T) {
+	resetMetrics()
+	defer resetMetrics()
+
+	mux := http.NewServeMux()
+	mux.Handle("PUT /goals/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer func(previous *http.ServeMux) { appMux = previous }(appMux)
+	appMux = mux
+
+	handler := metricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("0123456789"))
+		w.Write([]byte("01234"))
+	}))
+
+	// Content-Length известен
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/goals/7", strings.NewReader("abcd")))
+
+	// Chunked: длина неизвестна, байты считаются при чтении
+	req := httptest.NewRequest("PUT", "/goals/8", strings.NewReader("abcdef"))
+	req.ContentLength = -1
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Оба запроса и оба ответа меньше 64 байт: все наблюдения в первой корзине
+	for _, tc := range []struct {
+		collector prometheus.Collector
+		name      string
+		help      string
+		sum       int
+	}{
+		{requestSize, "http_request_size_bytes", "Размер тела HTTP запроса в байтах", 4 + 6},
+		{responseSize, "http_response_size_bytes", "Размер тела HTTP ответа в байтах", 15 + 15},
+	} {
+		var expected strings.Builder
+		fmt.Fprintf(&expected, "# HELP %s %s\n# TYPE %s histogram\n", tc.name, tc.help, tc.name)
+		for _, bucket := range sizeBuckets {
+			fmt.Fprintf(&expected, "%s_bucket{endpoint=\"/goals/{id}\",method=\"PUT\",le=\"%g\"} 2\n", tc.name, bucket)
+		}
+		fmt.Fprintf(&expected, "%s_bucket{endpoint=\"/goals/{id}\",method=\"PUT\",le=\"+Inf\"} 2\n", tc.name)
+		fmt.Fprintf(&expected, "%s_sum{endpoint=\"/goals/{id}\",method=\"PUT\"} %d\n", tc.name, tc.sum)
+		fmt.Fprintf(&expected, "%s_count{endpoint=\"/goals/{id}\",method=\"PUT\"} 2\n", tc.name)
+
+		if err := testutil.CollectAndCompare(tc.collector, strings.NewReader(expected.String()), tc.name); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+// ТЕСТ: Счётчик запросов получает настоящий статус ответа, а не всегда 200
+func TestMetricsMiddlewareStatusLabel(t *testing.T) {
+	resetMetrics()
+	defer resetMetrics()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /goals", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer func(previous *http.ServeMux) { appMux = previous }(appMux)
+	appMux = mux
+
+	for _, handler := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+		func(w http.ResponseWriter, r *http.Request) {},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusOK) // Повторный WriteHeader игнорируется net/http
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "not found", http.StatusNotFound)
+		},
+	} {
+		metricsMiddleware(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/goals", nil))
+	}
+
+	for status, expected := range map[string]float64{"200": 2, "404": 1, "500": 1} {
+		if got := testutil.ToFloat64(requestCount.WithLabelValues("GET", "/goals", status)); got != expected {
+			t.Errorf("Expected %v requests with status %s, got %v", expected, status, got)
+		}
+	}
+}
+
+// ТЕСТ: При LOG_SAMPLE_RATE строка METRIC об ошибке пишется всегда, успешные — прореживаются
+func TestMetricsMiddlewareLogSampling(t *testing.T) {
+	resetMetrics()
+	defer resetMetrics()
+
+	t.Setenv("LOG_SAMPLE_RATE", "1000")
+	defer func(previous *AppLogger) { logger = previous }(logger)
+	logger = newLoggerWithFile(t.TempDir() + "/app.log")
+	var out bytes.Buffer
+	logger.InfoLogger.SetOutput(&out)
+
+	ok := metricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	failed := metricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	for i := 0; i < 5; i++ {
+		ok.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/goals", nil))
+	}
+	failed.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/goals", nil))
+
+	if got := strings.Count(out.String(), "METRIC: GET /goals 200"); got != 0 {
+		t.Errorf("Expected successful requests to be sampled out, got %d lines", got)
+	}
+	if !strings.Contains(out.String(), "METRIC: GET /goals 500") {
+		t.Errorf("Expected METRIC line for a failed request to always be logged, got %q", out.String())
+	}
+}
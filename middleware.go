@@ -0,0 +1,297 @@
+// ФАЙЛ: middleware.go
+// НАЗНАЧЕНИЕ: Общие middleware, не относящиеся к безопасности и метрикам
+// ОСОБЕННОСТИ:
+//   - Ограничение времени обработки запроса (504 Gateway Timeout)
+//   - Идентификатор запроса (X-Request-ID) для сквозного поиска в логах
+//   - Единый формат JSON-ошибок (в том числе 404 для неизвестных путей)
+//   - Ограничение числа одновременных запросов (503 вместо перегрузки БД);
+//     long polling (GET /goals?wait=) ограничивается отдельно и не занимает общие слоты
+//   - Перенаправление HTTP → HTTPS за прокси по X-Forwarded-Proto (FORCE_HTTPS)
+//   - Заголовки безопасности для всех ответов (nosniff, запрет фреймов, CSP, Referrer-Policy)
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ MIDDLEWARE
+var (
+	// Максимальное время обработки запроса
+	handlerTimeout = 10 * time.Second
+	// Максимум одновременно обрабатываемых запросов (MAX_CONCURRENT_REQUESTS, 0 — без ограничения)
+	maxConcurrentRequests = 0
+	// Семафор ограничителя: занятый слот — запрос в обработке (nil — ограничения нет)
+	concurrencySlots chan struct{}
+	// Максимум одновременных long poll (MAX_LONG_POLL_REQUESTS, по умолчанию как
+	// MAX_CONCURRENT_REQUESTS; 0 — без ограничения)
+	maxLongPollRequests = 0
+	// Отдельный семафор для long poll: ожидание до 25 секунд не должно вытеснять обычные запросы
+	longPollSlots chan struct{}
+	// Перенаправлять запросы, пришедшие на прокси по HTTP, на HTTPS (FORCE_HTTPS)
+	forceHTTPS = false
+	// Content-Security-Policy ответов (CONTENT_SECURITY_POLICY, пустое значение — не отправлять)
+	contentSecurityPolicy = defaultContentSecurityPolicy
+)
+
+// CSP по умолчанию: корневая страница использует только встроенные стили,
+// поэтому скрипты, изображения и встраивание во фреймы запрещены
+const defaultContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+// Ключ контекста для идентификатора запроса
+type requestIDKey struct{}
+
+// Максимальная длина X-Request-ID, принимаемого от клиента
+const maxRequestIDLength = 64
+
+// ИНИЦИАЛИЗАЦИЯ MIDDLEWARE
+func initMiddleware() {
+	handlerTimeout = getEnvDuration("HANDLER_TIMEOUT", 10*time.Second)
+	logger.InfoLogger.Printf("⏱️ Таймаут обработки запроса: %s", handlerTimeout)
+
+	maxConcurrentRequests = getEnvInt("MAX_CONCURRENT_REQUESTS", 0)
+	concurrencySlots = nil
+	if maxConcurrentRequests > 0 {
+		concurrencySlots = make(chan struct{}, maxConcurrentRequests)
+		logger.InfoLogger.Printf("🚦 Одновременных запросов не больше %d, остальные получают 503", maxConcurrentRequests)
+	}
+
+	maxLongPollRequests = getEnvInt("MAX_LONG_POLL_REQUESTS", maxConcurrentRequests)
+	longPollSlots = nil
+	if maxLongPollRequests > 0 {
+		longPollSlots = make(chan struct{}, maxLongPollRequests)
+		logger.InfoLogger.Printf("🚦 Одновременных long poll не больше %d (отдельно от обычных запросов)", maxLongPollRequests)
+	}
+
+	forceHTTPS = getEnvBool("FORCE_HTTPS", false)
+	if forceHTTPS {
+		logger.InfoLogger.Println("🔒 HTTP-запросы (X-Forwarded-Proto: http) перенаправляются на HTTPS")
+	}
+
+	contentSecurityPolicy = defaultContentSecurityPolicy
+	if policy, ok := os.LookupEnv("CONTENT_SECURITY_POLICY"); ok {
+		contentSecurityPolicy = strings.TrimSpace(policy)
+	}
+	if contentSecurityPolicy == "" {
+		logger.InfoLogger.Println("⚠️ CONTENT_SECURITY_POLICY пуст, заголовок Content-Security-Policy не отправляется")
+	}
+}
+
+// MIDDLEWARE: Заголовки безопасности
+// Ставятся до обработчика, поэтому попадают и в ответы с ошибками, и в перенаправления
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		if contentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", contentSecurityPolicy)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ФУНКЦИЯ: writeJSONError
+// НАЗНАЧЕНИЕ: Отправляет ошибку в формате {"error":{"code":...,"message":...}}
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	writeJSON(w, r, status, map[string]map[string]string{
+		"error": {"code": code, "message": message},
+	})
+}
+
+// ФУНКЦИЯ: notFoundHandler
+// НАЗНАЧЕНИЕ: JSON-ответ 404 для путей, которых нет в appMux
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, r, http.StatusNotFound, "not_found", "Маршрут не найден")
+}
+
+// MIDDLEWARE: Ограничение числа одновременных запросов (load shedding)
+// Когда все слоты заняты, запрос сразу получает 503 с Retry-After, не дожидаясь очереди.
+// /metrics не ограничивается, чтобы мониторинг видел перегрузку.
+// Long poll берёт слот из своего семафора: иначе висящие ожидания занимали бы все общие слоты
+func concurrencyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slots := concurrencySlots
+		if isLongPollRequest(r) {
+			slots = longPollSlots
+		}
+		if slots == nil || r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			requestsShed.WithLabelValues(normalizeMethod(r.Method)).Inc()
+			w.Header().Set("Retry-After", "1")
+			writeJSONError(w, r, http.StatusServiceUnavailable, "overloaded", "Сервер перегружен, повторите запрос позже")
+		}
+	})
+}
+
+// Запрос на long polling: GET /goals с ненулевым ?wait= (см. longpoll.go)
+func isLongPollRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet || r.URL.Path != "/goals" {
+		return false
+	}
+	wait := r.URL.Query().Get("wait")
+	return wait != "" && wait != "0"
+}
+
+// MIDDLEWARE: Перенаправление на HTTPS
+// Схему исходного запроса сообщает прокси (Heroku router) в X-Forwarded-Proto.
+// Служебные пути (/health, /metrics...) не перенаправляются: пробы и скрейпер
+// ходят к приложению напрямую по HTTP
+func httpsRedirectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !forceHTTPS || isExemptPath(r.URL.Path) || !isForwardedHTTP(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// 301 браузеры повторяют как GET, поэтому для запросов с телом — 308 (метод сохраняется)
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), status)
+	})
+}
+
+// Пришёл ли запрос на прокси по HTTP
+// Через цепочку прокси заголовок может быть списком — решает первый (ближайший к клиенту)
+func isForwardedHTTP(r *http.Request) bool {
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "http")
+}
+
+// MIDDLEWARE: Ограничение времени обработки запроса
+// Если обработчик не уложился в handlerTimeout, клиент получает 504,
+// а контекст запроса отменяется, чтобы прервать запрос к БД
+func timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		// Обработчик пишет в буфер, чтобы после таймаута его ответ не смешался с нашим
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			// Передаём панику дальше, чтобы её обработал alertMiddleware
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			for key, values := range tw.header {
+				w.Header()[key] = values
+			}
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.body.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+
+			logger.InfoLogger.Printf("⏱️ Превышен таймаут %s: %s %s", handlerTimeout, r.Method, r.URL.Path)
+			writeJSONError(w, r, http.StatusGatewayTimeout, "timeout", "Превышено время обработки запроса")
+		}
+	})
+}
+
+// СТРУКТУРА: Буферизующий ResponseWriter для timeoutMiddleware
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+// MIDDLEWARE: Идентификатор запроса
+// Берёт X-Request-ID от клиента/прокси или генерирует новый и возвращает его в ответе
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Получаем идентификатор текущего запроса ("-", если его нет)
+func getRequestID(r *http.Request) string {
+	if requestID, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	return "-"
+}
+
+// Генерируем случайный идентификатор из 16 hex-символов
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "-"
+	}
+	return hex.EncodeToString(buf)
+}
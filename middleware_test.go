@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// ТЕСТ: Медленный обработчик получает 504 и отменённый контекст
+func TestTimeoutMiddleware(t *testing.T) {
+	defer func(previous time.Duration) { handlerTimeout = previous }(handlerTimeout)
+	handlerTimeout = 50 * time.Millisecond
+
+	cancelled := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(time.Second):
+		}
+	})
+
+	recorder := httptest.NewRecorder()
+	timeoutMiddleware(slow).ServeHTTP(recorder, httptest.NewRequest("GET", "/goals", nil))
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusGatewayTimeout, recorder.Code)
+	}
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Expected JSON error, got Content-Type %q", ct)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("Expected request context to be cancelled")
+	}
+}
+
+// ТЕСТ: Быстрый обработчик отвечает как обычно
+func TestTimeoutMiddlewarePassThrough(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("ok"))
+	})
+
+	recorder := httptest.NewRecorder()
+	timeoutMiddleware(fast).ServeHTTP(recorder, httptest.NewRequest("POST", "/goals", nil))
+
+	if recorder.Code != http.StatusCreated || recorder.Body.String() != "ok" || recorder.Header().Get("X-Test") != "1" {
+		t.Errorf("Unexpected response: %d %q %v", recorder.Code, recorder.Body.String(), recorder.Header())
+	}
+}
+
+// ТЕСТ: Неизвестный путь получает JSON 404
+func TestUnknownRouteJSON404(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, err := server.Client().Get(server.URL + "/no/such/route")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error.Code != "not_found" {
+		t.Errorf("Expected error code not_found, got %+v (%v)", body, err)
+	}
+}
+
+// ТЕСТ: При занятых слотах запрос получает 503 с Retry-After
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	resetMetrics()
+	defer func(previous chan struct{}) { concurrencySlots = previous }(concurrencySlots)
+	concurrencySlots = make(chan struct{}, 1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := concurrencyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/slow", nil))
+		done <- recorder.Code
+	}()
+	<-started
+
+	// Единственный слот занят — запрос отклоняется
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/goals", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on shed request")
+	}
+	if got := testutil.ToFloat64(requestsShed.WithLabelValues("GET")); got != 1 {
+		t.Errorf("Expected http_requests_shed_total = 1, got %v", got)
+	}
+
+	// /metrics не ограничивается
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected /metrics to bypass the limiter, got %d", recorder.Code)
+	}
+
+	// После освобождения слота запросы снова проходят
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected slow request to finish with %d, got %d", http.StatusOK, code)
+	}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/goals", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d after slot release, got %d", http.StatusOK, recorder.Code)
+	}
+}
+
+// ТЕСТ: Long poll занимает свой слот, а не общий: обычные запросы проходят
+func TestConcurrencyLimitLongPoll(t *testing.T) {
+	resetMetrics()
+	defer func(general, longPoll chan struct{}) {
+		concurrencySlots, longPollSlots = general, longPoll
+	}(concurrencySlots, longPollSlots)
+	concurrencySlots, longPollSlots = make(chan struct{}, 1), make(chan struct{}, 1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := concurrencyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("wait") == "20" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/goals?wait=20", nil))
+		done <- recorder.Code
+	}()
+	<-started
+
+	for _, tc := range []struct {
+		target string
+		status int
+	}{
+		{"/goals", http.StatusOK},                        // Общий слот свободен
+		{"/goals?wait=0", http.StatusOK},                 // Без ожидания — обычный запрос
+		{"/goals?wait=5", http.StatusServiceUnavailable}, // Слот long poll занят
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", tc.target, nil))
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.target, tc.status, recorder.Code)
+		}
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected long poll to finish with %d, got %d", http.StatusOK, code)
+	}
+}
+
+// ТЕСТ: FORCE_HTTPS перенаправляет HTTP-запросы от прокси, не трогая служебные пути
+func TestHTTPSRedirectMiddleware(t *testing.T) {
+	defer func(previous bool) { forceHTTPS = previous }(forceHTTPS)
+
+	handler := httpsRedirectMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(method, target, proto string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if proto != "" {
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Флаг выключен — ничего не меняется
+	forceHTTPS = false
+	if code := request("GET", "http://app.example.com/goals", "http").Code; code != http.StatusOK {
+		t.Errorf("Expected no-op with FORCE_HTTPS off, got %d", code)
+	}
+
+	forceHTTPS = true
+	cases := []struct {
+		method   string
+		target   string
+		proto    string
+		status   int
+		location string
+	}{
+		{"GET", "http://app.example.com/goals?completed=true", "http", http.StatusMovedPermanently, "https://app.example.com/goals?completed=true"},
+		{"POST", "http://app.example.com/goals", "HTTP, https", http.StatusPermanentRedirect, "https://app.example.com/goals"},
+		{"GET", "http://app.example.com/goals", "https", http.StatusOK, ""},
+		{"GET", "http://app.example.com/goals", "", http.StatusOK, ""},
+		{"GET", "http://app.example.com/metrics", "http", http.StatusOK, ""},
+		{"GET", "http://app.example.com/health", "http", http.StatusOK, ""},
+	}
+	for _, tc := range cases {
+		recorder := request(tc.method, tc.target, tc.proto)
+		if recorder.Code != tc.status {
+			t.Errorf("%s %s (%q): expected status %d, got %d", tc.method, tc.target, tc.proto, tc.status, recorder.Code)
+		}
+		if got := recorder.Header().Get("Location"); got != tc.location {
+			t.Errorf("%s %s (%q): expected Location %q, got %q", tc.method, tc.target, tc.proto, tc.location, got)
+		}
+	}
+}
+
+// ТЕСТ: Заголовки безопасности есть в ответах, CSP настраивается и отключается пустым значением
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	defer func(previous string) { contentSecurityPolicy = previous }(contentSecurityPolicy)
+	handler := securityHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+
+	contentSecurityPolicy = defaultContentSecurityPolicy
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	expected := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "no-referrer",
+		"Content-Security-Policy": defaultContentSecurityPolicy,
+	}
+	for name, value := range expected {
+		if got := recorder.Header().Get(name); got != value {
+			t.Errorf("Expected %s: %q even on error responses, got %q", name, value, got)
+		}
+	}
+
+	// Настройка через окружение: пустое значение отключает CSP
+	t.Setenv("CONTENT_SECURITY_POLICY", "")
+	initMiddleware()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if _, present := recorder.Header()["Content-Security-Policy"]; present {
+		t.Errorf("Expected no Content-Security-Policy with empty CONTENT_SECURITY_POLICY")
+	}
+	if recorder.Header().Get("X-Frame-Options") != "DENY" {
+		t.Errorf("Expected other headers to stay when CSP is disabled")
+	}
+}
@@ -0,0 +1,140 @@
+// ФАЙЛ: migrations.go
+// НАЗНАЧЕНИЕ: Версионированные изменения схемы БД
+// ОСОБЕННОСТИ:
+//   - Применённые версии хранятся в таблице schema_migrations
+//   - Имена таблиц в SQL задаются как {goals}, {goals_archive} и {goals_staging} (см. GOALS_TABLE)
+//   - Каждая миграция выполняется в своей транзакции вместе с записью версии
+//   - Advisory-блокировка не даёт двум экземплярам применять миграции одновременно
+//   - Новые миграции только добавляются в конец списка, старые не меняются
+
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ключ advisory-блокировки для миграций (произвольная константа приложения)
+const migrationsLockKey = 7314002
+
+// СТРУКТУРА МИГРАЦИИ
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// СПИСОК МИГРАЦИЙ (по возрастанию версии)
+var schemaMigrations = []migration{
+	{
+		Version: 1,
+		Name:    "goals table",
+		SQL: `CREATE TABLE IF NOT EXISTS {goals} (
+			id SERIAL PRIMARY KEY,
+			goal TEXT NOT NULL,
+			timeline TEXT NOT NULL,
+			salary_target INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+	},
+	{
+		Version: 2,
+		Name:    "goal completion and archive",
+		SQL: `ALTER TABLE {goals} ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP WITH TIME ZONE;
+			CREATE TABLE IF NOT EXISTS {goals_archive} (
+				id INTEGER PRIMARY KEY,
+				goal TEXT NOT NULL,
+				timeline TEXT NOT NULL,
+				salary_target INTEGER NOT NULL DEFAULT 0,
+				created_at TIMESTAMP WITH TIME ZONE,
+				completed_at TIMESTAMP WITH TIME ZONE,
+				archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+	},
+	{
+		Version: 3,
+		Name:    "numeric salary_target",
+		// Целые значения переносятся без изменений: 1500 → 1500
+		SQL: `ALTER TABLE {goals} ALTER COLUMN salary_target TYPE NUMERIC USING salary_target::numeric;
+			ALTER TABLE {goals_archive} ALTER COLUMN salary_target TYPE NUMERIC USING salary_target::numeric`,
+	},
+	{
+		Version: 4,
+		Name:    "goal currency",
+		SQL: `ALTER TABLE {goals} ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'RUB';
+			ALTER TABLE {goals_archive} ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'RUB'`,
+	},
+	{
+		Version: 5,
+		Name:    "goal tags",
+		SQL: `ALTER TABLE {goals} ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+			ALTER TABLE {goals_archive} ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+	},
+	{
+		Version: 6,
+		Name:    "goals staging for parallel copy",
+		// Нежурналируемая: после сбоя PostgreSQL очищает её, а для промежуточных строк это и нужно
+		SQL: `CREATE UNLOGGED TABLE IF NOT EXISTS {goals_staging} (
+				batch_id INTEGER NOT NULL,
+				id INTEGER NOT NULL,
+				goal TEXT NOT NULL,
+				timeline TEXT NOT NULL,
+				salary_target NUMERIC NOT NULL DEFAULT 0,
+				currency TEXT NOT NULL DEFAULT 'RUB',
+				tags TEXT[] NOT NULL DEFAULT '{}',
+				created_at TIMESTAMP WITH TIME ZONE
+			);
+			CREATE INDEX ON {goals_staging} (batch_id)`,
+	},
+}
+
+// ФУНКЦИЯ: runMigrations
+// НАЗНАЧЕНИЕ: Применяет ещё не применённые миграции
+func runMigrations(ctx context.Context) error {
+	conn, err := dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// Сессионная блокировка держится, пока не применим все миграции
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationsLockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockKey)
+
+	versions := schemaMigrationsTable()
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+versions+` (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := conn.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM "+versions).Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range schemaMigrations {
+		if m.Version <= current {
+			continue
+		}
+
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, withTables(m.SQL)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO "+versions+" (version) VALUES ($1)", m.Version)
+			return err
+		})
+		if err != nil {
+			logger.LogError(err, "Ошибка миграции "+m.Name)
+			return err
+		}
+		logger.InfoLogger.Printf("🧱 Применена миграция %d: %s", m.Version, m.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// ФАЙЛ: naming.go
+// НАЗНАЧЕНИЕ: Имена полей цели в JSON: snake_case или camelCase
+// ОСОБЕННОСТИ:
+//   - JSON_NAMING=camel отдаёт цели с ключами salaryTargetRubPerHour, createdAt...
+//   - По умолчанию (JSON_NAMING=snake) формат ответов не меняется
+//   - На входе принимаются оба варианта независимо от настройки
+//   - Неизвестные поля по-прежнему отклоняются
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Варианты JSON_NAMING
+const (
+	jsonNamingSnake = "snake"
+	jsonNamingCamel = "camel"
+)
+
+// Текущее именование полей в ответах (JSON_NAMING)
+var jsonNaming = jsonNamingSnake
+
+// Представление цели без собственных MarshalJSON/UnmarshalJSON (теги snake_case из Goal)
+type goalJSON Goal
+
+// MarshalJSON кодирует цель с именами полей по JSON_NAMING
+// created_at всегда отдаётся в UTC (с суффиксом Z): pgx читает TIMESTAMPTZ в локальной
+// зоне процесса, и без приведения клиенты видели бы разные смещения для одного момента
+func (g Goal) MarshalJSON() ([]byte, error) {
+	g.CreatedAt = g.CreatedAt.UTC()
+	data, err := json.Marshal(goalJSON(g))
+	if err != nil || jsonNaming != jsonNamingCamel {
+		return data, err
+	}
+	return renameJSONKeys(data, snakeToCamel)
+}
+
+// UnmarshalJSON принимает ключи в snake_case и camelCase
+func (g *Goal) UnmarshalJSON(data []byte) error {
+	normalized, err := renameJSONKeys(data, camelToSnake)
+	if err != nil {
+		return err
+	}
+
+	// Внешний декодер не передаёт DisallowUnknownFields вложенному, поэтому проверяем здесь
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*goalJSON)(g))
+}
+
+// ФУНКЦИЯ: renameJSONKeys
+// НАЗНАЧЕНИЕ: Переименовывает ключи JSON-объекта верхнего уровня, сохраняя их порядок
+// Значения не разбираются и копируются как есть
+func renameJSONKeys(data []byte, rename func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		// Не объект (например, null): переименовывать нечего
+		return data, nil
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, errors.New("ожидался ключ JSON-объекта")
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		encodedKey, _ := json.Marshal(rename(key))
+		out.Write(encodedKey)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// salary_target_rub_per_hour → salaryTargetRubPerHour
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// salaryTargetRubPerHour → salary_target_rub_per_hour (ключи без заглавных букв не меняются)
+func camelToSnake(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: JSON_NAMING=camel меняет ключи ответа, вход принимает оба варианта
+func TestGoalJSONNaming(t *testing.T) {
+	defer func(previous string) { jsonNaming = previous }(jsonNaming)
+	goal := Goal{ID: 1, Goal: "Learn Go", Timeline: "2026", SalaryTarget: "10.50", Currency: "RUB",
+		Tags: []string{"career"}, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	jsonNaming = jsonNamingSnake
+	snake, _ := json.Marshal(goal)
+	if !strings.Contains(string(snake), `"salary_target_rub_per_hour":10.50`) || !strings.Contains(string(snake), `"created_at"`) {
+		t.Errorf("Expected snake_case keys by default, got %s", snake)
+	}
+
+	jsonNaming = jsonNamingCamel
+	camel, _ := json.Marshal(goal)
+	expected := `{"id":1,"goal":"Learn Go","timeline":"2026","salaryTargetRubPerHour":10.50,"currency":"RUB","tags":["career"],"createdAt":"2026-01-02T03:04:05Z"}`
+	if string(camel) != expected {
+		t.Errorf("Expected %s, got %s", expected, camel)
+	}
+
+	// Оба варианта ключей читаются одинаково при любой настройке
+	for _, body := range [][]byte{snake, camel} {
+		var decoded Goal
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("Failed to decode %s: %v", body, err)
+		}
+		if decoded.SalaryTarget != goal.SalaryTarget || !decoded.CreatedAt.Equal(goal.CreatedAt) {
+			t.Errorf("Decoded %s into %+v", body, decoded)
+		}
+	}
+}
+
+// ТЕСТ: Неизвестные поля отклоняются и в camelCase
+func TestGoalJSONUnknownField(t *testing.T) {
+	req := httptest.NewRequest("POST", "/goals", strings.NewReader(`{"goal":"x","timeline":"y","salaryTarget":1}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	var goal Goal
+	err := decodeJSONBody(httptest.NewRecorder(), req, &goal)
+	decodeErr, ok := err.(*bodyDecodeError)
+	if !ok || decodeErr.Status != http.StatusBadRequest || !strings.Contains(decodeErr.Message, "salary_target") {
+		t.Errorf("Expected 400 for unknown field, got %v", err)
+	}
+}
+
+// ТЕСТ: created_at в ответе всегда в UTC с суффиксом Z, независимо от зоны значения
+func TestGoalCreatedAtMarshalsAsUTC(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	goal := Goal{ID: 1, Goal: "Learn Go", Timeline: "2026", CreatedAt: time.Date(2026, 1, 2, 6, 4, 5, 0, moscow)}
+
+	data, err := json.Marshal(goal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"created_at":"2026-01-02T03:04:05Z"`) {
+		t.Errorf("Expected created_at in UTC with Z suffix, got %s", data)
+	}
+	if goal.CreatedAt.Location() != moscow {
+		t.Errorf("Expected MarshalJSON not to modify the original goal")
+	}
+}
@@ -0,0 +1,141 @@
+// ФАЙЛ: nonce.go
+// НАЗНАЧЕНИЕ: Защита изменяющих запросов от повторной отправки (replay)
+// ОСОБЕННОСТИ:
+//   - Включается через REQUIRE_NONCE, по умолчанию выключена
+//   - X-Nonce должен быть уникален в пределах NONCE_TTL, повтор — 409
+//   - X-Timestamp (unix-секунды) не может отличаться от времени сервера больше чем на NONCE_MAX_SKEW
+//   - Использованные nonce хранятся в памяти; для нескольких инстансов потребуется общее хранилище
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ NONCE
+var (
+	// Требовать X-Nonce и X-Timestamp для POST/PUT/PATCH/DELETE (REQUIRE_NONCE)
+	requireNonce = false
+	// Сколько помнить использованный nonce (NONCE_TTL)
+	nonceTTL = 10 * time.Minute
+	// Допустимое расхождение X-Timestamp с часами сервера (NONCE_MAX_SKEW)
+	nonceMaxSkew = 5 * time.Minute
+	// Использованные nonce: значение → когда получен
+	seenNonces = make(map[string]time.Time)
+	nonceMutex sync.Mutex
+)
+
+// Максимальная длина X-Nonce
+const maxNonceLength = 128
+
+// Причина отказа для метрик и security.log
+const rejectReasonNonceReplay = "nonce_replay"
+
+// ИНИЦИАЛИЗАЦИЯ ЗАЩИТЫ ОТ ПОВТОРОВ
+func initNonce() {
+	requireNonce = getEnvBool("REQUIRE_NONCE", false)
+	if !requireNonce {
+		return
+	}
+
+	nonceTTL = getEnvDuration("NONCE_TTL", 10*time.Minute)
+	nonceMaxSkew = getEnvDuration("NONCE_MAX_SKEW", 5*time.Minute)
+	// Запрос с допустимой меткой времени не должен пережить свой nonce,
+	// иначе его можно повторить после очистки
+	if nonceTTL < 2*nonceMaxSkew {
+		logger.InfoLogger.Printf("⚠️ NONCE_TTL (%s) меньше двух NONCE_MAX_SKEW, используем %s", nonceTTL, 2*nonceMaxSkew)
+		nonceTTL = 2 * nonceMaxSkew
+	}
+	logger.InfoLogger.Printf("🔁 Защита от повторов включена: nonce хранится %s, расхождение времени до %s", nonceTTL, nonceMaxSkew)
+
+	startBackground("nonce-cleanup", cleanNonces)
+}
+
+// Изменяющие методы, для которых нужен nonce
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// MIDDLEWARE: Проверка X-Nonce и X-Timestamp у изменяющих запросов
+func nonceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requireNonce || !isMutatingMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// ШАГ 1: Проверяем наличие nonce
+		nonce := r.Header.Get("X-Nonce")
+		if nonce == "" || len(nonce) > maxNonceLength {
+			writeJSONError(w, r, http.StatusBadRequest, "nonce_required", "Нужен заголовок X-Nonce (до 128 символов)")
+			return
+		}
+
+		// ШАГ 2: Проверяем метку времени
+		seconds, err := strconv.ParseInt(r.Header.Get("X-Timestamp"), 10, 64)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, "timestamp_required", "Нужен заголовок X-Timestamp (unix-время в секундах)")
+			return
+		}
+		now := securityClock.Now()
+		if skew := now.Sub(time.Unix(seconds, 0)).Abs(); skew > nonceMaxSkew {
+			writeJSONError(w, r, http.StatusBadRequest, "timestamp_expired", "X-Timestamp слишком отличается от времени сервера")
+			return
+		}
+
+		// ШАГ 3: Запоминаем nonce; повтор отклоняем
+		if !rememberNonce(nonce, now) {
+			logSecurityEventWithReason("NONCE_REPLAY", getIP(r), r.URL.Path, rejectReasonNonceReplay)
+			rateLimitRejections.WithLabelValues(rejectReasonNonceReplay).Inc()
+			writeJSONError(w, r, http.StatusConflict, "nonce_reused", "Этот X-Nonce уже использован")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Сохраняет nonce; false, если он уже встречался в пределах nonceTTL
+func rememberNonce(nonce string, now time.Time) bool {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	if seenAt, exists := seenNonces[nonce]; exists && now.Sub(seenAt) < nonceTTL {
+		return false
+	}
+	seenNonces[nonce] = now
+	return true
+}
+
+// Раз в минуту удаляем nonce старше nonceTTL
+func cleanNonces(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-securityClock.After(time.Minute):
+			cleanupNonces()
+		}
+	}
+}
+
+// Один проход очистки использованных nonce
+func cleanupNonces() {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	now := securityClock.Now()
+	for nonce, seenAt := range seenNonces {
+		if now.Sub(seenAt) >= nonceTTL {
+			delete(seenNonces, nonce)
+		}
+	}
+}
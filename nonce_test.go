@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Повторный nonce даёт 409, устаревшая метка времени — 400
+func TestNonceMiddleware(t *testing.T) {
+	clock := useFakeSecurityClock(t)
+	securityLogger = log.New(io.Discard, "", 0)
+	resetMetrics()
+
+	defer func(require bool, ttl, skew time.Duration) {
+		requireNonce, nonceTTL, nonceMaxSkew = require, ttl, skew
+	}(requireNonce, nonceTTL, nonceMaxSkew)
+	requireNonce, nonceTTL, nonceMaxSkew = true, 10*time.Minute, 5*time.Minute
+	nonceMutex.Lock()
+	seenNonces = make(map[string]time.Time)
+	nonceMutex.Unlock()
+
+	handler := nonceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(method, nonce string, timestamp time.Time) int {
+		req := httptest.NewRequest(method, "/goals", nil)
+		if nonce != "" {
+			req.Header.Set("X-Nonce", nonce)
+		}
+		if !timestamp.IsZero() {
+			req.Header.Set("X-Timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	cases := []struct {
+		name      string
+		method    string
+		nonce     string
+		timestamp time.Time
+		expected  int
+	}{
+		{"чтение без nonce", "GET", "", time.Time{}, http.StatusOK},
+		{"запись без nonce", "POST", "", clock.Now(), http.StatusBadRequest},
+		{"без метки времени", "POST", "n-1", time.Time{}, http.StatusBadRequest},
+		{"устаревшая метка", "POST", "n-1", clock.Now().Add(-6 * time.Minute), http.StatusBadRequest},
+		{"метка из будущего", "POST", "n-1", clock.Now().Add(6 * time.Minute), http.StatusBadRequest},
+		{"первый запрос", "POST", "n-1", clock.Now(), http.StatusOK},
+		{"повтор", "DELETE", "n-1", clock.Now(), http.StatusConflict},
+		{"новый nonce", "PUT", "n-2", clock.Now(), http.StatusOK},
+	}
+	for _, tc := range cases {
+		if code := request(tc.method, tc.nonce, tc.timestamp); code != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, code)
+		}
+	}
+
+	// После истечения TTL nonce забывается
+	clock.Advance(nonceTTL)
+	cleanupNonces()
+	if code := request("POST", "n-1", clock.Now()); code != http.StatusOK {
+		t.Errorf("Expected nonce to be accepted after TTL, got %d", code)
+	}
+}
@@ -0,0 +1,117 @@
+// ФАЙЛ: quota.go
+// НАЗНАЧЕНИЕ: Ограничение количества целей (MAX_GOALS_PER_USER)
+// ОСОБЕННОСТИ:
+//   - По умолчанию ограничения нет (0)
+//   - Пользователей (user_id) в схеме пока нет, поэтому лимит действует на всю таблицу
+//   - Подсчёт и вставка выполняются в одной транзакции под advisory-блокировкой,
+//     чтобы параллельные запросы не превысили лимит
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ключ advisory-блокировки для проверки лимита (произвольная константа приложения)
+const goalQuotaLockKey = 7314001
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ ЛИМИТА
+var (
+	// Максимум целей; 0 — без ограничений
+	maxGoalsPerUser = 0
+	// Ошибка: лимит целей исчерпан
+	errGoalLimitReached = errors.New("достигнут лимит количества целей")
+)
+
+// ИНИЦИАЛИЗАЦИЯ ЛИМИТА
+func initGoalQuota() {
+	maxGoalsPerUser = getEnvInt("MAX_GOALS_PER_USER", 0)
+	if maxGoalsPerUser < 0 {
+		maxGoalsPerUser = 0
+	}
+	if maxGoalsPerUser > 0 {
+		logger.InfoLogger.Printf("🎯 Лимит количества целей: %d", maxGoalsPerUser)
+	}
+}
+
+// ФУНКЦИЯ: insertGoal
+// НАЗНАЧЕНИЕ: Вставляет цель и заполняет её ID и время создания
+// Незаданный CreatedAt заменяется на NOW(); заданный (импорт администратором) сохраняется
+// При включённом лимите возвращает errGoalLimitReached, если целей уже достаточно
+func insertGoal(ctx context.Context, goal *Goal) error {
+	if maxGoalsPerUser <= 0 {
+		return insertGoalRow(ctx, dbPool, goal)
+	}
+
+	return pgx.BeginFunc(ctx, dbPool, func(tx pgx.Tx) error {
+		if err := checkGoalQuota(ctx, tx, 1); err != nil {
+			return err
+		}
+		return insertGoalRow(ctx, tx, goal)
+	})
+}
+
+// ФУНКЦИЯ: insertGoals
+// НАЗНАЧЕНИЕ: Вставляет несколько целей: либо все, либо ни одной
+// Большие пачки копируются через COPY (см. bulk.go), маленькие вставляются построчно
+// При включённом лимите возвращает errGoalLimitReached, если все цели не помещаются
+func insertGoals(ctx context.Context, goals []Goal) error {
+	if len(goals) >= bulkCopyThreshold {
+		return copyGoals(ctx, goals)
+	}
+
+	return pgx.BeginFunc(ctx, dbPool, func(tx pgx.Tx) error {
+		if maxGoalsPerUser > 0 {
+			if err := checkGoalQuota(ctx, tx, len(goals)); err != nil {
+				return err
+			}
+		}
+		for i := range goals {
+			if err := insertGoalRow(ctx, tx, &goals[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ФУНКЦИЯ: checkGoalQuota
+// НАЗНАЧЕНИЕ: Проверяет, что в лимит помещается ещё adding целей
+// Advisory-блокировка держится до конца транзакции и сериализует только создание целей
+func checkGoalQuota(ctx context.Context, tx pgx.Tx, adding int) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", goalQuotaLockKey); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM "+goalsTable).Scan(&count); err != nil {
+		return err
+	}
+	if count+adding > maxGoalsPerUser {
+		return fmt.Errorf("%w (%d)", errGoalLimitReached, maxGoalsPerUser)
+	}
+	return nil
+}
+
+// Источник запроса для вставки: пул или транзакция
+type goalRowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Вставка одной строки; заполняет ID и CreatedAt цели
+func insertGoalRow(ctx context.Context, q goalRowQuerier, goal *Goal) error {
+	query := withTables(`INSERT INTO {goals} (goal, timeline, salary_target, currency, tags, created_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW())) RETURNING id, created_at`)
+
+	var createdAt *time.Time
+	if !goal.CreatedAt.IsZero() {
+		createdAt = &goal.CreatedAt
+	}
+	return q.QueryRow(ctx, query, goal.Goal, goal.Timeline, goal.SalaryTarget, goal.Currency, goal.Tags, createdAt).
+		Scan(&goal.ID, &goal.CreatedAt)
+}
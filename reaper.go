@@ -0,0 +1,124 @@
+// ФАЙЛ: reaper.go
+// НАЗНАЧЕНИЕ: Фоновое закрытие лишних простаивающих соединений пула
+// ОСОБЕННОСТИ:
+//   - Включается DB_IDLE_REAPER_INTERVAL (по умолчанию выключен)
+//   - После всплеска трафика пул держит много простаивающих соединений до MaxConnIdleTime,
+//     а в общей базе они нужны соседним приложениям
+//   - Пул сжимается к MinConns только при устойчиво низком трафике:
+//     DB_IDLE_REAPER_QUIET_PERIODS интервалов подряд не больше DB_IDLE_REAPER_QUIET_ACQUIRES выдач соединений
+//   - За интервал закрывается не больше DB_IDLE_REAPER_STEP соединений
+//   - Подробности работы пишутся в отладочный лог (LOG_DEBUG)
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ СБОРЩИКА СОЕДИНЕНИЙ
+var (
+	// Как часто проверять пул; 0 — сборщик выключен
+	reaperInterval time.Duration
+	// Сколько интервалов подряд трафик должен быть низким
+	reaperQuietPeriods = 3
+	// Сколько выдач соединений за интервал ещё считается низким трафиком
+	reaperQuietAcquires int64 = 10
+	// Сколько соединений закрывать за один интервал
+	reaperStep = 2
+)
+
+// ИНИЦИАЛИЗАЦИЯ СБОРЩИКА
+func initIdleReaper() {
+	reaperInterval = getEnvDuration("DB_IDLE_REAPER_INTERVAL", 0)
+	if reaperInterval <= 0 || dbPool == nil {
+		return
+	}
+
+	reaperQuietPeriods = max(getEnvInt("DB_IDLE_REAPER_QUIET_PERIODS", 3), 1)
+	reaperQuietAcquires = int64(max(getEnvInt("DB_IDLE_REAPER_QUIET_ACQUIRES", 10), 0))
+	reaperStep = max(getEnvInt("DB_IDLE_REAPER_STEP", 2), 1)
+
+	logger.InfoLogger.Printf("🧹 Сборщик простаивающих соединений: каждые %s, после %d тихих интервалов (≤%d выдач), по %d за раз",
+		reaperInterval, reaperQuietPeriods, reaperQuietAcquires, reaperStep)
+	startBackground("idle-reaper", runIdleReaper)
+}
+
+// СОСТОЯНИЕ СБОРЩИКА МЕЖДУ ИНТЕРВАЛАМИ
+type idleReaper struct {
+	lastAcquires int64 // Счётчик выдач соединений на прошлой проверке
+	quiet        int   // Тихих интервалов подряд
+}
+
+// ФУНКЦИЯ: plan
+// НАЗНАЧЕНИЕ: Сколько соединений закрыть сейчас
+// acquires — счётчик выдач соединений пула с момента создания (pgxpool.Stat.AcquireCount)
+func (r *idleReaper) plan(acquires int64, idle, total, minConns int32) int {
+	delta := acquires - r.lastAcquires
+	r.lastAcquires = acquires
+
+	if delta > reaperQuietAcquires {
+		r.quiet = 0
+		return 0
+	}
+	r.quiet++
+	if r.quiet < reaperQuietPeriods {
+		return 0
+	}
+
+	// Закрываем только простаивающие и не опускаемся ниже MinConns
+	return max(0, min(int(idle), int(total-minConns), reaperStep))
+}
+
+// Фоновый цикл сборщика
+func runIdleReaper(ctx context.Context) {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+
+	reaper := &idleReaper{lastAcquires: dbPool.Stat().AcquireCount()}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stat := dbPool.Stat()
+		minConns := dbPool.Config().MinConns
+		count := reaper.plan(stat.AcquireCount(), stat.IdleConns(), stat.TotalConns(), minConns)
+		if count == 0 {
+			logger.DebugLogger.Printf("🧹 Пул: всего %d, простаивают %d, тихих интервалов %d — закрывать нечего",
+				stat.TotalConns(), stat.IdleConns(), reaper.quiet)
+			continue
+		}
+
+		closed := closeIdleConns(ctx, dbPool, count)
+		// Захват простаивающих соединений не должен выглядеть как трафик на следующей проверке
+		reaper.lastAcquires = dbPool.Stat().AcquireCount()
+		logger.DebugLogger.Printf("🧹 Закрыто простаивающих соединений: %d (в пуле осталось %d, MinConns %d)",
+			closed, dbPool.Stat().TotalConns(), minConns)
+	}
+}
+
+// ФУНКЦИЯ: closeIdleConns
+// НАЗНАЧЕНИЕ: Закрывает до count простаивающих соединений, остальные возвращает в пул
+func closeIdleConns(ctx context.Context, pool *pgxpool.Pool, count int) int {
+	closed := 0
+	for _, conn := range pool.AcquireAllIdle(ctx) {
+		if closed >= count {
+			conn.Release()
+			continue
+		}
+
+		// Hijack забирает соединение из пула, дальше оно закрывается как обычное
+		closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if err := conn.Hijack().Close(closeCtx); err != nil {
+			logger.DebugLogger.Printf("🧹 Ошибка закрытия соединения: %v", err)
+		}
+		cancel()
+		closed++
+	}
+	return closed
+}
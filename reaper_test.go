@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// ТЕСТ: Пул сжимается только после нескольких тихих интервалов и не ниже MinConns
+func TestIdleReaperPlan(t *testing.T) {
+	defer func(periods int, acquires int64, step int) {
+		reaperQuietPeriods, reaperQuietAcquires, reaperStep = periods, acquires, step
+	}(reaperQuietPeriods, reaperQuietAcquires, reaperStep)
+	reaperQuietPeriods, reaperQuietAcquires, reaperStep = 2, 5, 3
+
+	reaper := &idleReaper{}
+	steps := []struct {
+		name     string
+		acquires int64
+		idle     int32
+		total    int32
+		expected int
+	}{
+		{"всплеск трафика", 500, 10, 10, 0},
+		{"первый тихий интервал", 503, 10, 10, 0},
+		{"второй тихий интервал", 505, 10, 10, 3},
+		{"тишина продолжается", 505, 7, 7, 3},
+		{"не ниже MinConns", 506, 4, 4, 2},
+		{"только простаивающие", 507, 1, 4, 1},
+		{"трафик снова вырос", 520, 2, 2, 0},
+	}
+	for _, step := range steps {
+		if got := reaper.plan(step.acquires, step.idle, step.total, 2); got != step.expected {
+			t.Errorf("%s: expected %d connections to close, got %d", step.name, step.expected, got)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// ФАЙЛ: reload.go
+// НАЗНАЧЕНИЕ: Перечитывание правил безопасности без перезапуска (SIGHUP)
+// ОСОБЕННОСТИ:
+//   - Лимит запросов, время блокировки, порог алертов, подозрительные пути и действия для них
+//     читаются из переменных окружения, поверх них — из SECURITY_CONFIG_FILE
+//   - Окружение процесса после запуска не меняется, поэтому во время инцидента
+//     правят файл и присылают SIGHUP: kill -HUP <pid>
+//   - Значения применяются под мьютексами своих подсистем, изменения пишутся в лог
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Файл с правилами безопасности в формате KEY=VALUE (SECURITY_CONFIG_FILE)
+var securityConfigFile string
+
+// Значения по умолчанию
+const (
+	defaultRequestLimit   = 100
+	defaultBlockDuration  = 1 * time.Hour
+	defaultErrorThreshold = 5
+)
+
+// ПРАВИЛА БЕЗОПАСНОСТИ, КОТОРЫЕ МОЖНО МЕНЯТЬ НА ХОДУ
+type securityRules struct {
+	RequestLimit    int                  // RATE_LIMIT: запросов в минуту с одного IP
+	BlockDuration   time.Duration        // BLOCK_DURATION: время блокировки IP
+	ErrorThreshold  int                  // ERROR_THRESHOLD: ошибок от IP до алерта
+	SuspiciousPaths []suspiciousPathRule // SUSPICIOUS_PATHS: подстроки путей и действия при обращении
+	ShortBlock      time.Duration        // SUSPICIOUS_SHORT_BLOCK: срок короткой блокировки
+}
+
+// ФУНКЦИЯ: readSecurityRules
+// НАЗНАЧЕНИЕ: Читает правила из окружения; некорректные значения заменяются значениями по умолчанию
+func readSecurityRules() securityRules {
+	rules := securityRules{
+		RequestLimit:   getEnvInt("RATE_LIMIT", defaultRequestLimit),
+		BlockDuration:  getEnvDuration("BLOCK_DURATION", defaultBlockDuration),
+		ErrorThreshold: getEnvInt("ERROR_THRESHOLD", defaultErrorThreshold),
+		ShortBlock:     getEnvDuration("SUSPICIOUS_SHORT_BLOCK", defaultSuspiciousShortBlock),
+	}
+
+	if rules.RequestLimit <= 0 {
+		logger.InfoLogger.Printf("⚠️ RATE_LIMIT должен быть больше нуля, используем %d", defaultRequestLimit)
+		rules.RequestLimit = defaultRequestLimit
+	}
+	if rules.BlockDuration <= 0 {
+		logger.InfoLogger.Printf("⚠️ BLOCK_DURATION должен быть больше нуля, используем %s", defaultBlockDuration)
+		rules.BlockDuration = defaultBlockDuration
+	}
+	if rules.ErrorThreshold <= 0 {
+		logger.InfoLogger.Printf("⚠️ ERROR_THRESHOLD должен быть больше нуля, используем %d", defaultErrorThreshold)
+		rules.ErrorThreshold = defaultErrorThreshold
+	}
+
+	if rules.ShortBlock <= 0 {
+		logger.InfoLogger.Printf("⚠️ SUSPICIOUS_SHORT_BLOCK должен быть больше нуля, используем %s", defaultSuspiciousShortBlock)
+		rules.ShortBlock = defaultSuspiciousShortBlock
+	}
+
+	// Действие для путей без явного "=действие" (SUSPICIOUS_PATH_ACTION)
+	action := strings.ToLower(strings.TrimSpace(os.Getenv("SUSPICIOUS_PATH_ACTION")))
+	if action == "" {
+		action = suspiciousActionShortBlock
+	}
+	if !isSuspiciousAction(action) {
+		logger.InfoLogger.Printf("⚠️ Некорректное SUSPICIOUS_PATH_ACTION=%q, используем %s", action, suspiciousActionShortBlock)
+		action = suspiciousActionShortBlock
+	}
+	rules.SuspiciousPaths = parseSuspiciousPathRules(os.Getenv("SUSPICIOUS_PATHS"), action)
+	return rules
+}
+
+// ФУНКЦИЯ: applySecurityRules
+// НАЗНАЧЕНИЕ: Применяет правила; возвращает прежние значения
+// Лимиты и пути меняются под countMutex, пороги алертов — под alertMutex,
+// поэтому запрос видит либо старый, либо новый набор значений подсистемы целиком
+func applySecurityRules(rules securityRules) securityRules {
+	var before securityRules
+
+	countMutex.Lock()
+	before.RequestLimit, before.BlockDuration = requestLimit, blockDuration
+	before.SuspiciousPaths, before.ShortBlock = suspiciousPaths, suspiciousShortBlock
+	requestLimit, blockDuration = rules.RequestLimit, rules.BlockDuration
+	suspiciousPaths, suspiciousShortBlock = rules.SuspiciousPaths, rules.ShortBlock
+	countMutex.Unlock()
+
+	alertMutex.Lock()
+	before.ErrorThreshold = errorThreshold
+	errorThreshold = rules.ErrorThreshold
+	errorResetThreshold = resetThresholdFor(errorThreshold)
+	alertMutex.Unlock()
+
+	return before
+}
+
+// ФУНКЦИЯ: reloadSecurityConfig
+// НАЗНАЧЕНИЕ: Перечитывает SECURITY_CONFIG_FILE и окружение и применяет правила
+// Если файл не прочитан, правила не меняются: полупримененная конфигурация хуже старой
+func reloadSecurityConfig() error {
+	if securityConfigFile != "" {
+		if err := loadEnvFile(securityConfigFile); err != nil {
+			logger.LogError(err, "⚠️ Не удалось перечитать SECURITY_CONFIG_FILE, правила не изменены")
+			return err
+		}
+	}
+
+	rules := readSecurityRules()
+	before := applySecurityRules(rules)
+
+	changes := diffSecurityRules(before, rules)
+	if len(changes) == 0 {
+		logger.InfoLogger.Println("🔄 Правила безопасности перечитаны, изменений нет")
+		return nil
+	}
+	for _, change := range changes {
+		logger.InfoLogger.Printf("🔄 %s", change)
+	}
+	securityLogger.Printf("CONFIG_RELOADED | %s", strings.Join(changes, "; "))
+	return nil
+}
+
+// Изменившиеся значения в виде "ПАРАМЕТР: было → стало"
+func diffSecurityRules(before, after securityRules) []string {
+	var changes []string
+	if before.RequestLimit != after.RequestLimit {
+		changes = append(changes, fmt.Sprintf("RATE_LIMIT: %d → %d", before.RequestLimit, after.RequestLimit))
+	}
+	if before.BlockDuration != after.BlockDuration {
+		changes = append(changes, fmt.Sprintf("BLOCK_DURATION: %s → %s", before.BlockDuration, after.BlockDuration))
+	}
+	if before.ErrorThreshold != after.ErrorThreshold {
+		changes = append(changes, fmt.Sprintf("ERROR_THRESHOLD: %d → %d", before.ErrorThreshold, after.ErrorThreshold))
+	}
+	if !slices.Equal(before.SuspiciousPaths, after.SuspiciousPaths) {
+		changes = append(changes, fmt.Sprintf("SUSPICIOUS_PATHS: %v → %v", before.SuspiciousPaths, after.SuspiciousPaths))
+	}
+	if before.ShortBlock != after.ShortBlock {
+		changes = append(changes, fmt.Sprintf("SUSPICIOUS_SHORT_BLOCK: %s → %s", before.ShortBlock, after.ShortBlock))
+	}
+	return changes
+}
+
+// ФУНКЦИЯ: loadEnvFile
+// НАЗНАЧЕНИЕ: Переносит строки KEY=VALUE из файла в окружение процесса
+// Пустые строки и строки с # пропускаются, кавычки вокруг значения снимаются
+func loadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return fmt.Errorf("%s:%d: ожидается KEY=VALUE", path, line)
+		}
+		values[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// В окружение попадает только полностью разобранный файл
+	for key, value := range values {
+		os.Setenv(key, value)
+	}
+	return nil
+}
+
+// ФУНКЦИЯ: watchReloadSignal
+// НАЗНАЧЕНИЕ: Фоновая задача: перечитывает правила безопасности по SIGHUP
+func watchReloadSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.InfoLogger.Println("📥 Получен SIGHUP, перечитываем правила безопасности")
+			reloadSecurityConfig()
+		}
+	}
+}
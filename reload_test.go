@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ТЕСТ: Правила из SECURITY_CONFIG_FILE применяются при перечитывании
+func TestReloadSecurityConfig(t *testing.T) {
+	securityLogger = log.New(io.Discard, "", 0)
+	defer func(limit int, block time.Duration, threshold, reset int, paths []suspiciousPathRule, short time.Duration) {
+		requestLimit, blockDuration, suspiciousPaths, suspiciousShortBlock = limit, block, paths, short
+		errorThreshold, errorResetThreshold = threshold, reset
+	}(requestLimit, blockDuration, errorThreshold, errorResetThreshold, suspiciousPaths, suspiciousShortBlock)
+	defer func(previous string) { securityConfigFile = previous }(securityConfigFile)
+
+	// t.Setenv вернёт окружение после теста, даже если его поменяет loadEnvFile
+	for _, key := range []string{"RATE_LIMIT", "BLOCK_DURATION", "ERROR_THRESHOLD", "SUSPICIOUS_PATHS", "SUSPICIOUS_PATH_ACTION", "SUSPICIOUS_SHORT_BLOCK", "ALERT_RESET_THRESHOLD"} {
+		t.Setenv(key, "")
+	}
+
+	securityConfigFile = filepath.Join(t.TempDir(), "security.env")
+	config := "# во время инцидента\nRATE_LIMIT=20\nBLOCK_DURATION=\"15m\"\n\nERROR_THRESHOLD=8\nSUSPICIOUS_PATHS=/phpmyadmin, /.git\n"
+	if err := os.WriteFile(securityConfigFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadSecurityConfig(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+
+	countMutex.Lock()
+	limit, block := requestLimit, blockDuration
+	countMutex.Unlock()
+	if limit != 20 || block != 15*time.Minute {
+		t.Errorf("Expected limit 20 and block 15m, got %d and %s", limit, block)
+	}
+	alertMutex.Lock()
+	threshold, reset := errorThreshold, errorResetThreshold
+	alertMutex.Unlock()
+	if threshold != 8 || reset != 4 {
+		t.Errorf("Expected error threshold 8 (reset 4), got %d (reset %d)", threshold, reset)
+	}
+	if suspicious, _, _ := isSuspicious("203.0.113.30", "/.git/config"); !suspicious {
+		t.Error("Expected new suspicious path to be applied")
+	}
+	if suspicious, _, _ := isSuspicious("203.0.113.30", "/wp-login.php"); suspicious {
+		t.Error("Expected default suspicious paths to be replaced")
+	}
+
+	// Битый файл не меняет действующие правила
+	if err := os.WriteFile(securityConfigFile, []byte("RATE_LIMIT=1\nбез знака равенства\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadSecurityConfig(); err == nil {
+		t.Error("Expected error for malformed config file")
+	}
+	if got := currentRequestLimit(); got != 20 {
+		t.Errorf("Expected limit to stay 20 after failed reload, got %d", got)
+	}
+}
@@ -0,0 +1,116 @@
+// ФАЙЛ: replica.go
+// НАЗНАЧЕНИЕ: Чтение целей с реплики базы данных
+// ОСОБЕННОСТИ:
+//   - READ_DATABASE_URL задаёт реплику; без неё все запросы идут в основной пул dbPool
+//   - Реплика используется только для чтения: список, одна цель (GET /goals/{id}), количество,
+//     выгрузка, статистика и сроки целей
+//   - Клиент, который только что изменял цели, READ_AFTER_WRITE_WINDOW читает с основной базы,
+//     чтобы не увидеть устаревшие данные из-за задержки репликации
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ РЕПЛИКИ
+var (
+	// Пул соединений с репликой (nil — реплика не настроена)
+	readPool *pgxpool.Pool
+	// Сколько после записи клиент читает с основной базы (0 — не переключать)
+	readAfterWriteWindow time.Duration
+	// Время последней записи по IP клиента
+	recentWrites      = make(map[string]time.Time)
+	recentWritesMutex sync.Mutex
+)
+
+// Ключ контекста: читать с основной базы
+type primaryReadKey struct{}
+
+// ИНИЦИАЛИЗАЦИЯ РЕПЛИКИ
+// Недоступная реплика не останавливает сервер: чтение остаётся на основной базе
+func initReadReplica() {
+	url := strings.TrimSpace(os.Getenv("READ_DATABASE_URL"))
+	if url == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := newDBPool(ctx, url)
+	if err != nil {
+		logger.LogError(err, "⚠️ Не удалось подключиться к реплике READ_DATABASE_URL, чтение идёт с основной базы")
+		return
+	}
+	readPool = pool
+
+	readAfterWriteWindow = getEnvDuration("READ_AFTER_WRITE_WINDOW", 0)
+	logger.InfoLogger.Printf("📖 Чтение целей с реплики %s (после записи с основной базы: %s)",
+		maskDBURL(url), readAfterWriteWindow)
+	if readAfterWriteWindow > 0 {
+		startBackground("recent-writes-cleanup", cleanRecentWrites)
+	}
+}
+
+// ФУНКЦИЯ: readDB
+// НАЗНАЧЕНИЕ: Пул для запросов на чтение: реплика, если она есть и клиент недавно не писал
+func readDB(ctx context.Context) *pgxpool.Pool {
+	if readPool == nil || ctx.Value(primaryReadKey{}) != nil {
+		return dbPool
+	}
+	return readPool
+}
+
+// MIDDLEWARE: Маршрутизация чтения после записи
+// Запоминает время изменяющего запроса клиента, а его чтения в пределах
+// readAfterWriteWindow помечает для основной базы
+func readRoutingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readPool != nil && readAfterWriteWindow > 0 && preferPrimary(getIP(r), r.Method, time.Now()) {
+			r = r.WithContext(context.WithValue(r.Context(), primaryReadKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Учитывает запрос клиента; true, если его чтение должно идти в основную базу
+func preferPrimary(ip, method string, now time.Time) bool {
+	recentWritesMutex.Lock()
+	defer recentWritesMutex.Unlock()
+
+	if isMutatingMethod(method) {
+		recentWrites[ip] = now
+		return false
+	}
+	writtenAt, ok := recentWrites[ip]
+	return ok && now.Sub(writtenAt) < readAfterWriteWindow
+}
+
+// Раз в минуту забываем клиентов, чьё окно после записи истекло
+func cleanRecentWrites(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			recentWritesMutex.Lock()
+			for ip, writtenAt := range recentWrites {
+				if now.Sub(writtenAt) >= readAfterWriteWindow {
+					delete(recentWrites, ip)
+				}
+			}
+			recentWritesMutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ТЕСТ: Чтение идёт с реплики, кроме окна после записи того же клиента
+func TestReadRoutingAfterWrite(t *testing.T) {
+	defer func(primary, replica *pgxpool.Pool, window time.Duration) {
+		dbPool, readPool, readAfterWriteWindow = primary, replica, window
+	}(dbPool, readPool, readAfterWriteWindow)
+
+	// Пулы только сравниваются по указателю, соединения не открываются
+	dbPool, readPool = &pgxpool.Pool{}, &pgxpool.Pool{}
+	readAfterWriteWindow = time.Minute
+	recentWritesMutex.Lock()
+	recentWrites = make(map[string]time.Time)
+	recentWritesMutex.Unlock()
+
+	var used *pgxpool.Pool
+	handler := readRoutingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		used = readDB(r.Context())
+	}))
+	request := func(method, ip string) *pgxpool.Pool {
+		req := httptest.NewRequest(method, "/goals", nil)
+		req.RemoteAddr = ip + ":12345"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return used
+	}
+
+	if request("GET", "203.0.113.1") != readPool {
+		t.Error("Expected read to use the replica")
+	}
+	request("POST", "203.0.113.1")
+	if request("GET", "203.0.113.1") != dbPool {
+		t.Error("Expected read right after a write to use the primary")
+	}
+	if request("GET", "203.0.113.2") != readPool {
+		t.Error("Expected other clients to keep reading from the replica")
+	}
+
+	// Без реплики всё идёт в основной пул
+	readPool = nil
+	if request("GET", "203.0.113.2") != dbPool {
+		t.Error("Expected primary pool when no replica is configured")
+	}
+}
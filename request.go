@@ -0,0 +1,169 @@
+// ФАЙЛ: request.go
+// НАЗНАЧЕНИЕ: Единое декодирование JSON-тела запросов
+// ОСОБЕННОСТИ:
+//   - Проверка Content-Type и ограничение размера тела
+//   - Запрет неизвестных полей
+//   - Понятные сообщения для каждого вида ошибки
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Максимальный размер тела запроса (1 МБ)
+const maxRequestBodyBytes = 1 << 20
+
+// Допустимые Content-Type для JSON-тела (ALLOWED_CONTENT_TYPES, через запятую)
+var allowedContentTypes = []string{"application/json"}
+
+// ИНИЦИАЛИЗАЦИЯ ДЕКОДИРОВАНИЯ ТЕЛА
+func initRequestDecoding() {
+	if raw := os.Getenv("ALLOWED_CONTENT_TYPES"); raw != "" {
+		allowedContentTypes = parseContentTypes(raw)
+	}
+	logger.InfoLogger.Printf("📨 Допустимые Content-Type тела запроса: %s", strings.Join(allowedContentTypes, ", "))
+
+	// Файл импорта (см. import.go) ограничивается отдельно: CSV заметно больше одной цели
+	importMaxBytes = int64(getEnvInt("IMPORT_MAX_BYTES", 5<<20))
+	if importMaxBytes <= 0 {
+		importMaxBytes = 5 << 20
+	}
+}
+
+// Разбираем список типов: приводим к нижнему регистру, пустые пропускаем
+func parseContentTypes(raw string) []string {
+	var types []string
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			types = append(types, value)
+		}
+	}
+	if len(types) == 0 {
+		return []string{"application/json"}
+	}
+	return types
+}
+
+// Проверяем, входит ли media type запроса в список допустимых
+func isAllowedContentType(mediaType string) bool {
+	for _, allowed := range allowedContentTypes {
+		if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// СТРУКТУРА ОШИБКИ ДЕКОДИРОВАНИЯ ТЕЛА
+// Содержит HTTP-статус и сообщение, безопасное для отправки клиенту
+type bodyDecodeError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *bodyDecodeError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *bodyDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ФУНКЦИЯ: checkContentType
+// НАЗНАЧЕНИЕ: Проверяет, что тело объявлено как JSON (415, если нет)
+// Без проверки клиент с form-data получил бы невнятное "Неверный JSON"
+func checkContentType(r *http.Request) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return &bodyDecodeError{Status: http.StatusUnsupportedMediaType,
+			Message: "Не указан Content-Type, ожидается " + strings.Join(allowedContentTypes, " или ")}
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !isAllowedContentType(mediaType) {
+		return &bodyDecodeError{Status: http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("Content-Type %q не поддерживается, ожидается %s", contentType, strings.Join(allowedContentTypes, " или "))}
+	}
+	return nil
+}
+
+// ФУНКЦИЯ: decodeJSONBody
+// НАЗНАЧЕНИЕ: Декодирует JSON из тела запроса в dst
+// Возвращает *bodyDecodeError с подходящим статусом (400, 413, 415)
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	// ШАГ 1: ПРОВЕРКА CONTENT-TYPE
+	if err := checkContentType(r); err != nil {
+		return err
+	}
+
+	// ШАГ 2: ОГРАНИЧЕНИЕ РАЗМЕРА ТЕЛА
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	// ШАГ 3: ДЕКОДИРОВАНИЕ СО СТРОГОЙ ПРОВЕРКОЙ ПОЛЕЙ
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		var maxBytesErr *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Тело запроса пустое", Err: err}
+		case errors.As(err, &syntaxErr):
+			return &bodyDecodeError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Неверный JSON (позиция %d)", syntaxErr.Offset), Err: err}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Неверный JSON (неожиданный конец тела)", Err: err}
+		case errors.As(err, &typeErr):
+			return &bodyDecodeError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Неверный тип поля %q", typeErr.Field), Err: err}
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Неизвестное поле " + field, Err: err}
+		case errors.As(err, &maxBytesErr):
+			return &bodyDecodeError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Тело запроса больше %d байт", maxRequestBodyBytes), Err: err}
+		default:
+			return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Неверный JSON", Err: err}
+		}
+	}
+
+	// ШАГ 4: ТЕЛО ДОЛЖНО СОДЕРЖАТЬ РОВНО ОДИН JSON-ОБЪЕКТ
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Тело запроса должно содержать один JSON-объект"}
+	}
+
+	return nil
+}
+
+// ФУНКЦИЯ: writeDecodeError
+// НАЗНАЧЕНИЕ: Отправляет клиенту ошибку, полученную от decodeJSONBody
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	// Нарушения схемы — это ошибки валидации, отвечаем в том же формате 422
+	var schemaErr *schemaValidationError
+	if errors.As(err, &schemaErr) {
+		writeValidationErrors(w, r, schemaErr.Errors)
+		return
+	}
+
+	status, message := http.StatusBadRequest, "Неверный JSON"
+
+	var decodeErr *bodyDecodeError
+	if errors.As(err, &decodeErr) {
+		status, message = decodeErr.Status, decodeErr.Message
+	}
+
+	http.Error(w, message, status)
+	logger.LogRequest(r.Method, r.URL.Path, status)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ТЕСТ: Неподходящий Content-Type даёт 415 с понятным сообщением
+func TestDecodeJSONBodyContentType(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		status      int
+	}{
+		{"форма", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"текст", "text/plain", http.StatusUnsupportedMediaType},
+		{"без заголовка", "", http.StatusUnsupportedMediaType},
+		{"мусор", ";;;", http.StatusUnsupportedMediaType},
+		{"JSON с charset", "application/json; charset=utf-8", http.StatusOK},
+		{"JSON в верхнем регистре", "Application/JSON", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/goals", bytes.NewBufferString(`{"goal": "x"}`))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		recorder := httptest.NewRecorder()
+
+		var goal Goal
+		if err := decodeJSONBody(recorder, req, &goal); err != nil {
+			writeDecodeError(recorder, req, err)
+		}
+
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, recorder.Code)
+		}
+		if tc.status == http.StatusUnsupportedMediaType && !strings.Contains(recorder.Body.String(), "application/json") {
+			t.Errorf("%s: expected message to mention application/json, got %q", tc.name, recorder.Body.String())
+		}
+	}
+}
+
+// ТЕСТ: Список допустимых типов настраивается
+func TestDecodeJSONBodyAllowedContentTypes(t *testing.T) {
+	previous := allowedContentTypes
+	defer func() { allowedContentTypes = previous }()
+
+	allowedContentTypes = parseContentTypes("application/json, Application/Merge-Patch+JSON")
+
+	req := httptest.NewRequest("POST", "/goals", bytes.NewBufferString(`{"goal": "x"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	var goal Goal
+	if err := decodeJSONBody(httptest.NewRecorder(), req, &goal); err != nil {
+		t.Errorf("Expected configured content type to be accepted, got %v", err)
+	}
+}
+
+// ТЕСТ: при VALIDATE_SCHEMA тело проверяется по схеме до декодирования
+func TestDecodeGoalBodySchema(t *testing.T) {
+	defer func(previous *jsonschema.Schema) { goalSchema = previous }(goalSchema)
+	schema, err := compileGoalSchema(defaultGoalSchema)
+	if err != nil {
+		t.Fatalf("Embedded schema does not compile: %v", err)
+	}
+	goalSchema = schema
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+	}{
+		{"корректное тело", `{"goal": "Go", "timeline": "2025", "salary_target_rub_per_hour": 1500.50}`, http.StatusOK, ""},
+		{"зарплата строкой", `{"goal": "Go", "timeline": "2025", "salary_target_rub_per_hour": "1500"}`, http.StatusUnprocessableEntity, "salary_target_rub_per_hour"},
+		{"нет цели", `{"timeline": "2025"}`, http.StatusUnprocessableEntity, ""},
+		{"нет срока (подставится DEFAULT_TIMELINE)", `{"goal": "Go"}`, http.StatusOK, ""},
+		{"некорректная дата", `{"goal": "Go", "timeline": "2025", "created_at": "вчера"}`, http.StatusUnprocessableEntity, "created_at"},
+		{"неверный JSON", `{"goal": `, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/goals", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		var goal Goal
+		if err := decodeGoalBody(recorder, req, &goal); err != nil {
+			writeDecodeError(recorder, req, err)
+		}
+
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.name, tc.status, recorder.Code, recorder.Body.String())
+			continue
+		}
+		if tc.status != http.StatusUnprocessableEntity {
+			continue
+		}
+
+		var response struct {
+			Errors []FieldError `json:"errors"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		if len(response.Errors) == 0 || response.Errors[0].Code != ValidationCodeSchema || response.Errors[0].Message == "" {
+			t.Errorf("%s: expected schema errors with messages, got %s", tc.name, recorder.Body.String())
+			continue
+		}
+		if response.Errors[0].Field != tc.field {
+			t.Errorf("%s: expected field %q, got %q", tc.name, tc.field, response.Errors[0].Field)
+		}
+	}
+
+	// Без схемы зарплата строкой по-прежнему принимается декодером
+	goalSchema = nil
+	req := httptest.NewRequest("POST", "/goals", bytes.NewBufferString(`{"goal": "Go", "timeline": "2025", "salary_target_rub_per_hour": "1500"}`))
+	req.Header.Set("Content-Type", "application/json")
+	var goal Goal
+	if err := decodeGoalBody(httptest.NewRecorder(), req, &goal); err != nil {
+		t.Errorf("Expected body to decode without schema, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// ФАЙЛ: response.go
+// НАЗНАЧЕНИЕ: Единая отправка JSON-ответов
+// ОСОБЕННОСТИ:
+//   - По умолчанию компактный JSON
+//   - ?pretty=true (или PRETTY_JSON=true по умолчанию) включает отступы для чтения глазами
+//   - ?pretty=false отключает отступы, даже если PRETTY_JSON включён
+//   - Список целей по умолчанию — голый массив [...];
+//     RESPONSE_ENVELOPE=true оборачивает его в {"data":[...],"meta":{"count":N}}
+//   - JSON_NAMING=camel переключает поля цели на camelCase (см. naming.go)
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ФОРМАТА ОТВЕТОВ
+var (
+	// Отступы в JSON по умолчанию (PRETTY_JSON)
+	prettyJSONDefault = false
+	// Оборачивать ли списки в конверт с метаданными (RESPONSE_ENVELOPE)
+	responseEnvelope = false
+)
+
+// СТРУКТУРА КОНВЕРТА СПИСКА
+// Формат: {"data":[...],"meta":{"count":N}}
+type listEnvelope struct {
+	Data interface{} `json:"data"`
+	Meta listMeta    `json:"meta"`
+}
+
+// Метаданные списка
+type listMeta struct {
+	Count int  `json:"count"`           // Количество элементов в data
+	Stale bool `json:"stale,omitempty"` // Данные из кэша: БД была недоступна
+}
+
+// ИНИЦИАЛИЗАЦИЯ ФОРМАТА ОТВЕТОВ
+func initResponseFormat() {
+	prettyJSONDefault = getEnvBool("PRETTY_JSON", false)
+	if prettyJSONDefault {
+		logger.InfoLogger.Println("🖨️ JSON-ответы форматируются с отступами (PRETTY_JSON)")
+	}
+
+	jsonNaming = strings.ToLower(strings.TrimSpace(os.Getenv("JSON_NAMING")))
+	switch jsonNaming {
+	case "", jsonNamingSnake:
+		jsonNaming = jsonNamingSnake
+	case jsonNamingCamel:
+		logger.InfoLogger.Println("🐫 Поля целей в ответах именуются в camelCase (JSON_NAMING=camel)")
+	default:
+		logger.InfoLogger.Printf("⚠️ Неизвестное значение JSON_NAMING=%q, используем snake", jsonNaming)
+		jsonNaming = jsonNamingSnake
+	}
+
+	responseEnvelope = getEnvBool("RESPONSE_ENVELOPE", false)
+	if responseEnvelope {
+		logger.InfoLogger.Println("✉️ Списки отдаются в конверте {\"data\":[...],\"meta\":{...}} (RESPONSE_ENVELOPE)")
+	}
+}
+
+// ФУНКЦИЯ: goalListPayload
+// НАЗНАЧЕНИЕ: Тело ответа со списком целей: голый массив или конверт (RESPONSE_ENVELOPE)
+// nil-срез (от хранилища или из кэша) отдаётся как [], а не null: клиенты ждут массив
+func goalListPayload(goals []Goal, stale bool) interface{} {
+	if goals == nil {
+		goals = []Goal{}
+	}
+	if !responseEnvelope {
+		return goals
+	}
+	return listEnvelope{Data: goals, Meta: listMeta{Count: len(goals), Stale: stale}}
+}
+
+// Нужны ли отступы для этого запроса
+func isPrettyJSON(r *http.Request) bool {
+	if value := r.URL.Query().Get("pretty"); value != "" {
+		if pretty, err := strconv.ParseBool(value); err == nil {
+			return pretty
+		}
+	}
+	return prettyJSONDefault
+}
+
+// ФУНКЦИЯ: encodeJSON
+// НАЗНАЧЕНИЕ: Кодирует payload с учётом ?pretty; как и json.Encoder, добавляет перевод строки
+func encodeJSON(r *http.Request, payload interface{}) ([]byte, error) {
+	var data []byte
+	var err error
+	if isPrettyJSON(r) {
+		data, err = json.MarshalIndent(payload, "", "  ")
+	} else {
+		data, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ФУНКЦИЯ: writeJSON
+// НАЗНАЧЕНИЕ: Отправляет payload как JSON с указанным статусом и логирует запрос
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, payload interface{}) {
+	data, err := encodeJSON(r, payload)
+	if err != nil {
+		logger.LogError(err, "Ошибка кодирования JSON в writeJSON")
+		http.Error(w, "Encode error", http.StatusInternalServerError)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+	logger.LogRequest(r.Method, r.URL.Path, status)
+}
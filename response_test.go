@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ТЕСТ: ?pretty переключает отступы, по умолчанию JSON компактный
+func TestWriteJSONPretty(t *testing.T) {
+	defer func(previous bool) { prettyJSONDefault = previous }(prettyJSONDefault)
+
+	payload := map[string]int{"count": 3}
+	cases := []struct {
+		name          string
+		defaultPretty bool
+		query         string
+		expected      string
+	}{
+		{"по умолчанию компактно", false, "", "{\"count\":3}\n"},
+		{"pretty=true", false, "?pretty=true", "{\n  \"count\": 3\n}\n"},
+		{"PRETTY_JSON по умолчанию", true, "", "{\n  \"count\": 3\n}\n"},
+		{"pretty=false перекрывает PRETTY_JSON", true, "?pretty=false", "{\"count\":3}\n"},
+	}
+
+	for _, tc := range cases {
+		prettyJSONDefault = tc.defaultPretty
+		recorder := httptest.NewRecorder()
+		writeJSON(recorder, httptest.NewRequest("GET", "/goals/count"+tc.query, nil), http.StatusOK, payload)
+
+		if got := recorder.Body.String(); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+		if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("%s: expected JSON content type, got %q", tc.name, recorder.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// ТЕСТ: RESPONSE_ENVELOPE переключает голый массив и конверт
+func TestGoalListPayloadEnvelope(t *testing.T) {
+	defer func(previous bool) { responseEnvelope = previous }(responseEnvelope)
+	goals := []Goal{{ID: 1, Goal: "a"}, {ID: 2, Goal: "b"}}
+
+	responseEnvelope = false
+	if _, ok := goalListPayload(goals, false).([]Goal); !ok {
+		t.Errorf("Expected bare array by default")
+	}
+
+	responseEnvelope = true
+	envelope, ok := goalListPayload(goals, true).(listEnvelope)
+	if !ok {
+		t.Fatalf("Expected envelope when RESPONSE_ENVELOPE is enabled")
+	}
+	if envelope.Meta.Count != 2 || !envelope.Meta.Stale {
+		t.Errorf("Unexpected meta: %+v", envelope.Meta)
+	}
+}
@@ -0,0 +1,151 @@
+// ФАЙЛ: schema.go
+// НАЗНАЧЕНИЕ: Проверка тела создания/обновления цели по JSON Schema (VALIDATE_SCHEMA)
+// ОСОБЕННОСТИ:
+//   - Схема встроена в бинарник (goal.schema.json), GOAL_SCHEMA_FILE подменяет её своей
+//   - Проверяется сырое тело до декодирования: декодер Go молча принимает
+//     то, что схема запрещает (например, зарплату строкой "1500")
+//   - Все нарушения схемы возвращаются одним ответом 422
+
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Схема цели по умолчанию
+//
+//go:embed goal.schema.json
+var defaultGoalSchema []byte
+
+// Скомпилированная схема; nil — проверка по схеме выключена
+var goalSchema *jsonschema.Schema
+
+// ИНИЦИАЛИЗАЦИЯ ПРОВЕРКИ ПО СХЕМЕ
+func initSchemaValidation() {
+	if !getEnvBool("VALIDATE_SCHEMA", false) {
+		return
+	}
+
+	source, raw := "встроенная", defaultGoalSchema
+	if path := os.Getenv("GOAL_SCHEMA_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("❌ Не удалось прочитать GOAL_SCHEMA_FILE: %v", err)
+		}
+		source, raw = path, data
+	}
+
+	schema, err := compileGoalSchema(raw)
+	if err != nil {
+		log.Fatalf("❌ Некорректная JSON Schema (%s): %v", source, err)
+	}
+
+	goalSchema = schema
+	logger.InfoLogger.Printf("📐 Тело запросов проверяется по JSON Schema (%s)", source)
+}
+
+// ФУНКЦИЯ: compileGoalSchema
+// НАЗНАЧЕНИЕ: Компилирует схему; format (date-time и т.п.) проверяется, а не только описывается
+func compileGoalSchema(raw []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+	if err := compiler.AddResource("goal.schema.json", bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile("goal.schema.json")
+}
+
+// СТРУКТУРА ОШИБКИ ПРОВЕРКИ ПО СХЕМЕ
+type schemaValidationError struct {
+	Errors []FieldError
+}
+
+func (e *schemaValidationError) Error() string {
+	return fmt.Sprintf("тело не соответствует схеме (%d нарушений)", len(e.Errors))
+}
+
+// ФУНКЦИЯ: decodeGoalBody
+// НАЗНАЧЕНИЕ: Декодирует цель из тела; при VALIDATE_SCHEMA сначала проверяет тело по схеме
+// Ошибки синтаксиса JSON, размера и Content-Type формирует decodeJSONBody, как и без схемы
+func decodeGoalBody(w http.ResponseWriter, r *http.Request, dst *Goal) error {
+	if goalSchema == nil {
+		return decodeJSONBody(w, r, dst)
+	}
+
+	if err := checkContentType(r); err != nil {
+		return err
+	}
+
+	// Читаем тело целиком (с тем же лимитом) и отдаём его декодеру повторно
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return &bodyDecodeError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Тело запроса больше %d байт", maxRequestBodyBytes), Err: err}
+		}
+		return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Не удалось прочитать тело запроса", Err: err}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+
+	// Невалидный JSON схемой не проверить — понятное сообщение даст decodeJSONBody
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var document interface{}
+	if decoder.Decode(&document) == nil {
+		// Схема описывает snake_case; ключи в camelCase приводим к нему, как и UnmarshalJSON
+		if object, ok := document.(map[string]interface{}); ok {
+			for key, value := range object {
+				if snake := camelToSnake(key); snake != key {
+					delete(object, key)
+					object[snake] = value
+				}
+			}
+		}
+		if err := goalSchema.Validate(document); err != nil {
+			var validationErr *jsonschema.ValidationError
+			if !errors.As(err, &validationErr) {
+				return &bodyDecodeError{Status: http.StatusBadRequest, Message: "Ошибка проверки по схеме", Err: err}
+			}
+			return &schemaValidationError{Errors: schemaFieldErrors(validationErr)}
+		}
+	}
+
+	return decodeJSONBody(w, r, dst)
+}
+
+// ФУНКЦИЯ: schemaFieldErrors
+// НАЗНАЧЕНИЕ: Превращает дерево ошибок библиотеки в плоский список по полям
+// Берутся только листья: промежуточные узлы лишь сообщают, что не прошла вложенная проверка
+func schemaFieldErrors(root *jsonschema.ValidationError) []FieldError {
+	var errs []FieldError
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			errs = append(errs, FieldError{
+				Field:   strings.TrimPrefix(e.InstanceLocation, "/"),
+				Code:    ValidationCodeSchema,
+				Message: e.Message,
+			})
+			return
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(root)
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
@@ -4,12 +4,18 @@
 //   - Автоматическое блокирование IP
 //   - Гибкие лимиты для разных endpoint'ов
 //   - Интеграция с логированием
+//   - Служебные пути (EXEMPT_PATHS: /health, /metrics...) не ограничиваются и не блокируются
+//   - Для каждого подозрительного пути своё действие: только журнал, короткая или полная блокировка
 
 package main
 
 import (
+	"context"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"strings"
 	"sync"
@@ -22,8 +28,8 @@ var (
 	requestCounts = make(map[string]int)
 	// Хранилище времени последнего запроса
 	lastRequestTime = make(map[string]time.Time)
-	// Мапа заблокированных IP
-	blockedIPs = make(map[string]time.Time)
+	// Мапа заблокированных IP: IP → когда и за что заблокирован
+	blockedIPs = make(map[string]blockEntry)
 	// Мьютекс для потокобезопасности
 	countMutex sync.Mutex
 	// Белый список IP (разрешены без лимитов)
@@ -33,57 +39,247 @@ var (
 		"10.0.0.1",  // Внутренний IP офиса
 	}
 	// Лимиты запросов
-	requestLimit   = 100           // Максимум запросов в минуту
-	blockDuration  = 1 * time.Hour // Время блокировки
-	securityLogger *log.Logger     // Отдельный логгер для безопасности
+	requestLimit   = defaultRequestLimit  // Максимум запросов в минуту (RATE_LIMIT)
+	blockDuration  = defaultBlockDuration // Время блокировки (BLOCK_DURATION)
+	securityLogger *log.Logger            // Отдельный логгер для безопасности
+	// Защита отключена (DISABLE_SECURITY) — только для локальной разработки
+	securityDisabled = false
+	// Заголовок с реальным IP клиента от прокси (CLIENT_IP_HEADER, например CF-Connecting-IP)
+	clientIPHeader string
+	// Прокси, которым разрешено передавать clientIPHeader и X-Forwarded-For (TRUSTED_PROXIES: IP или CIDR)
+	trustedProxies []netip.Prefix
+	// Максимальная длина пути запроса (MAX_PATH_LENGTH), длиннее — 414 URI Too Long
+	maxPathLength = 2048
+	// Файл журнала безопасности
+	securityLogPath = "security.log"
+	// Служебные пути без rate limiting и проверки подозрительных путей (EXEMPT_PATHS)
+	exemptPaths = defaultExemptPaths
+	// Правила для подозрительных путей (SUSPICIOUS_PATHS): подстрока пути и действие
+	suspiciousPaths = parseSuspiciousPathRules("", suspiciousActionShortBlock)
+	// Срок короткой блокировки за подозрительный путь (SUSPICIOUS_SHORT_BLOCK)
+	suspiciousShortBlock = defaultSuspiciousShortBlock
 )
 
+// Подозрительные пути по умолчанию
+var defaultSuspiciousPaths = []string{"/admin", "/wp-login.php", "/.env", "/backup"}
+
+// ДЕЙСТВИЯ ПРИ ОБРАЩЕНИИ К ПОДОЗРИТЕЛЬНОМУ ПУТИ
+// За одним IP может стоять NAT с обычными пользователями, поэтому по умолчанию — короткая блокировка
+const (
+	suspiciousActionLog        = "log"   // Только запись в security.log, запрос обрабатывается
+	suspiciousActionShortBlock = "short" // Блокировка на SUSPICIOUS_SHORT_BLOCK
+	suspiciousActionBlock      = "block" // Блокировка на общий срок BLOCK_DURATION
+)
+
+// Срок короткой блокировки по умолчанию
+const defaultSuspiciousShortBlock = 5 * time.Minute
+
+// ПРАВИЛО ДЛЯ ПОДОЗРИТЕЛЬНОГО ПУТИ
+type suspiciousPathRule struct {
+	Path   string // Подстрока пути
+	Action string // См. константы suspiciousAction*
+}
+
+func (r suspiciousPathRule) String() string { return r.Path + "=" + r.Action }
+
+// Служебные пути по умолчанию: мониторинг и пробы оркестратора
+var defaultExemptPaths = []string{"/health", "/ready", "/metrics", "/version"}
+
+// ПРИЧИНЫ БЛОКИРОВКИ IP
+const (
+	blockReasonRateLimit      = "rate_limit"      // Превышен лимит запросов
+	blockReasonSuspiciousPath = "suspicious_path" // Запрос к подозрительному пути (/admin, /.env...)
+	blockReasonRequestFlood   = "request_flood"   // Запросов больше двойного лимита
+	blockReasonHighErrorRate  = "high_error_rate" // Много ошибок от IP (см. alerts.go)
+	blockReasonAdminAuth      = "admin_auth"      // Подбор пароля администратора (см. admin.go)
+)
+
+// ПРИЧИНЫ ОТКАЗА БЕЗ БЛОКИРОВКИ IP
+const (
+	rejectReasonURITooLong     = "uri_too_long"    // Слишком длинный путь
+	rejectReasonBlockedCountry = "blocked_country" // Страна клиента в BLOCKED_COUNTRIES
+)
+
+// Сколько символов пути попадает в security.log при отказе 414
+const loggedPathPrefixLength = 128
+
+// ИНТЕРФЕЙС ЧАСОВ
+// Позволяет подменить время в тестах, чтобы проверять истечение блокировок без реальных ожиданий
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// Реальные часы (используются по умолчанию)
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Часы подсистемы безопасности (в тестах заменяются на фейковые)
+var securityClock clock = realClock{}
+
+// СТРУКТУРА ЗАПИСИ О БЛОКИРОВКЕ
+type blockEntry struct {
+	BlockedAt time.Time     // Время блокировки
+	Reason    string        // Причина (см. константы blockReason*)
+	Duration  time.Duration // Срок блокировки; 0 — общий blockDuration
+}
+
+// Когда истекает блокировка; вызывается под countMutex
+func (e blockEntry) expiresAt() time.Time {
+	if e.Duration > 0 {
+		return e.BlockedAt.Add(e.Duration)
+	}
+	return e.BlockedAt.Add(blockDuration)
+}
+
 // ИНИЦИАЛИЗАЦИЯ ЗАЩИТЫ
 func initSecurity() {
 	// Создаём отдельный лог-файл для безопасности
-	securityFile, err := os.OpenFile("security.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatalf("❌ Не удалось создать security.log: %v", err)
+	securityLogger = newSecurityLogger(securityLogPath)
+
+	// Правила и пороги (их же перечитывает SIGHUP, см. reload.go)
+	securityConfigFile = strings.TrimSpace(os.Getenv("SECURITY_CONFIG_FILE"))
+	if securityConfigFile != "" {
+		if err := loadEnvFile(securityConfigFile); err != nil {
+			logger.LogError(err, "⚠️ Не удалось прочитать SECURITY_CONFIG_FILE, используем переменные окружения")
+		}
+	}
+	applySecurityRules(readSecurityRules())
+
+	// Заголовок с IP клиента учитывается только от доверенных прокси
+	clientIPHeader = http.CanonicalHeaderKey(strings.TrimSpace(os.Getenv("CLIENT_IP_HEADER")))
+	trustedProxies = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if len(trustedProxies) == 0 {
+		logger.InfoLogger.Println("⚠️ TRUSTED_PROXIES пуст — X-Forwarded-For принимается от любого источника")
+	}
+	if clientIPHeader != "" {
+		if len(trustedProxies) == 0 {
+			logger.InfoLogger.Printf("⚠️ CLIENT_IP_HEADER=%s задан, но TRUSTED_PROXIES пуст — заголовок игнорируется", clientIPHeader)
+		} else {
+			logger.InfoLogger.Printf("🌐 IP клиента берётся из %s (доверенных прокси: %d)", clientIPHeader, len(trustedProxies))
+		}
+	}
+
+	maxPathLength = getEnvInt("MAX_PATH_LENGTH", 2048)
+	if maxPathLength <= 0 {
+		logger.InfoLogger.Printf("⚠️ MAX_PATH_LENGTH должен быть больше нуля, используем 2048")
+		maxPathLength = 2048
+	}
+
+	if raw, ok := os.LookupEnv("EXEMPT_PATHS"); ok {
+		exemptPaths = parseExemptPaths(raw)
+	}
+	logger.InfoLogger.Printf("🩺 Без rate limiting: %s", strings.Join(exemptPaths, ", "))
+
+	// Отключение защиты никогда не включено по умолчанию и всегда заметно в логах
+	securityDisabled = getEnvBool("DISABLE_SECURITY", false)
+	if securityDisabled {
+		logger.ErrorLogger.Println("🚨🚨🚨 DISABLE_SECURITY=true: rate limiting и проверка подозрительных путей ОТКЛЮЧЕНЫ. Только для локальной разработки! 🚨🚨🚨")
+		securityLogger.Println("SECURITY_DISABLED | защита отключена переменной DISABLE_SECURITY")
 	}
-	securityLogger = log.New(securityFile, "SECURITY: ", log.Ldate|log.Ltime|log.LUTC)
 
 	// Запускаем очистку старых записей каждые 5 минут
-	go cleanRequestCounts()
+	startBackground("security-cleanup", cleanRequestCounts)
+}
+
+// ФУНКЦИЯ: newSecurityLogger
+// НАЗНАЧЕНИЕ: Логгер событий безопасности в файл path
+// Если файл не открыть (файловая система только для чтения, нет прав на cwd),
+// события пишутся в stderr: из-за журнала приложение не должно падать
+func newSecurityLogger(path string) *log.Logger {
+	var out io.Writer = os.Stderr
+	securityFile, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.ErrorLogger.Printf("⚠️ Не удалось открыть %s (%v), события безопасности пишутся в stderr", path, err)
+	} else {
+		out = securityFile
+	}
+	return log.New(out, "SECURITY: ", log.Ldate|log.Ltime|log.LUTC)
 }
 
 // MIDDLEWARE: Rate limiting и защита от DDoS
 func securityMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Защита отключена для локальной разработки
+		if securityDisabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		ip := getIP(r)
 
-		// ШАГ 1: Проверяем белый список
+		// ШАГ 0: Отсекаем патологически длинные пути до любой другой обработки:
+		// обработчики режут и разбирают путь, а логгер пишет его целиком
+		if len(r.URL.Path) > maxPathLength {
+			logSecurityEventWithReason("URI_TOO_LONG", ip, r.URL.Path[:min(len(r.URL.Path), loggedPathPrefixLength)]+"...", rejectReasonURITooLong)
+			rateLimitRejections.WithLabelValues(rejectReasonURITooLong).Inc()
+			http.Error(w, "Слишком длинный URI", http.StatusRequestURITooLong)
+			return
+		}
+
+		// ШАГ 1: Служебные пути (мониторинг, пробы) не считаются и не блокируются:
+		// иначе частые скрейпы /metrics сами упираются в лимит.
+		// Если IP при этом заблокирован, обращение фиксируется в журнале безопасности
+		if isExemptPath(r.URL.Path) {
+			if entry, blocked := getBlock(ip); blocked {
+				logSecurityEventWithReason("EXEMPT_ACCESS", ip, r.URL.Path, entry.Reason)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// ШАГ 2: Проверяем белый список
 		if isTrusted(ip) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// ШАГ 2: Проверяем блокировку
-		if isBlocked(ip) {
-			logSecurityEvent("BLOCKED_ACCESS", ip, r.URL.Path)
+		// ШАГ 3: Проверяем страну клиента (только при настроенном GeoIP)
+		if country, blocked := isBlockedCountry(ip); blocked {
+			logSecurityEventWithReason("BLOCKED_COUNTRY", ip, r.URL.Path, rejectReasonBlockedCountry)
+			rateLimitRejections.WithLabelValues(rejectReasonBlockedCountry).Inc()
+			http.Error(w, "Доступ из вашей страны ("+country+") запрещён", http.StatusForbidden)
+			return
+		}
+
+		// ШАГ 4: Проверяем блокировку
+		if entry, blocked := getBlock(ip); blocked {
+			logSecurityEventWithReason("BLOCKED_ACCESS", ip, r.URL.Path, entry.Reason)
+			rateLimitRejections.WithLabelValues(entry.Reason).Inc()
 			http.Error(w, "Доступ временно заблокирован", http.StatusTooManyRequests)
 			return
 		}
 
-		// ШАГ 3: Обновляем счётчики запросов
+		// ШАГ 5: Обновляем счётчики запросов
 		count := incrementRequestCount(ip)
 
-		// ШАГ 4: Проверяем лимит запросов
-		if count > requestLimit {
-			blockIP(ip)
-			logSecurityEvent("RATE_LIMIT_EXCEEDED", ip, r.URL.Path)
+		// ШАГ 6: Проверяем лимит запросов
+		if count > currentRequestLimit() {
+			blockIP(ip, blockReasonRateLimit)
+			logSecurityEventWithReason("RATE_LIMIT_EXCEEDED", ip, r.URL.Path, blockReasonRateLimit)
+			rateLimitRejections.WithLabelValues(blockReasonRateLimit).Inc()
 			http.Error(w, "Слишком много запросов. Попробуйте позже.", http.StatusTooManyRequests)
 			return
 		}
 
-		// ШАГ 5: Проверяем подозрительную активность
-		if isSuspicious(ip, r.URL.Path) {
-			blockIP(ip)
-			logSecurityEvent("SUSPICIOUS_ACTIVITY", ip, r.URL.Path)
+		// ШАГ 7: Проверяем подозрительную активность
+		if suspicious, reason, action := isSuspicious(ip, r.URL.Path); suspicious {
+			switch action {
+			case suspiciousActionLog:
+				// Разведку фиксируем, но запрос обрабатываем как обычно
+				logSecurityEventWithReason("SUSPICIOUS_ACTIVITY_LOGGED", ip, r.URL.Path, reason)
+				next.ServeHTTP(w, r)
+				return
+			case suspiciousActionShortBlock:
+				blockIPFor(ip, reason, currentSuspiciousShortBlock())
+				logSecurityEventWithReason("SUSPICIOUS_ACTIVITY_SHORT_BLOCK", ip, r.URL.Path, reason)
+			default:
+				blockIP(ip, reason)
+				logSecurityEventWithReason("SUSPICIOUS_ACTIVITY", ip, r.URL.Path, reason)
+			}
+			rateLimitRejections.WithLabelValues(reason).Inc()
 			http.Error(w, "Подозрительная активность обнаружена", http.StatusForbidden)
 			return
 		}
@@ -94,21 +290,116 @@ func securityMiddleware(next http.Handler) http.Handler {
 
 // ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
 
-// Получаем реальный IP (учитывая прокси и Heroku)
+// Получаем IP клиента
+// Порядок: CLIENT_IP_HEADER (только от доверенного прокси) → X-Forwarded-For
+// (от доверенного прокси, если TRUSTED_PROXIES задан) → RemoteAddr
 func getIP(r *http.Request) string {
-	// Сначала проверяем X-Forwarded-For (актуально для Heroku)
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		// Берём первый IP из списка (наиболее удалённый)
-		ips := strings.Split(forwarded, ",")
-		return strings.TrimSpace(ips[0])
+	peer := remoteIP(r)
+
+	// Заголовок прокси (Cloudflare, nginx) — только если запрос пришёл от самого прокси,
+	// иначе клиент мог бы подставить любой IP и обойти rate limiter
+	if clientIPHeader != "" && isTrustedProxy(peer) {
+		if value := strings.TrimSpace(r.Header.Get(clientIPHeader)); value != "" {
+			ips := strings.Split(value, ",")
+			return strings.TrimSpace(ips[0])
+		}
+	}
+
+	// Затем X-Forwarded-For (актуально для Heroku). С TRUSTED_PROXIES — только от доверенного
+	// прокси; без него (адреса роутера Heroku заранее неизвестны) — от любого источника
+	if len(trustedProxies) == 0 || isTrustedProxy(peer) {
+		if ip := forwardedClientIP(r.Header.Get("X-Forwarded-For")); ip != "" {
+			return ip
+		}
+	}
+
+	return peer
+}
+
+// IP клиента из X-Forwarded-For: первый справа адрес, который не является доверенным прокси.
+// Левые записи присылает сам клиент, поэтому доверять можно только тем, что дописали прокси
+func forwardedClientIP(forwarded string) string {
+	ips := strings.Split(forwarded, ",")
+	for i := len(ips) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(ips[i])
+		if ip != "" && (i == 0 || !isTrustedProxy(ip)) {
+			return ip
+		}
+	}
+	return ""
+}
+
+// IP непосредственного собеседника из RemoteAddr (без порта, IPv6 без скобок)
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Разбираем список служебных путей: "/health, /ready"
+// Пустая строка отключает исключения
+func parseExemptPaths(raw string) []string {
+	paths := []string{}
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.TrimRight(strings.TrimSpace(value), "/")
+		if value == "" {
+			continue
+		}
+		if !strings.HasPrefix(value, "/") {
+			logger.InfoLogger.Printf("⚠️ Некорректная запись в EXEMPT_PATHS (путь должен начинаться с /): %q", value)
+			continue
+		}
+		paths = append(paths, value)
+	}
+	return paths
+}
+
+// Служебный ли путь: точное совпадение или вложенный путь (/health/live для /health)
+func isExemptPath(path string) bool {
+	for _, exempt := range exemptPaths {
+		if path == exempt || strings.HasPrefix(path, exempt+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Разбираем список доверенных прокси: "10.0.0.0/8, 192.0.2.1"
+func parseTrustedProxies(raw string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		logger.InfoLogger.Printf("⚠️ Некорректная запись в TRUSTED_PROXIES: %q", value)
 	}
+	return prefixes
+}
 
-	// Если нет X-Forwarded-For, берём RemoteAddr
-	ip := r.RemoteAddr
-	if colon := strings.LastIndex(ip, ":"); colon != -1 {
-		ip = ip[:colon]
+// Пришёл ли запрос от доверенного прокси
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
 	}
-	return ip
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
 
 // Проверяем, является ли IP доверенным
@@ -128,11 +419,11 @@ func incrementRequestCount(ip string) int {
 
 	// Инициализируем время первого запроса
 	if _, exists := lastRequestTime[ip]; !exists {
-		lastRequestTime[ip] = time.Now()
+		lastRequestTime[ip] = securityClock.Now()
 	}
 
 	// Обновляем время последнего запроса
-	lastRequestTime[ip] = time.Now()
+	lastRequestTime[ip] = securityClock.Now()
 
 	// Увеличиваем счётчик
 	requestCounts[ip]++
@@ -141,77 +432,157 @@ func incrementRequestCount(ip string) int {
 
 // Проверяем, заблокирован ли IP
 func isBlocked(ip string) bool {
+	_, blocked := getBlock(ip)
+	return blocked
+}
+
+// Получаем действующую блокировку IP (с причиной)
+func getBlock(ip string) (blockEntry, bool) {
 	countMutex.Lock()
 	defer countMutex.Unlock()
 
-	blockTime, exists := blockedIPs[ip]
+	entry, exists := blockedIPs[ip]
 	if !exists {
-		return false
+		return blockEntry{}, false
 	}
 
 	// Проверяем, не истёк ли срок блокировки
-	return time.Since(blockTime) < blockDuration
+	return entry, securityClock.Now().Before(entry.expiresAt())
+}
+
+// Блокируем IP на определённое время с указанием причины
+func blockIP(ip, reason string) {
+	blockIPFor(ip, reason, 0)
 }
 
-// Блокируем IP на определённое время
-func blockIP(ip string) {
+// Блокируем IP на срок duration (0 — общий blockDuration)
+func blockIPFor(ip, reason string, duration time.Duration) {
 	countMutex.Lock()
 	defer countMutex.Unlock()
 
-	blockedIPs[ip] = time.Now()
+	blockedIPs[ip] = blockEntry{BlockedAt: securityClock.Now(), Reason: reason, Duration: duration}
+	rateLimitBlocks.WithLabelValues(reason).Inc()
+}
+
+// Срок короткой блокировки (может меняться по SIGHUP)
+func currentSuspiciousShortBlock() time.Duration {
+	countMutex.Lock()
+	defer countMutex.Unlock()
+	return suspiciousShortBlock
+}
+
+// Текущий лимит запросов (может меняться через /admin/config)
+func currentRequestLimit() int {
+	countMutex.Lock()
+	defer countMutex.Unlock()
+	return requestLimit
 }
 
 // Проверяем подозрительную активность
-func isSuspicious(ip string, path string) bool {
+// Возвращает причину блокировки и действие (см. suspiciousAction*), если активность подозрительная
+func isSuspicious(ip string, path string) (bool, string, string) {
 	countMutex.Lock()
 	defer countMutex.Unlock()
 
 	// Правило 1: Слишком частые запросы к одному endpoint
 	if count, exists := requestCounts[ip]; exists && count > requestLimit*2 {
-		return true
+		return true, blockReasonRequestFlood, suspiciousActionBlock
 	}
 
 	// Правило 2: Запросы к несуществующим endpoint'ам
-	suspiciousPaths := []string{"/admin", "/wp-login.php", "/.env", "/backup"}
-	for _, sp := range suspiciousPaths {
-		if strings.Contains(path, sp) {
-			return true
+	for _, rule := range suspiciousPaths {
+		if strings.Contains(path, rule.Path) {
+			return true, blockReasonSuspiciousPath, rule.Action
 		}
 	}
 
+	return false, "", ""
+}
+
+// Разбираем правила подозрительных путей: "/admin=log, /.env=block, /backup"
+// Путь без действия получает defaultAction; пустая строка — пути по умолчанию
+func parseSuspiciousPathRules(raw, defaultAction string) []suspiciousPathRule {
+	var rules []suspiciousPathRule
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		path, action, found := strings.Cut(entry, "=")
+		path, action = strings.TrimSpace(path), strings.ToLower(strings.TrimSpace(action))
+		if !found {
+			action = defaultAction
+		}
+		if path == "" || !isSuspiciousAction(action) {
+			logger.InfoLogger.Printf("⚠️ Некорректная запись в SUSPICIOUS_PATHS: %q (действия: log, short, block)", entry)
+			continue
+		}
+		rules = append(rules, suspiciousPathRule{Path: path, Action: action})
+	}
+
+	if len(rules) == 0 {
+		for _, path := range defaultSuspiciousPaths {
+			rules = append(rules, suspiciousPathRule{Path: path, Action: defaultAction})
+		}
+	}
+	return rules
+}
+
+// Известно ли действие для подозрительного пути
+func isSuspiciousAction(action string) bool {
+	switch action {
+	case suspiciousActionLog, suspiciousActionShortBlock, suspiciousActionBlock:
+		return true
+	}
 	return false
 }
 
 // Логируем события безопасности
 func logSecurityEvent(eventType, ip, path string) {
-	securityLogger.Printf("%s | IP: %s | PATH: %s", eventType, ip, path)
+	securityLogger.Printf("%s | IP: %s | PATH: %s%s", eventType, ip, path, countryLogSuffix(ip))
+}
+
+// Логируем события безопасности с причиной блокировки
+func logSecurityEventWithReason(eventType, ip, path, reason string) {
+	securityLogger.Printf("%s | IP: %s | PATH: %s | REASON: %s%s", eventType, ip, path, reason, countryLogSuffix(ip))
 }
 
 // Очищаем старые записи из счётчиков
-func cleanRequestCounts() {
+func cleanRequestCounts(ctx context.Context) {
 	for {
-		time.Sleep(5 * time.Minute)
-
-		countMutex.Lock()
-		currentTime := time.Now()
-
-		// Удаляем IP, которые не делали запросы больше 10 минут
-		for ip := range requestCounts {
-			if lastTime, exists := lastRequestTime[ip]; exists {
-				if currentTime.Sub(lastTime) > 10*time.Minute {
-					delete(requestCounts, ip)
-					delete(lastRequestTime, ip)
-				}
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-securityClock.After(5 * time.Minute):
+			cleanupSecurityState()
 		}
+	}
+}
+
+// Один проход очистки: удаляем неактивные IP и истёкшие блокировки
+func cleanupSecurityState() {
+	countMutex.Lock()
+	defer countMutex.Unlock()
 
-		// Очищаем список заблокированных IP
-		for ip, blockTime := range blockedIPs {
-			if currentTime.Sub(blockTime) > blockDuration {
-				delete(blockedIPs, ip)
+	currentTime := securityClock.Now()
+
+	// Удаляем IP, которые не делали запросы больше 10 минут
+	for ip := range requestCounts {
+		if lastTime, exists := lastRequestTime[ip]; exists {
+			if currentTime.Sub(lastTime) > 10*time.Minute {
+				delete(requestCounts, ip)
+				delete(lastRequestTime, ip)
 			}
 		}
+	}
 
-		countMutex.Unlock()
+	// Очищаем список заблокированных IP
+	for ip, entry := range blockedIPs {
+		if currentTime.After(entry.expiresAt()) {
+			delete(blockedIPs, ip)
+		}
 	}
+
+	// Неудачные попытки входа администратора с закрытым окном
+	cleanupAdminAuthFailures(currentTime)
 }
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// ФЕЙКОВЫЕ ЧАСЫ ДЛЯ ТЕСТОВ
+// Время двигается только вызовом Advance
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Now().Add(d)
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Подменяем часы и состояние лимитера на время теста
+func useFakeSecurityClock(t *testing.T) *fakeClock {
+	t.Helper()
+
+	clock := newFakeClock()
+	previous := securityClock
+	securityClock = clock
+
+	countMutex.Lock()
+	previousCounts, previousTimes, previousBlocks := requestCounts, lastRequestTime, blockedIPs
+	requestCounts = make(map[string]int)
+	lastRequestTime = make(map[string]time.Time)
+	blockedIPs = make(map[string]blockEntry)
+	countMutex.Unlock()
+
+	adminAuthMutex.Lock()
+	previousFailures := adminAuthFailures
+	adminAuthFailures = make(map[string]adminAuthFailure)
+	adminAuthMutex.Unlock()
+
+	t.Cleanup(func() {
+		securityClock = previous
+		countMutex.Lock()
+		requestCounts, lastRequestTime, blockedIPs = previousCounts, previousTimes, previousBlocks
+		countMutex.Unlock()
+		adminAuthMutex.Lock()
+		adminAuthFailures = previousFailures
+		adminAuthMutex.Unlock()
+	})
+	return clock
+}
+
+// ФУНКЦИЯ: newSecurityTestHandler
+// НАЗНАЧЕНИЕ: securityMiddleware перед обработчиком, который отвечает status, на фейковых часах
+// и с чистым состоянием лимитера. События безопасности пишутся в securityLog (nil — в никуда);
+// часы, лимитер и securityLogger восстанавливаются после теста.
+// Возвращает функцию, которая отправляет GET path с адреса ip и отдаёт код ответа
+func newSecurityTestHandler(t *testing.T, status int, securityLog io.Writer) (*fakeClock, func(ip, path string) int) {
+	t.Helper()
+
+	clock := useFakeSecurityClock(t)
+	if securityLog == nil {
+		securityLog = io.Discard
+	}
+	previousLogger := securityLogger
+	securityLogger = log.New(securityLog, "", 0)
+	t.Cleanup(func() { securityLogger = previousLogger })
+	resetMetrics()
+
+	handler := securityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	return clock, func(ip, path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+}
+
+// ТЕСТ: Блокировка истекает через blockDuration
+func TestBlockExpiresWithFakeClock(t *testing.T) {
+	clock := useFakeSecurityClock(t)
+
+	blockIP("198.51.100.1", blockReasonRateLimit)
+	if !isBlocked("198.51.100.1") {
+		t.Fatalf("Expected IP to be blocked right after blockIP")
+	}
+
+	clock.Advance(blockDuration - time.Second)
+	if !isBlocked("198.51.100.1") {
+		t.Errorf("Expected IP to stay blocked before blockDuration passes")
+	}
+
+	clock.Advance(2 * time.Second)
+	if isBlocked("198.51.100.1") {
+		t.Errorf("Expected block to expire after blockDuration")
+	}
+}
+
+// ТЕСТ: Очистка удаляет неактивные IP и истёкшие блокировки
+func TestCleanupSecurityStateWithFakeClock(t *testing.T) {
+	clock := useFakeSecurityClock(t)
+
+	incrementRequestCount("198.51.100.2")
+	blockIP("198.51.100.3", blockReasonSuspiciousPath)
+
+	clock.Advance(11 * time.Minute)
+	incrementRequestCount("198.51.100.4") // Активный IP должен остаться
+	cleanupSecurityState()
+
+	countMutex.Lock()
+	_, staleCounted := requestCounts["198.51.100.2"]
+	_, activeCounted := requestCounts["198.51.100.4"]
+	_, stillBlocked := blockedIPs["198.51.100.3"]
+	countMutex.Unlock()
+
+	if staleCounted {
+		t.Errorf("Expected inactive IP counter to be removed")
+	}
+	if !activeCounted {
+		t.Errorf("Expected active IP counter to be kept")
+	}
+	if !stillBlocked {
+		t.Errorf("Expected block to be kept before blockDuration passes")
+	}
+
+	clock.Advance(blockDuration)
+	cleanupSecurityState()
+
+	countMutex.Lock()
+	_, stillBlocked = blockedIPs["198.51.100.3"]
+	countMutex.Unlock()
+	if stillBlocked {
+		t.Errorf("Expected expired block to be removed")
+	}
+}
+
+// ТЕСТ: Блокировка и отказ в доступе увеличивают счётчики rate limiter
+func TestRateLimitCountersOnForcedBlock(t *testing.T) {
+	_, request := newSecurityTestHandler(t, http.StatusOK, nil)
+
+	blockIP("198.51.100.9", blockReasonRateLimit)
+
+	if code := request("198.51.100.9", "/goals"); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+	if got := testutil.ToFloat64(rateLimitBlocks.WithLabelValues(blockReasonRateLimit)); got != 1 {
+		t.Errorf("Expected rate_limit_blocks_total = 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(rateLimitRejections.WithLabelValues(blockReasonRateLimit)); got != 1 {
+		t.Errorf("Expected rate_limit_rejections_total = 1, got %v", got)
+	}
+}
+
+// ТЕСТ: CLIENT_IP_HEADER и X-Forwarded-For учитываются только от доверенного прокси
+func TestGetIPClientIPHeader(t *testing.T) {
+	defer func(header string, proxies []netip.Prefix) {
+		clientIPHeader, trustedProxies = header, proxies
+	}(clientIPHeader, trustedProxies)
+
+	clientIPHeader = "Cf-Connecting-Ip"
+	trustedProxies = parseTrustedProxies("173.245.48.0/20, 192.0.2.10")
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		forwarded  string
+		expected   string
+	}{
+		{"доверенный прокси из подсети", "173.245.48.5:443", "203.0.113.1", "", "203.0.113.1"},
+		{"доверенный прокси по IP", "192.0.2.10:443", "203.0.113.2", "198.51.100.1", "203.0.113.2"},
+		{"чужой источник — заголовки игнорируются", "198.51.100.7:5000", "203.0.113.3", "198.51.100.2", "198.51.100.7"},
+		{"X-Forwarded-For от доверенного прокси", "173.245.48.5:443", "", "198.51.100.3", "198.51.100.3"},
+		{"подделанное начало X-Forwarded-For", "173.245.48.5:443", "", "10.0.0.1, 198.51.100.4", "198.51.100.4"},
+		{"цепочка доверенных прокси", "192.0.2.10:443", "", "198.51.100.5, 173.245.48.9", "198.51.100.5"},
+		{"без заголовков — RemoteAddr", "198.51.100.8:5000", "", "", "198.51.100.8"},
+		{"IPv6 RemoteAddr", "[2001:db8::1]:5000", "", "", "2001:db8::1"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/goals", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.header != "" {
+			req.Header.Set("CF-Connecting-IP", tc.header)
+		}
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+
+		if got := getIP(req); got != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, got)
+		}
+	}
+}
+
+// ТЕСТ: без TRUSTED_PROXIES X-Forwarded-For принимается от любого источника (Heroku),
+// но берётся адрес, дописанный последним прокси, а не присланный клиентом
+func TestGetIPForwardedWithoutTrustedProxies(t *testing.T) {
+	defer func(header string, proxies []netip.Prefix) {
+		clientIPHeader, trustedProxies = header, proxies
+	}(clientIPHeader, trustedProxies)
+	clientIPHeader, trustedProxies = "", nil
+
+	req := httptest.NewRequest("GET", "/goals", nil)
+	req.RemoteAddr = "10.1.2.3:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.66, 198.51.100.6")
+	if got := getIP(req); got != "198.51.100.6" {
+		t.Errorf("Expected IP appended by the proxy, got %s", got)
+	}
+}
+
+// ТЕСТ: Слишком длинный путь отклоняется с 414 без блокировки IP
+func TestSecurityMiddlewareURITooLong(t *testing.T) {
+	_, request := newSecurityTestHandler(t, http.StatusOK, nil)
+
+	if code := request("198.51.100.20", "/goals/"+strings.Repeat("1", maxPathLength)); code != http.StatusRequestURITooLong {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestURITooLong, code)
+	}
+	if got := testutil.ToFloat64(rateLimitRejections.WithLabelValues(rejectReasonURITooLong)); got != 1 {
+		t.Errorf("Expected rate_limit_rejections_total{reason=%q} = 1, got %v", rejectReasonURITooLong, got)
+	}
+	if code := request("198.51.100.20", "/goals/1"); code != http.StatusOK {
+		t.Errorf("Expected regular request to pass after 414, got %d", code)
+	}
+}
+
+// ТЕСТ: Недоступный security.log не роняет приложение, события идут в stderr
+func TestNewSecurityLoggerFallback(t *testing.T) {
+	fallback := newSecurityLogger(t.TempDir() + "/missing/security.log")
+	if fallback == nil || fallback.Writer() != os.Stderr {
+		t.Fatalf("Expected fallback logger writing to stderr")
+	}
+
+	path := t.TempDir() + "/security.log"
+	fileLogger := newSecurityLogger(path)
+	fileLogger.Println("TEST_EVENT")
+	if content, err := os.ReadFile(path); err != nil || !strings.Contains(string(content), "TEST_EVENT") {
+		t.Errorf("Expected event in %s, got %q (%v)", path, content, err)
+	}
+}
+
+// ТЕСТ: Запросы из запрещённых стран получают 403, доверенные IP проходят
+func TestSecurityMiddlewareBlockedCountry(t *testing.T) {
+	_, request := newSecurityTestHandler(t, http.StatusOK, nil)
+
+	setGeoLookup(&fakeCountryLookup{countries: map[string]string{
+		"203.0.113.7": "KP",
+		"203.0.113.8": "DE",
+		"127.0.0.1":   "KP",
+	}})
+	defer setGeoLookup(nil)
+	defer func(previous map[string]bool) { blockedCountries = previous }(blockedCountries)
+	blockedCountries = parseCountryList(" kp, ,IR ")
+
+	if code := request("203.0.113.7", "/goals"); code != http.StatusForbidden {
+		t.Errorf("Expected status %d for blocked country, got %d", http.StatusForbidden, code)
+	}
+	if code := request("203.0.113.8", "/goals"); code != http.StatusOK {
+		t.Errorf("Expected status %d for allowed country, got %d", http.StatusOK, code)
+	}
+	if code := request("127.0.0.1", "/goals"); code != http.StatusOK {
+		t.Errorf("Expected trusted IP to bypass country check, got %d", code)
+	}
+	if got := testutil.ToFloat64(rateLimitRejections.WithLabelValues(rejectReasonBlockedCountry)); got != 1 {
+		t.Errorf("Expected rate_limit_rejections_total{reason=%q} = 1, got %v", rejectReasonBlockedCountry, got)
+	}
+
+	// Без GeoIP список стран ни на что не влияет
+	setGeoLookup(nil)
+	if code := request("203.0.113.7", "/goals"); code != http.StatusOK {
+		t.Errorf("Expected no-op without GeoIP, got %d", code)
+	}
+}
+
+// ТЕСТ: После requestLimit запросов IP блокируется, доверенный IP — никогда
+func TestSecurityMiddlewareRateLimit(t *testing.T) {
+	_, request := newSecurityTestHandler(t, http.StatusOK, nil)
+
+	limit := currentRequestLimit()
+	for i := 0; i < limit; i++ {
+		if code := request("203.0.113.40", "/goals"); code != http.StatusOK {
+			t.Fatalf("Expected request %d within the limit to pass, got %d", i+1, code)
+		}
+	}
+	if code := request("203.0.113.40", "/goals"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d after %d requests, got %d", http.StatusTooManyRequests, limit, code)
+	}
+	if entry, blocked := getBlock("203.0.113.40"); !blocked || entry.Reason != blockReasonRateLimit {
+		t.Errorf("Expected IP to be blocked for %q, got %+v (blocked=%t)", blockReasonRateLimit, entry, blocked)
+	}
+
+	// Доверенный IP не ограничивается
+	for i := 0; i < limit*3; i++ {
+		if code := request("127.0.0.1", "/goals"); code != http.StatusOK {
+			t.Fatalf("Expected trusted IP to pass, got %d on request %d", code, i+1)
+		}
+	}
+	if _, blocked := getBlock("127.0.0.1"); blocked {
+		t.Error("Expected trusted IP never to be blocked")
+	}
+}
+
+// ТЕСТ: Служебные пути не считаются лимитером и не блокируются
+func TestSecurityMiddlewareExemptPaths(t *testing.T) {
+	var logged bytes.Buffer
+	_, request := newSecurityTestHandler(t, http.StatusOK, &logged)
+
+	defer func(previousPaths []string, previousLimit int) {
+		exemptPaths, requestLimit = previousPaths, previousLimit
+	}(exemptPaths, requestLimit)
+	exemptPaths = parseExemptPaths(" /health/, /metrics ,, version")
+	requestLimit = 2
+
+	if len(exemptPaths) != 2 || exemptPaths[0] != "/health" || exemptPaths[1] != "/metrics" {
+		t.Fatalf("Unexpected parsed exempt paths: %v", exemptPaths)
+	}
+
+	// Частые скрейпы не расходуют лимит
+	for i := 0; i < 10; i++ {
+		if code := request("203.0.113.20", "/metrics"); code != http.StatusOK {
+			t.Fatalf("Expected exempt path to pass, got %d on request %d", code, i+1)
+		}
+	}
+	if code := request("203.0.113.20", "/health/live"); code != http.StatusOK {
+		t.Errorf("Expected nested exempt path to pass, got %d", code)
+	}
+	if code := request("203.0.113.20", "/healthz"); code != http.StatusOK {
+		t.Errorf("Expected first counted request to pass, got %d", code)
+	}
+
+	// Обычные пути по-прежнему упираются в лимит, IP блокируется
+	request("203.0.113.20", "/goals")
+	if code := request("203.0.113.20", "/goals"); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected rate limit on regular path, got %d", code)
+	}
+
+	// Заблокированный IP всё равно получает ответ от служебного пути, а обращение попадает в журнал
+	if code := request("203.0.113.20", "/health"); code != http.StatusOK {
+		t.Errorf("Expected exempt path to bypass block, got %d", code)
+	}
+	if !strings.Contains(logged.String(), "EXEMPT_ACCESS | IP: 203.0.113.20 | PATH: /health") {
+		t.Errorf("Expected exempt access of blocked IP in security log, got %q", logged.String())
+	}
+}
+
+// ТЕСТ: Действие при обращении к подозрительному пути задаётся для каждого правила
+func TestSecurityMiddlewareSuspiciousPathActions(t *testing.T) {
+	defer func(rules []suspiciousPathRule, short time.Duration) {
+		suspiciousPaths, suspiciousShortBlock = rules, short
+	}(suspiciousPaths, suspiciousShortBlock)
+	suspiciousPaths = parseSuspiciousPathRules("/wp-login.php=log, /.env=BLOCK, /backup, /bad=ban", suspiciousActionShortBlock)
+	suspiciousShortBlock = 5 * time.Minute
+
+	expectedRules := []suspiciousPathRule{
+		{"/wp-login.php", suspiciousActionLog},
+		{"/.env", suspiciousActionBlock},
+		{"/backup", suspiciousActionShortBlock},
+	}
+	if len(suspiciousPaths) != len(expectedRules) {
+		t.Fatalf("Expected rules %v, got %v", expectedRules, suspiciousPaths)
+	}
+	for i, rule := range expectedRules {
+		if suspiciousPaths[i] != rule {
+			t.Errorf("Rule %d: expected %v, got %v", i, rule, suspiciousPaths[i])
+		}
+	}
+
+	cases := []struct {
+		name      string
+		path      string
+		status    int
+		blocked   bool
+		expiresIn time.Duration
+	}{
+		{"только журнал", "/wp-login.php", http.StatusNotFound, false, 0},
+		{"короткая блокировка", "/backup.zip", http.StatusForbidden, true, 5 * time.Minute},
+		{"полная блокировка", "/.env", http.StatusForbidden, true, blockDuration},
+	}
+	for i, tc := range cases {
+		clock, request := newSecurityTestHandler(t, http.StatusNotFound, nil)
+		ip := fmt.Sprintf("203.0.113.%d", 50+i)
+
+		if code := request(ip, tc.path); code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, code)
+		}
+		if _, blocked := getBlock(ip); blocked != tc.blocked {
+			t.Errorf("%s: expected blocked=%t, got %t", tc.name, tc.blocked, blocked)
+		}
+		if !tc.blocked {
+			continue
+		}
+
+		// Блокировка действует до своего срока и снимается сразу после него
+		clock.Advance(tc.expiresIn - time.Second)
+		if _, blocked := getBlock(ip); !blocked {
+			t.Errorf("%s: expected block to last %s", tc.name, tc.expiresIn)
+		}
+		clock.Advance(time.Second)
+		if _, blocked := getBlock(ip); blocked {
+			t.Errorf("%s: expected block to expire after %s", tc.name, tc.expiresIn)
+		}
+	}
+}
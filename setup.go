@@ -0,0 +1,68 @@
+// ФАЙЛ: setup.go
+// НАЗНАЧЕНИЕ: Инициализация подсистем сервера перед запуском
+// ОСОБЕННОСТИ:
+//   - Весь порядок инициализации собран в одной функции setup, которую вызывает main
+//   - Каждый шаг опирается только на то, что подготовлено предыдущими шагами
+//   - Подключение к БД подменяется в тестах через connectDatabase
+
+package main
+
+// Подключение к базе данных и миграции (в тестах заменяется заглушкой)
+var connectDatabase = SetupDatabase
+
+// ФУНКЦИЯ: setup
+// НАЗНАЧЕНИЕ: Инициализирует подсистемы в обязательном порядке:
+//  1. логгер — в него пишут все остальные шаги;
+//  2. метрики — middleware, алерты и лимитер обновляют счётчики Prometheus;
+//  3. безопасность и алерты — используют логгер и метрики;
+//  4. база данных — пул, реплика, кэш и архивация;
+//  5. обработчики — регистрируются последними, когда готово всё, к чему они обращаются
+func setup() {
+	// ШАГ 1: ЛОГГЕР (main создаёт его раньше ради -check; здесь — на случай прямого вызова)
+	if logger == nil {
+		logger = NewLogger()
+	}
+	logger.InfoLogger.Println("🚀 Сервер запускается...")
+
+	// ШАГ 2: МЕТРИКИ (initMetrics безопасен при повторном вызове)
+	initMetrics()
+	initStatsD()
+	registerMetricsEndpoint()
+	logger.InfoLogger.Println("📊 Система мониторинга активирована")
+
+	// ШАГ 3: БЕЗОПАСНОСТЬ И АЛЕРТЫ
+	initSecurity()
+	initGeoIP()
+	initAdmin()
+	initAlerts()
+	logger.InfoLogger.Println("🛡️ Система безопасности активирована")
+
+	// ШАГ 4: ПОДКЛЮЧЕНИЕ К БАЗЕ ДАННЫХ
+	initGoalsTable()
+	connectDatabase()
+	initGoalStore()
+	initReadReplica()
+	initReadCache()
+	initArchiver()
+	initIdleReaper()
+	initAlertAudit()
+	logger.InfoLogger.Println("🗄️ Подключение к базе данных настроено")
+
+	// ШАГ 5: ОБРАБОТЧИКИ С MIDDLEWARE
+	initMiddleware()
+	initCORS()
+	initNonce()
+	initIdempotency()
+	initRequestDecoding()
+	initSchemaValidation()
+	initResponseFormat()
+	initGoalQuota()
+	initBulkInsert()
+	initGoalDefaults()
+	initGoalLengthLimits()
+	initStats()
+	registerHandlers()
+	registerAdminHandlers()
+	registerPprofHandlers()
+	logger.InfoLogger.Println("🔌 Обработчики запросов зарегистрированы")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// ТЕСТ: setup инициализирует подсистемы и регистрирует маршруты без настоящей БД
+func TestSetupOrder(t *testing.T) {
+	previousMux, previousConnect, previousLogPath := appMux, connectDatabase, securityLogPath
+	t.Cleanup(func() {
+		stopBackgroundTasks()
+		appMux, connectDatabase, securityLogPath = previousMux, previousConnect, previousLogPath
+	})
+
+	appMux = http.NewServeMux()
+	securityLogPath = filepath.Join(t.TempDir(), "security.log")
+
+	// К моменту подключения к БД метрики и защита уже должны быть готовы
+	connected := false
+	connectDatabase = func() {
+		connected = true
+		if requestDuration == nil {
+			t.Error("Expected metrics to be initialized before the database")
+		}
+		if securityLogger == nil {
+			t.Error("Expected security to be initialized before the database")
+		}
+	}
+
+	setup()
+
+	if !connected {
+		t.Fatal("Expected setup to connect to the database")
+	}
+	for _, path := range []string{"/metrics", "/goals", "/admin/config"} {
+		if _, pattern := appMux.Handler(httptest.NewRequest("GET", path, nil)); pattern == "" || pattern == "/" {
+			t.Errorf("Expected %s to be registered, got pattern %q", path, pattern)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// ФАЙЛ: shutdown.go
+// НАЗНАЧЕНИЕ: Плавная остановка сервера
+// ОСОБЕННОСТИ:
+//   - Новые соединения перестают приниматься сразу
+//   - Активные запросы дорабатывают не дольше SHUTDOWN_TIMEOUT
+//   - По истечении таймаута оставшиеся соединения закрываются принудительно
+//   - Последним шагом сбрасывается и закрывается файл журнала
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ФУНКЦИЯ: shutdownServer
+// НАЗНАЧЕНИЕ: Останавливает сервер с ожиданием активных запросов
+func shutdownServer(server *http.Server) {
+	// По умолчанию укладываемся в 30-секундное окно Heroku с запасом
+	timeout := getEnvDuration("SHUTDOWN_TIMEOUT", 25*time.Second)
+	logger.InfoLogger.Printf("⏳ Ожидаем завершения активных запросов (%d шт.), не дольше %s",
+		inFlightRequests.Load(), timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// ШАГ 1: ПЕРЕСТАЁМ ПРИНИМАТЬ СОЕДИНЕНИЯ И ЖДЁМ АКТИВНЫЕ ЗАПРОСЫ
+	if err := server.Shutdown(ctx); err != nil {
+		// ШАГ 2: ТАЙМАУТ ИСТЁК — ЗАКРЫВАЕМ ОСТАВШИЕСЯ СОЕДИНЕНИЯ
+		logger.InfoLogger.Printf("⚠️ Таймаут остановки истёк, принудительно закрываем соединения (активных запросов: %d)",
+			inFlightRequests.Load())
+		server.Close()
+	}
+
+	// ШАГ 3: ОСТАНАВЛИВАЕМ ФОНОВЫЕ ЦИКЛЫ
+	stopBackgroundTasks()
+
+	// ШАГ 4: ЗАКРЫВАЕМ ПУЛЫ СОЕДИНЕНИЙ С БД (основной, реплику и отдельную базу журнала алертов)
+	if dbPool != nil {
+		dbPool.Close()
+	}
+	if readPool != nil {
+		readPool.Close()
+	}
+	if alertAuditPool != nil && alertAuditPool != dbPool {
+		alertAuditPool.Close()
+	}
+
+	logger.InfoLogger.Println("👋 Сервер остановлен")
+
+	// ШАГ 5: СБРАСЫВАЕМ ЖУРНАЛ НА ДИСК
+	if err := logger.Close(); err != nil {
+		logger.LogError(err, "Ошибка закрытия файла логов")
+	}
+}
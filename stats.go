@@ -0,0 +1,101 @@
+// ФАЙЛ: stats.go
+// НАЗНАЧЕНИЕ: Сводная статистика по целям для дашборда (GET /goals/stats)
+// ОСОБЕННОСТИ:
+//   - Все показатели считаются одним SQL-запросом с агрегатными функциями (GoalStore.Stats)
+//   - Зарплаты в разных валютах несравнимы, поэтому средняя/мин/макс считаются
+//     по каждой валюте отдельно (by_currency); количества — по всем целям
+//   - Пустая таблица даёт нули и пустой by_currency, а не null
+//   - Результат кэшируется на STATS_CACHE_TTL (по умолчанию 10 секунд)
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// СТРУКТУРА СТАТИСТИКИ
+type goalStats struct {
+	Count          int64               `json:"count"`
+	CompletedCount int64               `json:"completed_count"`
+	OpenCount      int64               `json:"open_count"`
+	ByCurrency     []currencyGoalStats `json:"by_currency"`
+}
+
+// СТАТИСТИКА ЗАРПЛАТ В ОДНОЙ ВАЛЮТЕ
+type currencyGoalStats struct {
+	Currency  string  `json:"currency"`
+	Count     int64   `json:"count"`
+	AvgSalary Decimal `json:"avg_salary_target"`
+	MinSalary Decimal `json:"min_salary_target"`
+	MaxSalary Decimal `json:"max_salary_target"`
+}
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ КЭША СТАТИСТИКИ
+var (
+	cachedStats   goalStats
+	cachedStatsAt time.Time
+	statsMutex    sync.Mutex
+	// Сколько отдавать статистику из кэша (STATS_CACHE_TTL)
+	statsCacheTTL = 10 * time.Second
+)
+
+// ИНИЦИАЛИЗАЦИЯ СТАТИСТИКИ
+func initStats() {
+	statsCacheTTL = getEnvDuration("STATS_CACHE_TTL", 10*time.Second)
+}
+
+// Запрос статистики по валютам: средняя округляется до копеек (центов)
+const goalStatsQuery = `SELECT
+	currency,
+	COUNT(*),
+	ROUND(AVG(salary_target), 2),
+	MIN(salary_target),
+	MAX(salary_target),
+	COUNT(*) FILTER (WHERE completed_at IS NOT NULL),
+	COUNT(*) FILTER (WHERE completed_at IS NULL)
+FROM {goals}
+GROUP BY currency
+ORDER BY currency`
+
+// ОБРАБОТЧИК: GET /goals/stats
+func goalStatsHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: СВЕЖИЙ РЕЗУЛЬТАТ ИЗ КЭША
+	statsMutex.Lock()
+	if !cachedStatsAt.IsZero() && time.Since(cachedStatsAt) < statsCacheTTL {
+		stats := cachedStats
+		statsMutex.Unlock()
+		writeJSON(w, r, http.StatusOK, stats)
+		return
+	}
+	statsMutex.Unlock()
+
+	// ШАГ 3: АГРЕГАЦИЯ В БД
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	stats, err := goalStore.Stats(ctx)
+	if err != nil {
+		logger.LogError(err, "Ошибка агрегации в goalStatsHandler")
+		writeDBError(w, r, err, "Query error")
+		return
+	}
+
+	statsMutex.Lock()
+	cachedStats, cachedStatsAt = stats, time.Now()
+	statsMutex.Unlock()
+
+	// ШАГ 4: ОТПРАВКА ОТВЕТА
+	writeJSON(w, r, http.StatusOK, stats)
+}
@@ -0,0 +1,133 @@
+// ФАЙЛ: statsd.go
+// НАЗНАЧЕНИЕ: Отправка метрик запросов в StatsD (в дополнение к Prometheus)
+// ОСОБЕННОСТИ:
+//   - Включается переменной окружения STATSD_ADDR (host:port)
+//   - Метрики копятся в буфере и уходят пачками по UDP
+//   - Отправка никогда не блокирует обработку запроса
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// Максимальный размер UDP-пакета, безопасный для большинства сетей
+	statsdMaxPacketSize = 1432
+	// Как часто отправлять накопленные метрики
+	statsdFlushInterval = 1 * time.Second
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ ДЛЯ STATSD
+var (
+	// Клиент StatsD (nil, если STATSD_ADDR не задан)
+	statsdClient *statsdEmitter
+)
+
+// СТРУКТУРА: Отправитель метрик в StatsD
+type statsdEmitter struct {
+	conn   net.Conn
+	prefix string
+	lines  chan string
+}
+
+// ИНИЦИАЛИЗАЦИЯ STATSD
+func initStatsD() {
+	addr := os.Getenv("STATSD_ADDR")
+	if addr == "" {
+		return
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		logger.LogError(err, "Не удалось подключиться к StatsD")
+		return
+	}
+
+	prefix := os.Getenv("STATSD_PREFIX")
+	if prefix == "" {
+		prefix = "go_app"
+	}
+
+	statsdClient = &statsdEmitter{
+		conn:   conn,
+		prefix: prefix,
+		lines:  make(chan string, 1000),
+	}
+	startBackground("statsd", statsdClient.run)
+
+	logger.InfoLogger.Printf("📈 Отправка метрик в StatsD активирована: %s", addr)
+}
+
+// ФУНКЦИЯ: recordStatsDRequest
+// НАЗНАЧЕНИЕ: Записывает счётчик и время запроса (те же метрики, что и в Prometheus)
+func recordStatsDRequest(method, endpoint, status string, duration time.Duration) {
+	if statsdClient == nil {
+		return
+	}
+
+	name := statsdClient.prefix + "." + sanitizeStatsDName(method) + "." + sanitizeStatsDName(endpoint)
+	statsdClient.send(fmt.Sprintf("%s.http_requests_total.%s:1|c", name, status))
+	statsdClient.send(fmt.Sprintf("%s.http_request_duration:%.3f|ms", name, float64(duration)/float64(time.Millisecond)))
+}
+
+// Кладём строку в очередь; при переполнении метрика отбрасывается
+func (s *statsdEmitter) send(line string) {
+	select {
+	case s.lines <- line:
+	default:
+	}
+}
+
+// Фоновый цикл: собираем строки в пакеты и отправляем
+// При остановке отправляем накопленное и закрываем соединение
+func (s *statsdEmitter) run(ctx context.Context) {
+	ticker := time.NewTicker(statsdFlushInterval)
+	defer ticker.Stop()
+
+	var packet bytes.Buffer
+	flush := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		if _, err := s.conn.Write(packet.Bytes()); err != nil {
+			logger.InfoLogger.Printf("⚠️ Ошибка отправки метрик в StatsD: %v", err)
+		}
+		packet.Reset()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			s.conn.Close()
+			return
+		case line := <-s.lines:
+			// Не даём пакету превысить допустимый размер
+			if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacketSize {
+				flush()
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Приводим путь к допустимому имени метрики: /goals/11 → goals_11
+func sanitizeStatsDName(value string) string {
+	value = strings.Trim(value, "/")
+	if value == "" {
+		return "root"
+	}
+	return strings.NewReplacer("/", "_", ".", "_", ":", "_", "|", "_", "@", "_", " ", "_").Replace(value)
+}
@@ -0,0 +1,382 @@
+// ФАЙЛ: store.go
+// НАЗНАЧЕНИЕ: Хранилище целей — интерфейс GoalStore и его реализация на PostgreSQL
+// ОСОБЕННОСТИ:
+//   - Обработчики работают с целями только через goalStore, а не через dbPool напрямую,
+//     поэтому в тестах хранилище подменяется на in-memory (см. newTestServer)
+//   - Отсутствие записи — ошибка errGoalNotFound, обработчики превращают её в 404
+//   - Повтор при обрыве соединения выполняется внутри реализации: чтение повторяется
+//     при любом обрыве (retryableQuery), запись — только если запрос не ушёл на сервер (withConnRetry)
+//   - Чтение (List, Each, Get, Count, Stats, Timelines) идёт через readDB: с реплики, если она настроена.
+//     Get тоже читает с реплики намеренно: свежесть после записи обеспечивает READ_AFTER_WRITE_WINDOW
+//   - Строка, которую не удалось прочитать, пропускается (STRICT_SCAN=false, по умолчанию):
+//     остальные цели возвращаются вместе с ошибкой *skippedRowsError
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Ошибка: цели с таким ID нет
+var errGoalNotFound = errors.New("цель не найдена")
+
+// Ошибка: цель изменилась после того, как клиент её прочитал (If-Match не совпал)
+var errGoalChanged = errors.New("цель изменилась с момента чтения")
+
+// Прерывать ли чтение списка на первой строке с ошибкой (STRICT_SCAN)
+var strictScan = false
+
+// ОШИБКА: ЧАСТЬ СТРОК ПРОПУЩЕНА
+// Возвращается вместе с остальными целями, когда строки с ошибками пропущены
+type skippedRowsError struct {
+	Count int
+}
+
+func (e *skippedRowsError) Error() string {
+	return fmt.Sprintf("пропущено строк с ошибками чтения: %d", e.Count)
+}
+
+// ИНИЦИАЛИЗАЦИЯ ХРАНИЛИЩА
+func initGoalStore() {
+	strictScan = getEnvBool("STRICT_SCAN", false)
+	if strictScan {
+		logger.InfoLogger.Println("🧱 Строка с ошибкой чтения прерывает запрос списка целей (STRICT_SCAN)")
+	}
+}
+
+// ИНТЕРФЕЙС ХРАНИЛИЩА ЦЕЛЕЙ
+type GoalStore interface {
+	// List возвращает цели по фильтру, старые первыми
+	List(ctx context.Context, filter goalFilter) ([]Goal, error)
+	// Each вызывает fn для каждой цели по фильтру, не загружая весь список в память
+	Each(ctx context.Context, filter goalFilter, fn func(Goal) error) error
+	// Get возвращает цель по ID (errGoalNotFound, если её нет)
+	Get(ctx context.Context, id int) (Goal, error)
+	// Count возвращает количество целей по фильтру
+	Count(ctx context.Context, filter goalFilter) (int64, error)
+	// Stats возвращает сводную статистику; зарплаты — по каждой валюте, по алфавиту валют
+	Stats(ctx context.Context) (goalStats, error)
+	// Timelines возвращает различные сроки целей по алфавиту
+	Timelines(ctx context.Context) ([]string, error)
+	// Create сохраняет цель и заполняет ID и CreatedAt (errGoalLimitReached при исчерпанном лимите)
+	Create(ctx context.Context, goal *Goal) error
+	// CreateMany сохраняет цели одной транзакцией: либо все, либо ни одной
+	CreateMany(ctx context.Context, goals []Goal) error
+	// Duplicate создаёт копию цели с суффиксом " (copy)" и текущим временем создания
+	// (errGoalLimitReached при исчерпанном лимите)
+	Duplicate(ctx context.Context, id int) (Goal, error)
+	// Update меняет поля цели, кроме created_at, и возвращает обновлённую цель.
+	// precondition (если задан) получает текущую цель и может отменить обновление ошибкой
+	Update(ctx context.Context, id int, goal Goal, precondition func(current Goal) error) (Goal, error)
+	// SetCreatedAt исправляет время создания и возвращает обновлённую цель
+	SetCreatedAt(ctx context.Context, id int, createdAt time.Time) (Goal, error)
+	// ToggleCompleted атомарно отмечает цель выполненной или снимает отметку
+	ToggleCompleted(ctx context.Context, id int) (goalCompletion, error)
+	// Delete удаляет цель
+	Delete(ctx context.Context, id int) error
+	// DeleteMany удаляет цели по списку ID и возвращает количество удалённых
+	DeleteMany(ctx context.Context, ids []int) (int64, error)
+}
+
+// СОСТОЯНИЕ ВЫПОЛНЕНИЯ ЦЕЛИ
+// Выполненной цель считается, когда заполнено completed_at
+type goalCompletion struct {
+	ID          int        `json:"id"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// Хранилище, которым пользуются обработчики
+var goalStore GoalStore = pgGoalStore{}
+
+// РЕАЛИЗАЦИЯ НА POSTGRESQL (общий пул dbPool, чтение — через readDB)
+type pgGoalStore struct{}
+
+// Колонки цели в порядке полей Goal (для SELECT и RETURNING)
+const goalColumns = "id, goal, timeline, salary_target, currency, tags, created_at"
+
+// Сканирование строки в цель (pgx.Row и pgx.Rows)
+func scanGoal(row pgx.Row, g *Goal) error {
+	return row.Scan(goalScanTargets(g)...)
+}
+
+// Поля цели в порядке goalColumns
+func goalScanTargets(g *Goal) []interface{} {
+	return []interface{}{&g.ID, &g.Goal, &g.Timeline, &g.SalaryTarget, &g.Currency, &g.Tags, &g.CreatedAt}
+}
+
+// ФУНКЦИЯ: scanGoalLenient
+// НАЗНАЧЕНИЕ: Сканирует строку по колонкам, не закрывая курсор при ошибке
+// rows.Scan при ошибке закрывает rows, и остальные строки уже не прочитать,
+// поэтому значения разбираются через карту типов соединения напрямую
+func scanGoalLenient(rows pgx.Rows, g *Goal) error {
+	fields, values := rows.FieldDescriptions(), rows.RawValues()
+	typeMap := rows.Conn().TypeMap()
+	for i, dst := range goalScanTargets(g) {
+		if err := typeMap.Scan(fields[i].DataTypeOID, fields[i].Format, values[i], dst); err != nil {
+			return pgx.ScanArgError{ColumnIndex: i, FieldName: fields[i].Name, Err: err}
+		}
+	}
+	return nil
+}
+
+func (pgGoalStore) List(ctx context.Context, filter goalFilter) ([]Goal, error) {
+	// Список собирается целиком, поэтому при обрыве его можно перечитать с начала
+	query, args := listGoalsQuery(filter)
+	var goals []Goal
+	err := retryableQuery("GoalStore.List", func() error {
+		// Пустой, а не nil срез: пустой список кодируется как [], а не null
+		goals = []Goal{}
+		return eachGoal(ctx, query, args, func(g Goal) error {
+			goals = append(goals, g)
+			return nil
+		})
+	})
+	return goals, err
+}
+
+func (pgGoalStore) Each(ctx context.Context, filter goalFilter, fn func(Goal) error) error {
+	// Повторяем при обрыве, только пока ни одна цель не передана в fn:
+	// иначе вызывающий (например, потоковый экспорт) получил бы те же цели дважды
+	query, args := listGoalsQuery(filter)
+	delivered := false
+	return retryOnce("GoalStore.Each", func() error {
+		return eachGoal(ctx, query, args, func(g Goal) error {
+			delivered = true
+			return fn(g)
+		})
+	}, func(err error) bool {
+		return !delivered && isConnClosedError(err)
+	})
+}
+
+// Один проход запроса списка: каждая прочитанная цель передаётся в fn
+func eachGoal(ctx context.Context, query string, args []interface{}, fn func(Goal) error) error {
+	rows, err := readDB(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close() // Закрываем курсор после использования
+
+	skipped := 0
+	for rows.Next() {
+		var g Goal
+		if strictScan {
+			if err := scanGoal(rows, &g); err != nil {
+				return err
+			}
+		} else if err := scanGoalLenient(rows, &g); err != nil {
+			// id читается первым, поэтому почти всегда известен
+			logger.LogError(err, fmt.Sprintf("⚠️ Строка цели id=%d пропущена", g.ID))
+			skipped++
+			continue
+		}
+		if err := fn(g); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if skipped > 0 {
+		return &skippedRowsError{Count: skipped}
+	}
+	return nil
+}
+
+func (pgGoalStore) Get(ctx context.Context, id int) (Goal, error) {
+	var goal Goal
+	query := "SELECT " + goalColumns + " FROM " + goalsTable + " WHERE id = $1"
+	err := retryableQuery("GoalStore.Get", func() error {
+		return scanGoal(readDB(ctx).QueryRow(ctx, query, id), &goal)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Goal{}, errGoalNotFound
+	}
+	return goal, err
+}
+
+func (pgGoalStore) Count(ctx context.Context, filter goalFilter) (int64, error) {
+	where, args := filter.whereClause()
+	var count int64
+	err := retryableQuery("GoalStore.Count", func() error {
+		return readDB(ctx).QueryRow(ctx, "SELECT COUNT(*) FROM "+goalsTable+where, args...).Scan(&count)
+	})
+	return count, err
+}
+
+func (pgGoalStore) Stats(ctx context.Context) (goalStats, error) {
+	var stats goalStats
+	err := retryableQuery("GoalStore.Stats", func() error {
+		rows, err := readDB(ctx).Query(ctx, withTables(goalStatsQuery))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Пустой срез, а не nil: пустая таблица даёт [], а не null
+		stats = goalStats{ByCurrency: []currencyGoalStats{}}
+		for rows.Next() {
+			var c currencyGoalStats
+			var completed, open int64
+			if err := rows.Scan(&c.Currency, &c.Count, &c.AvgSalary, &c.MinSalary, &c.MaxSalary, &completed, &open); err != nil {
+				return err
+			}
+			stats.Count += c.Count
+			stats.CompletedCount += completed
+			stats.OpenCount += open
+			stats.ByCurrency = append(stats.ByCurrency, c)
+		}
+		return rows.Err()
+	})
+	return stats, err
+}
+
+func (pgGoalStore) Timelines(ctx context.Context) ([]string, error) {
+	var timelines []string
+	err := retryableQuery("GoalStore.Timelines", func() error {
+		rows, err := readDB(ctx).Query(ctx, "SELECT DISTINCT timeline FROM "+goalsTable+" ORDER BY timeline")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Пустой срез, а не nil: пустая таблица даёт [], а не null
+		timelines = []string{}
+		for rows.Next() {
+			var timeline string
+			if err := rows.Scan(&timeline); err != nil {
+				return err
+			}
+			timelines = append(timelines, timeline)
+		}
+		return rows.Err()
+	})
+	return timelines, err
+}
+
+func (pgGoalStore) Create(ctx context.Context, goal *Goal) error {
+	// Вставка с проверкой лимита MAX_GOALS_PER_USER (см. quota.go)
+	return withConnRetry("GoalStore.Create", func() error {
+		return insertGoal(ctx, goal)
+	})
+}
+
+func (pgGoalStore) CreateMany(ctx context.Context, goals []Goal) error {
+	return withConnRetry("GoalStore.CreateMany", func() error {
+		return insertGoals(ctx, goals)
+	})
+}
+
+func (pgGoalStore) Duplicate(ctx context.Context, id int) (Goal, error) {
+	// Копирование одним запросом: если исходной цели нет, INSERT ... SELECT не вставит ни одной строки
+	var goal Goal
+	query := withTables(`INSERT INTO {goals} (goal, timeline, salary_target, currency, tags, created_at)
+		SELECT goal || ' (copy)', timeline, salary_target, currency, tags, NOW() FROM {goals} WHERE id = $1
+		RETURNING ` + goalColumns)
+	duplicate := func(q goalRowQuerier) error {
+		return scanGoal(q.QueryRow(ctx, query, id), &goal)
+	}
+	err := withConnRetry("GoalStore.Duplicate", func() error {
+		if maxGoalsPerUser <= 0 {
+			return duplicate(dbPool)
+		}
+		// Копия — тоже новая цель: проверка лимита и вставка под одной advisory-блокировкой (см. quota.go)
+		return pgx.BeginFunc(ctx, dbPool, func(tx pgx.Tx) error {
+			if err := checkGoalQuota(ctx, tx, 1); err != nil {
+				return err
+			}
+			return duplicate(tx)
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Goal{}, errGoalNotFound
+	}
+	return goal, err
+}
+
+func (pgGoalStore) Update(ctx context.Context, id int, goal Goal, precondition func(current Goal) error) (Goal, error) {
+	// WHERE id = $6 использует параметризованный запрос для безопасности
+	query := "UPDATE " + goalsTable + " SET goal = $1, timeline = $2, salary_target = $3, currency = $4, tags = $5 WHERE id = $6 RETURNING " + goalColumns
+	args := []interface{}{goal.Goal, goal.Timeline, goal.SalaryTarget, goal.Currency, goal.Tags, id}
+	var updated Goal
+	err := withConnRetry("GoalStore.Update", func() error {
+		if precondition == nil {
+			return scanGoal(dbPool.QueryRow(ctx, query, args...), &updated)
+		}
+
+		// FOR UPDATE держит строку до конца транзакции: между проверкой и записью её никто не изменит
+		return pgx.BeginFunc(ctx, dbPool, func(tx pgx.Tx) error {
+			var current Goal
+			if err := scanGoal(tx.QueryRow(ctx, "SELECT "+goalColumns+" FROM "+goalsTable+" WHERE id = $1 FOR UPDATE", id), &current); err != nil {
+				return err
+			}
+			if err := precondition(current); err != nil {
+				return err
+			}
+			return scanGoal(tx.QueryRow(ctx, query, args...), &updated)
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Goal{}, errGoalNotFound
+	}
+	return updated, err
+}
+
+func (pgGoalStore) SetCreatedAt(ctx context.Context, id int, createdAt time.Time) (Goal, error) {
+	var goal Goal
+	query := "UPDATE " + goalsTable + " SET created_at = $1 WHERE id = $2 RETURNING " + goalColumns
+	err := withConnRetry("GoalStore.SetCreatedAt", func() error {
+		return scanGoal(dbPool.QueryRow(ctx, query, createdAt, id), &goal)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Goal{}, errGoalNotFound
+	}
+	return goal, err
+}
+
+func (pgGoalStore) ToggleCompleted(ctx context.Context, id int) (goalCompletion, error) {
+	// Переключение одним UPDATE: параллельные запросы не прочитают одно и то же старое состояние
+	state := goalCompletion{ID: id}
+	query := "UPDATE " + goalsTable + ` SET completed_at = CASE WHEN completed_at IS NULL THEN NOW() END
+		WHERE id = $1 RETURNING completed_at`
+	err := withConnRetry("GoalStore.ToggleCompleted", func() error {
+		return dbPool.QueryRow(ctx, query, id).Scan(&state.CompletedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return goalCompletion{}, errGoalNotFound
+	}
+	state.Completed = state.CompletedAt != nil
+	return state, err
+}
+
+func (pgGoalStore) Delete(ctx context.Context, id int) error {
+	// Используем $1 для защиты от SQL-инъекций
+	var result pgconn.CommandTag
+	err := withConnRetry("GoalStore.Delete", func() error {
+		var err error
+		result, err = dbPool.Exec(ctx, "DELETE FROM "+goalsTable+" WHERE id = $1", id)
+		return err
+	})
+	if err == nil && result.RowsAffected() == 0 {
+		return errGoalNotFound
+	}
+	return err
+}
+
+func (pgGoalStore) DeleteMany(ctx context.Context, ids []int) (int64, error) {
+	// ANY($1) принимает массив ID, поэтому хватает одного обращения к БД
+	var result pgconn.CommandTag
+	err := withConnRetry("GoalStore.DeleteMany", func() error {
+		var err error
+		result, err = dbPool.Exec(ctx, "DELETE FROM "+goalsTable+" WHERE id = ANY($1)", ids)
+		return err
+	})
+	return result.RowsAffected(), err
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Запрос к тестовому серверу с JSON-телом; возвращает статус и тело ответа
+func doJSON(t *testing.T, server *httptest.Server, method, path string, body interface{}) (int, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, data
+}
+
+// ТЕСТ: Полный цикл CRUD через HTTP без базы данных
+func TestGoalCRUDInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	// Создание
+	status, body := doJSON(t, server, "POST", "/goals", Goal{Goal: "Learn Go", Timeline: "2026", SalaryTarget: "1500.50"})
+	if status != http.StatusCreated {
+		t.Fatalf("Create: expected %d, got %d (%s)", http.StatusCreated, status, body)
+	}
+	var created Goal
+	json.Unmarshal(body, &created)
+	if created.ID == 0 || created.Currency != defaultCurrency || created.CreatedAt.IsZero() {
+		t.Fatalf("Create: unexpected goal %+v", created)
+	}
+	path := "/goals/" + strconv.Itoa(created.ID)
+
+	// Список и количество
+	status, body = doJSON(t, server, "GET", "/goals", nil)
+	var goals []Goal
+	json.Unmarshal(body, &goals)
+	if status != http.StatusOK || len(goals) != 1 || goals[0].SalaryTarget != "1500.50" {
+		t.Fatalf("List: expected the created goal, got %d %s", status, body)
+	}
+	if status, body = doJSON(t, server, "GET", "/goals/count?q=learn", nil); string(bytes.TrimSpace(body)) != `{"count":1}` {
+		t.Errorf("Count: expected 1, got %d %s", status, body)
+	}
+
+	// Обновление: created_at не меняется
+	status, _ = doJSON(t, server, "PUT", path, Goal{Goal: "Master Go", Timeline: "2027", CreatedAt: time.Unix(0, 0)})
+	if status != http.StatusOK {
+		t.Fatalf("Update: expected %d, got %d", http.StatusOK, status)
+	}
+	_, body = doJSON(t, server, "GET", "/goals", nil)
+	json.Unmarshal(body, &goals)
+	if goals[0].Goal != "Master Go" || !goals[0].CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Update: unexpected goal %+v", goals[0])
+	}
+
+	// Копирование
+	status, body = doJSON(t, server, "POST", path+"/duplicate", nil)
+	var copied Goal
+	json.Unmarshal(body, &copied)
+	if status != http.StatusCreated || copied.Goal != "Master Go (copy)" || copied.ID == created.ID {
+		t.Errorf("Duplicate: unexpected response %d %s", status, body)
+	}
+
+	// Удаление и повторное удаление
+	if status, _ = doJSON(t, server, "DELETE", path, nil); status != http.StatusNoContent {
+		t.Errorf("Delete: expected %d, got %d", http.StatusNoContent, status)
+	}
+	if status, _ = doJSON(t, server, "DELETE", path, nil); status != http.StatusNotFound {
+		t.Errorf("Second delete: expected %d, got %d", http.StatusNotFound, status)
+	}
+	if status, _ = doJSON(t, server, "PUT", path, Goal{Goal: "x", Timeline: "y"}); status != http.StatusNotFound {
+		t.Errorf("Update of deleted goal: expected %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+// ТЕСТ: Сумма с ведущими нулями нормализуется и возвращается валидным JSON
+func TestGoalLeadingZeroSalaryInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	status, body := doJSON(t, server, "POST", "/goals", map[string]string{
+		"goal": "Learn Go", "timeline": "2026", "salary_target_rub_per_hour": "0150",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Create: expected %d, got %d (%s)", http.StatusCreated, status, body)
+	}
+	var created Goal
+	if err := json.Unmarshal(body, &created); err != nil || created.SalaryTarget != "150" {
+		t.Fatalf("Create: expected salary 150, got %s (%v)", body, err)
+	}
+
+	status, body = doJSON(t, server, "GET", "/goals/"+strconv.Itoa(created.ID), nil)
+	var fetched Goal
+	if err := json.Unmarshal(body, &fetched); status != http.StatusOK || err != nil || fetched.SalaryTarget != "150" {
+		t.Errorf("Get: expected salary 150, got %d %s (%v)", status, body, err)
+	}
+}
+
+// ТЕСТ: Пакетное удаление считает только существующие цели
+func TestBatchDeleteInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	var ids []int
+	for i := 0; i < 2; i++ {
+		_, body := doJSON(t, server, "POST", "/goals", Goal{Goal: "Batch", Timeline: "2026"})
+		var g Goal
+		json.Unmarshal(body, &g)
+		ids = append(ids, g.ID)
+	}
+
+	status, body := doJSON(t, server, "POST", "/goals/delete", batchDeleteRequest{IDs: append(ids, 999999)})
+	if status != http.StatusOK || string(bytes.TrimSpace(body)) != `{"deleted":2}` {
+		t.Errorf("Expected 2 deleted, got %d %s", status, body)
+	}
+}
+
+// ТЕСТ: Лимит количества целей даёт 409
+func TestCreateGoalLimitInMemory(t *testing.T) {
+	server := newTestServer(t)
+	defer func(previous int) { maxGoalsPerUser = previous }(maxGoalsPerUser)
+	maxGoalsPerUser = 1
+
+	status, body := doJSON(t, server, "POST", "/goals", Goal{Goal: "First", Timeline: "2026"})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected first goal to be created, got %d", status)
+	}
+	if status, _ := doJSON(t, server, "POST", "/goals", Goal{Goal: "Second", Timeline: "2026"}); status != http.StatusConflict {
+		t.Errorf("Expected %d when limit is reached, got %d", http.StatusConflict, status)
+	}
+
+	// Копия тоже новая цель и в лимит не помещается
+	var first Goal
+	json.Unmarshal(body, &first)
+	if status, _ := doJSON(t, server, "POST", "/goals/"+strconv.Itoa(first.ID)+"/duplicate", nil); status != http.StatusConflict {
+		t.Errorf("Expected %d when duplicating over the limit, got %d", http.StatusConflict, status)
+	}
+	if status, body := doJSON(t, server, "GET", "/goals/count", nil); string(bytes.TrimSpace(body)) != `{"count":1}` {
+		t.Errorf("Expected 1 goal after rejected duplicate, got %d %s", status, body)
+	}
+}
+
+// ТЕСТ: Шаблоны маршрутов /goals различают методы и пути
+func TestGoalsRoutingInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	cases := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{"GET", "/goals", http.StatusOK},
+		{"PATCH", "/goals", http.StatusMethodNotAllowed},
+		{"GET", "/goals/count", http.StatusOK},
+		{"GET", "/goals/1", http.StatusNotFound},
+		{"POST", "/goals/1", http.StatusMethodNotAllowed},
+		{"GET", "/goals/delete", http.StatusMethodNotAllowed},
+		{"POST", "/goals/stats", http.StatusMethodNotAllowed},
+		{"PUT", "/goals/abc", http.StatusBadRequest},
+		{"GET", "/goals/1/duplicate", http.StatusMethodNotAllowed},
+		{"GET", "/goals/", http.StatusNotFound},
+		{"DELETE", "/goals/1", http.StatusNotFound},
+		{"POST", "/goals/1/duplicate", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if status, _ := doJSON(t, server, tc.method, tc.path, nil); status != tc.expected {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.expected, status)
+		}
+	}
+}
+
+// ТЕСТ: Неверный ID — 400, а вложенный путь после /goals/{id} — 404
+func TestGoalsTrailingSegmentInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	cases := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{"PUT", "/goals/abc", http.StatusBadRequest},
+		{"DELETE", "/goals/abc", http.StatusBadRequest},
+		{"PUT", "/goals/11/extra", http.StatusNotFound},
+		{"DELETE", "/goals/11/extra/path", http.StatusNotFound},
+		{"PATCH", "/goals/11/extra", http.StatusNotFound},
+		{"POST", "/goals/11/duplicate/extra", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if status, _ := doJSON(t, server, tc.method, tc.path, Goal{Goal: "x", Timeline: "y"}); status != tc.expected {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.expected, status)
+		}
+	}
+}
+
+// ТЕСТ: Двойное переключение возвращает цель в исходное состояние
+func TestToggleGoalInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	_, body := doJSON(t, server, "POST", "/goals", Goal{Goal: "Toggle me", Timeline: "2026"})
+	var created Goal
+	json.Unmarshal(body, &created)
+	path := "/goals/" + strconv.Itoa(created.ID) + "/toggle"
+
+	for i, expected := range []bool{true, false} {
+		status, body := doJSON(t, server, "POST", path, nil)
+		var state goalCompletion
+		json.Unmarshal(body, &state)
+		if status != http.StatusOK || state.Completed != expected || (state.CompletedAt != nil) != expected {
+			t.Errorf("Toggle #%d: expected completed=%t, got %d %s", i+1, expected, status, body)
+		}
+	}
+
+	if status, _ := doJSON(t, server, "POST", "/goals/999999/toggle", nil); status != http.StatusNotFound {
+		t.Errorf("Expected %d for missing goal, got %d", http.StatusNotFound, status)
+	}
+}
+
+// ТЕСТ: Пустой список целей кодируется как [], а не null
+func TestEmptyGoalListInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	status, body := doJSON(t, server, "GET", "/goals", nil)
+	if status != http.StatusOK || string(bytes.TrimSpace(body)) != "[]" {
+		t.Errorf("Expected 200 with body [], got %d %q", status, body)
+	}
+
+	defer func(previous bool) { responseEnvelope = previous }(responseEnvelope)
+	responseEnvelope = true
+
+	status, body = doJSON(t, server, "GET", "/goals", nil)
+	if status != http.StatusOK || !bytes.Contains(body, []byte(`"data":[]`)) {
+		t.Errorf("Expected envelope with empty data array, got %d %q", status, body)
+	}
+}
+
+// ТЕСТ: Статистика: количества по всем целям, зарплаты — по каждой валюте отдельно
+func TestGoalStatsInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	// Пустое хранилище: нули и [], а не null
+	status, body := doJSON(t, server, "GET", "/goals/stats", nil)
+	if status != http.StatusOK || !bytes.Contains(body, []byte(`"by_currency":[]`)) {
+		t.Fatalf("Expected empty stats with by_currency [], got %d %s", status, body)
+	}
+	resetCachedStats()
+
+	var ids []int
+	for _, goal := range []Goal{
+		{Goal: "a", Timeline: "t", SalaryTarget: "100", Currency: "RUB"},
+		{Goal: "b", Timeline: "t", SalaryTarget: "300", Currency: "RUB"},
+		{Goal: "c", Timeline: "t", SalaryTarget: "10", Currency: "USD"},
+	} {
+		_, body := doJSON(t, server, "POST", "/goals", goal)
+		var created Goal
+		json.Unmarshal(body, &created)
+		ids = append(ids, created.ID)
+	}
+	doJSON(t, server, "POST", "/goals/"+strconv.Itoa(ids[0])+"/toggle", nil)
+
+	status, body = doJSON(t, server, "GET", "/goals/stats", nil)
+	var stats goalStats
+	if err := json.Unmarshal(body, &stats); status != http.StatusOK || err != nil {
+		t.Fatalf("Expected stats, got %d %s (%v)", status, body, err)
+	}
+	if stats.Count != 3 || stats.CompletedCount != 1 || stats.OpenCount != 2 || len(stats.ByCurrency) != 2 {
+		t.Fatalf("Unexpected stats: %s", body)
+	}
+	rub, usd := stats.ByCurrency[0], stats.ByCurrency[1]
+	if rub.Currency != "RUB" || rub.Count != 2 || rub.AvgSalary.String() != "200.00" || rub.MinSalary.String() != "100" || rub.MaxSalary.String() != "300" {
+		t.Errorf("Unexpected RUB stats: %+v", rub)
+	}
+	if usd.Currency != "USD" || usd.Count != 1 || usd.AvgSalary.String() != "10.00" {
+		t.Errorf("Unexpected USD stats: %+v", usd)
+	}
+}
+
+// ТЕСТ: GET /goals/timelines возвращает различные сроки по алфавиту
+func TestGoalTimelinesInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	if status, body := doJSON(t, server, "GET", "/goals/timelines", nil); status != http.StatusOK || string(bytes.TrimSpace(body)) != "[]" {
+		t.Errorf("Expected [] for an empty store, got %d %s", status, body)
+	}
+
+	for _, timeline := range []string{"2030", "2026", "2030"} {
+		doJSON(t, server, "POST", "/goals", Goal{Goal: "Timeline goal", Timeline: timeline})
+	}
+	status, body := doJSON(t, server, "GET", "/goals/timelines", nil)
+	if status != http.StatusOK || string(bytes.TrimSpace(body)) != `["2026","2030"]` {
+		t.Errorf("Expected [\"2026\",\"2030\"], got %d %s", status, body)
+	}
+}
+
+// ТЕСТ: Метки сохраняются нормализованными, ?tag= фильтрует список, некорректные метки отклоняются
+func TestGoalTagsInMemory(t *testing.T) {
+	server := newTestServer(t)
+
+	status, body := doJSON(t, server, "POST", "/goals", Goal{Goal: "Run", Timeline: "2026", Tags: []string{" Health ", "health", "спорт"}})
+	if status != http.StatusCreated {
+		t.Fatalf("Create: expected %d, got %d (%s)", http.StatusCreated, status, body)
+	}
+	var created Goal
+	json.Unmarshal(body, &created)
+	if len(created.Tags) != 2 || created.Tags[0] != "health" || created.Tags[1] != "спорт" {
+		t.Errorf("Expected normalized tags [health спорт], got %v", created.Tags)
+	}
+	doJSON(t, server, "POST", "/goals", Goal{Goal: "Promotion", Timeline: "2027"})
+
+	status, body = doJSON(t, server, "GET", "/goals?tag=HEALTH", nil)
+	var goals []Goal
+	json.Unmarshal(body, &goals)
+	if status != http.StatusOK || len(goals) != 1 || goals[0].ID != created.ID {
+		t.Errorf("Expected only goal %d for ?tag=health, got %d %s", created.ID, status, body)
+	}
+
+	// Цель без меток возвращает [], а не null
+	status, body = doJSON(t, server, "GET", "/goals", nil)
+	if status != http.StatusOK || !bytes.Contains(body, []byte(`"tags":[]`)) {
+		t.Errorf("Expected empty tags array for untagged goal, got %d %s", status, body)
+	}
+
+	if status, _ := doJSON(t, server, "GET", "/goals?tag=bad%20tag", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected %d for invalid tag filter, got %d", http.StatusBadRequest, status)
+	}
+	status, body = doJSON(t, server, "POST", "/goals", Goal{Goal: "x", Timeline: "y", Tags: []string{"no spaces"}})
+	if status != http.StatusUnprocessableEntity || !bytes.Contains(body, []byte(`"code":"invalid"`)) {
+		t.Errorf("Expected 422 invalid tag, got %d %s", status, body)
+	}
+}
+
+// ХРАНИЛИЩЕ, ЧЕЙ ЗАПРОС ДЛИТСЯ ДО ОТМЕНЫ КОНТЕКСТА
+// Имитирует долгий SELECT: возвращается только с ошибкой контекста и сообщает, чем закончился
+type blockingGoalStore struct {
+	*memoryGoalStore
+	started chan struct{}
+	aborted chan error
+}
+
+func (s *blockingGoalStore) wait(ctx context.Context) error {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	s.aborted <- ctx.Err()
+	return fmt.Errorf("query: %w", ctx.Err())
+}
+
+func (s *blockingGoalStore) List(ctx context.Context, filter goalFilter) ([]Goal, error) {
+	return nil, s.wait(ctx)
+}
+
+func (s *blockingGoalStore) Each(ctx context.Context, filter goalFilter, fn func(Goal) error) error {
+	return s.wait(ctx)
+}
+
+func (s *blockingGoalStore) Count(ctx context.Context, filter goalFilter) (int64, error) {
+	return 0, s.wait(ctx)
+}
+
+// ТЕСТ: Список, прочитанный до записи, не попадает в кэш после его сброса
+func TestStoreCachedGoalsSkipsStaleRead(t *testing.T) {
+	defer func(previous bool) { readCacheEnabled = previous }(readCacheEnabled)
+	readCacheEnabled = true
+	defer invalidateCachedGoals()
+
+	stale := []Goal{{ID: 1, Goal: "before write"}}
+	generation := cacheGeneration.Load()
+	invalidateCachedGoals() // Запись завершилась, пока шло чтение
+	storeCachedGoals(stale, generation)
+	if _, ok := loadCachedGoals(); ok {
+		t.Errorf("Expected stale list not to be cached after invalidation")
+	}
+
+	storeCachedGoals(stale, cacheGeneration.Load())
+	if goals, ok := loadCachedGoals(); !ok || len(goals) != 1 {
+		t.Errorf("Expected fresh list to be cached, got %v (ok=%v)", goals, ok)
+	}
+}
+
+// ТЕСТ: Отключение клиента отменяет запрос к хранилищу, обработчик сразу завершается
+func TestHandlersAbortQueryOnClientDisconnect(t *testing.T) {
+	defer func(previous GoalStore) { goalStore = previous }(goalStore)
+	store := &blockingGoalStore{memoryGoalStore: newMemoryGoalStore(), started: make(chan struct{}, 1), aborted: make(chan error, 1)}
+	goalStore = store
+	invalidateCachedGoals()
+
+	handlers := map[string]http.HandlerFunc{
+		"/goals":        getGoalsHandler,
+		"/goals/count":  countGoalsHandler,
+		"/goals/ndjson": exportGoalsNDJSONHandler,
+	}
+	for path, handler := range handlers {
+		ctx, cancel := context.WithCancel(context.Background())
+		recorder := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			handler(recorder, httptest.NewRequest("GET", path, nil).WithContext(ctx))
+			close(done)
+		}()
+
+		// Отключаемся, когда запрос уже выполняется
+		<-store.started
+		cancel()
+
+		select {
+		case err := <-store.aborted:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%s: expected query context to be canceled, got %v", path, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: query was not aborted after client disconnect", path)
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: handler did not return after client disconnect", path)
+		}
+		if recorder.Code == http.StatusOK && recorder.Body.Len() > 0 {
+			t.Errorf("%s: expected no successful response after disconnect, got %d %q", path, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+// ТЕСТ: PUT с If-Match обновляет цель только при совпадении ETag
+func TestUpdateGoalIfMatch(t *testing.T) {
+	server := newTestServer(t)
+
+	put := func(path, ifMatch string, goal Goal) *http.Response {
+		t.Helper()
+		data, _ := json.Marshal(goal)
+		req, _ := http.NewRequest("PUT", server.URL+path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("PUT %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// ETag приходит в ответе на создание
+	data, _ := json.Marshal(Goal{Goal: "Learn Go", Timeline: "2026"})
+	resp, err := server.Client().Post(server.URL+"/goals", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST /goals failed: %v", err)
+	}
+	var created Goal
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	firstETag := resp.Header.Get("ETag")
+	if firstETag == "" || firstETag != goalETag(created) {
+		t.Fatalf("Expected ETag of the created goal, got %q", firstETag)
+	}
+	path := "/goals/" + strconv.Itoa(created.ID)
+
+	// GET /goals/{id} отдаёт тот же ETag, что и создание
+	resp, err = server.Client().Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != firstETag {
+		t.Fatalf("GET %s: expected %d with ETag %q, got %d %q", path, http.StatusOK, firstETag, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	// Совпадающий ETag: обновление проходит, ETag меняется
+	resp = put(path, firstETag, Goal{Goal: "Master Go", Timeline: "2026"})
+	secondETag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || secondETag == "" || secondETag == firstETag {
+		t.Fatalf("Matching If-Match: expected %d with a new ETag, got %d %q", http.StatusOK, resp.StatusCode, secondETag)
+	}
+
+	// Устаревший ETag: 412, цель не меняется
+	resp = put(path, firstETag, Goal{Goal: "Lost update", Timeline: "2026"})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Stale If-Match: expected %d, got %d", http.StatusPreconditionFailed, resp.StatusCode)
+	}
+	_, body := doJSON(t, server, "GET", "/goals", nil)
+	var goals []Goal
+	json.Unmarshal(body, &goals)
+	if len(goals) != 1 || goals[0].Goal != "Master Go" {
+		t.Errorf("Stale If-Match: expected goal to stay unchanged, got %s", body)
+	}
+
+	// ETag из GET /goals/{id} после обновления подходит для следующего If-Match
+	req, _ := http.NewRequest("GET", server.URL+path, nil)
+	req.Header.Set("If-None-Match", secondETag)
+	resp, err = server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with current If-None-Match: expected %d, got %d", http.StatusNotModified, resp.StatusCode)
+	}
+
+	// Слабый ETag для If-Match не подходит; "*" и отсутствие заголовка — обновление без условий
+	if resp = put(path, "W/"+secondETag, Goal{Goal: "Weak", Timeline: "2026"}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Weak If-Match: expected %d, got %d", http.StatusPreconditionFailed, resp.StatusCode)
+	}
+	if resp = put(path, "*", Goal{Goal: "Any", Timeline: "2026"}); resp.StatusCode != http.StatusOK {
+		t.Errorf("If-Match *: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp = put(path, "", Goal{Goal: "Unconditional", Timeline: "2026"}); resp.StatusCode != http.StatusOK {
+		t.Errorf("No If-Match: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// IN-MEMORY ХРАНИЛИЩЕ ДЛЯ ТЕСТОВ
+// Повторяет поведение pgGoalStore (фильтры, лимит, ошибки), но без базы данных
+type memoryGoalStore struct {
+	mu     sync.Mutex
+	goals  []Goal
+	nextID int
+	// Время выполнения по ID (в Goal этого поля нет, как и в SELECT хранилища)
+	completedAt map[int]time.Time
+}
+
+func newMemoryGoalStore() *memoryGoalStore {
+	return &memoryGoalStore{nextID: 1, completedAt: make(map[int]time.Time)}
+}
+
+// Соответствует ли цель фильтру (семантика как у goalFilter.whereClause)
+func (s *memoryGoalStore) matches(f goalFilter, g Goal) bool {
+	if f.MinSalary != nil && decimalRat(g.SalaryTarget).Cmp(decimalRat(*f.MinSalary)) < 0 {
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(g.Goal), q) && !strings.Contains(strings.ToLower(g.Timeline), q) {
+			return false
+		}
+	}
+	if f.CreatedAfter != nil && g.CreatedAt.Before(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !g.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(g.Tags, f.Tag) {
+		return false
+	}
+	return true
+}
+
+func decimalRat(d Decimal) *big.Rat {
+	r, _ := new(big.Rat).SetString(d.String())
+	return r
+}
+
+func (s *memoryGoalStore) index(id int) int {
+	for i, g := range s.goals {
+		if g.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memoryGoalStore) Get(ctx context.Context, id int) (Goal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Goal{}, errGoalNotFound
+	}
+	return s.goals[i], nil
+}
+
+func (s *memoryGoalStore) List(ctx context.Context, filter goalFilter) ([]Goal, error) {
+	var goals []Goal
+	err := s.Each(ctx, filter, func(g Goal) error {
+		goals = append(goals, g)
+		return nil
+	})
+	return goals, err
+}
+
+func (s *memoryGoalStore) Each(ctx context.Context, filter goalFilter, fn func(Goal) error) error {
+	s.mu.Lock()
+	var goals []Goal
+	for _, g := range s.goals {
+		if s.matches(filter, g) {
+			goals = append(goals, g)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(goals, func(i, j int) bool { return goals[i].CreatedAt.Before(goals[j].CreatedAt) })
+	for _, g := range goals {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryGoalStore) Count(ctx context.Context, filter goalFilter) (int64, error) {
+	goals, err := s.List(ctx, filter)
+	return int64(len(goals)), err
+}
+
+func (s *memoryGoalStore) Stats(ctx context.Context) (goalStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Как в goalStatsQuery: GROUP BY currency, средняя округляется до двух знаков
+	type salaries struct {
+		stats    currencyGoalStats
+		sum      *big.Rat
+		min, max *big.Rat
+	}
+	byCurrency := map[string]*salaries{}
+	stats := goalStats{ByCurrency: []currencyGoalStats{}}
+	for _, g := range s.goals {
+		salary := decimalRat(g.SalaryTarget)
+		c, ok := byCurrency[g.Currency]
+		if !ok {
+			c = &salaries{stats: currencyGoalStats{Currency: g.Currency}, sum: new(big.Rat)}
+			byCurrency[g.Currency] = c
+		}
+		c.stats.Count++
+		c.sum.Add(c.sum, salary)
+		if c.min == nil || salary.Cmp(c.min) < 0 {
+			c.min, c.stats.MinSalary = salary, Decimal(g.SalaryTarget.String())
+		}
+		if c.max == nil || salary.Cmp(c.max) > 0 {
+			c.max, c.stats.MaxSalary = salary, Decimal(g.SalaryTarget.String())
+		}
+
+		stats.Count++
+		if _, done := s.completedAt[g.ID]; done {
+			stats.CompletedCount++
+		} else {
+			stats.OpenCount++
+		}
+	}
+
+	for _, c := range byCurrency {
+		avg := new(big.Rat).Quo(c.sum, big.NewRat(c.stats.Count, 1))
+		c.stats.AvgSalary = Decimal(avg.FloatString(2))
+		stats.ByCurrency = append(stats.ByCurrency, c.stats)
+	}
+	sort.Slice(stats.ByCurrency, func(i, j int) bool { return stats.ByCurrency[i].Currency < stats.ByCurrency[j].Currency })
+	return stats, nil
+}
+
+func (s *memoryGoalStore) Timelines(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timelines := []string{}
+	for _, g := range s.goals {
+		if !slices.Contains(timelines, g.Timeline) {
+			timelines = append(timelines, g.Timeline)
+		}
+	}
+	sort.Strings(timelines)
+	return timelines, nil
+}
+
+func (s *memoryGoalStore) Create(ctx context.Context, goal *Goal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxGoalsPerUser > 0 && len(s.goals) >= maxGoalsPerUser {
+		return fmt.Errorf("%w (%d)", errGoalLimitReached, maxGoalsPerUser)
+	}
+	goal.ID = s.nextID
+	s.nextID++
+	if goal.CreatedAt.IsZero() {
+		goal.CreatedAt = time.Now()
+	}
+	s.goals = append(s.goals, *goal)
+	return nil
+}
+
+func (s *memoryGoalStore) CreateMany(ctx context.Context, goals []Goal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxGoalsPerUser > 0 && len(s.goals)+len(goals) > maxGoalsPerUser {
+		return fmt.Errorf("%w (%d)", errGoalLimitReached, maxGoalsPerUser)
+	}
+	for i := range goals {
+		goals[i].ID = s.nextID
+		s.nextID++
+		if goals[i].CreatedAt.IsZero() {
+			goals[i].CreatedAt = time.Now()
+		}
+		s.goals = append(s.goals, goals[i])
+	}
+	return nil
+}
+
+func (s *memoryGoalStore) Duplicate(ctx context.Context, id int) (Goal, error) {
+	s.mu.Lock()
+	i := s.index(id)
+	if i < 0 {
+		s.mu.Unlock()
+		return Goal{}, errGoalNotFound
+	}
+	copied := s.goals[i]
+	s.mu.Unlock()
+
+	copied.Goal += " (copy)"
+	copied.CreatedAt = time.Time{}
+	err := s.Create(ctx, &copied)
+	return copied, err
+}
+
+func (s *memoryGoalStore) Update(ctx context.Context, id int, goal Goal, precondition func(current Goal) error) (Goal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Goal{}, errGoalNotFound
+	}
+	if precondition != nil {
+		if err := precondition(s.goals[i]); err != nil {
+			return Goal{}, err
+		}
+	}
+	goal.ID, goal.CreatedAt = id, s.goals[i].CreatedAt
+	s.goals[i] = goal
+	return goal, nil
+}
+
+func (s *memoryGoalStore) SetCreatedAt(ctx context.Context, id int, createdAt time.Time) (Goal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Goal{}, errGoalNotFound
+	}
+	s.goals[i].CreatedAt = createdAt
+	return s.goals[i], nil
+}
+
+func (s *memoryGoalStore) ToggleCompleted(ctx context.Context, id int) (goalCompletion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index(id) < 0 {
+		return goalCompletion{}, errGoalNotFound
+	}
+	if _, done := s.completedAt[id]; done {
+		delete(s.completedAt, id)
+		return goalCompletion{ID: id}, nil
+	}
+	now := time.Now()
+	s.completedAt[id] = now
+	return goalCompletion{ID: id, Completed: true, CompletedAt: &now}, nil
+}
+
+func (s *memoryGoalStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return errGoalNotFound
+	}
+	s.goals = append(s.goals[:i], s.goals[i+1:]...)
+	return nil
+}
+
+func (s *memoryGoalStore) DeleteMany(ctx context.Context, ids []int) (int64, error) {
+	var deleted int64
+	for _, id := range ids {
+		if s.Delete(ctx, id) == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// ФУНКЦИЯ: newTestServer
+// НАЗНАЧЕНИЕ: Поднимает все маршруты приложения поверх in-memory хранилища
+// Запросы идут через те же middleware, что и в продакшене; база данных не нужна.
+// Исходные хранилище и маршрутизатор восстанавливаются после теста
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	previousStore, previousMux := goalStore, appMux
+	goalStore = newMemoryGoalStore()
+	appMux = http.NewServeMux()
+	invalidateCachedGoals()
+	resetCachedStats()
+
+	initMetrics()
+	registerHandlers()
+	server := httptest.NewServer(appHandler())
+
+	t.Cleanup(func() {
+		server.Close()
+		goalStore, appMux = previousStore, previousMux
+		invalidateCachedGoals()
+		resetCachedStats()
+	})
+	return server
+}
+
+// Статистика кэшируется на STATS_CACHE_TTL и записью не сбрасывается: тестам нужна свежая
+func resetCachedStats() {
+	statsMutex.Lock()
+	cachedStatsAt = time.Time{}
+	statsMutex.Unlock()
+}
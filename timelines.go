@@ -0,0 +1,73 @@
+// ФАЙЛ: timelines.go
+// НАЗНАЧЕНИЕ: Список различных сроков целей для фильтра в интерфейсе (GET /goals/timelines)
+// ОСОБЕННОСТИ:
+//   - Клиенту не нужно выгружать весь список целей и убирать дубликаты самому
+//   - Результат кэшируется на STATS_CACHE_TTL и сбрасывается при любой записи
+//     (запрос, начатый до записи, свой результат в кэш не кладёт)
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ГЛОБАЛЬНЫЕ ПЕРЕМЕННЫЕ КЭША СРОКОВ
+var (
+	cachedTimelines   []string
+	cachedTimelinesAt time.Time
+	timelinesMutex    sync.Mutex
+)
+
+// ФУНКЦИЯ: invalidateCachedTimelines
+// НАЗНАЧЕНИЕ: Сбрасывает кэш сроков (вызывается вместе со сбросом кэша целей)
+func invalidateCachedTimelines() {
+	timelinesMutex.Lock()
+	cachedTimelines, cachedTimelinesAt = nil, time.Time{}
+	timelinesMutex.Unlock()
+}
+
+// ОБРАБОТЧИК: GET /goals/timelines
+func goalTimelinesHandler(w http.ResponseWriter, r *http.Request) {
+	logger.LogRequest(r.Method, r.URL.Path, 0)
+
+	// ШАГ 1: ПРОВЕРКА HTTP-МЕТОДА
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+		logger.LogRequest(r.Method, r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ШАГ 2: СВЕЖИЙ РЕЗУЛЬТАТ ИЗ КЭША
+	timelinesMutex.Lock()
+	if !cachedTimelinesAt.IsZero() && time.Since(cachedTimelinesAt) < statsCacheTTL {
+		timelines := cachedTimelines
+		timelinesMutex.Unlock()
+		writeJSON(w, r, http.StatusOK, timelines)
+		return
+	}
+	timelinesMutex.Unlock()
+
+	// ШАГ 3: ЗАПРОС К БД
+	generation := cacheGeneration.Load()
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	timelines, err := goalStore.Timelines(ctx)
+	if err != nil {
+		logger.LogError(err, "Ошибка выборки сроков в goalTimelinesHandler")
+		writeDBError(w, r, err, "Query error")
+		return
+	}
+
+	timelinesMutex.Lock()
+	if cacheGeneration.Load() == generation {
+		cachedTimelines, cachedTimelinesAt = timelines, time.Now()
+	}
+	timelinesMutex.Unlock()
+
+	// ШАГ 4: ОТПРАВКА ОТВЕТА
+	writeJSON(w, r, http.StatusOK, timelines)
+}
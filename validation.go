@@ -0,0 +1,204 @@
+// ФАЙЛ: validation.go
+// НАЗНАЧЕНИЕ: Проверка входных данных целей перед записью в БД
+// ОСОБЕННОСТИ:
+//   - Машиночитаемые коды ошибок для каждого поля
+//   - Ответ 422 Unprocessable Entity со списком всех найденных ошибок
+//   - Длина текста цели и срока ограничена (MAX_GOAL_LENGTH, MAX_TIMELINE_LENGTH), считается в символах
+//   - Метки (tags) приводятся к нижнему регистру, повторы убираются
+//   - DEFAULT_TIMELINE и DEFAULT_SALARY_TARGET подставляются в новые цели без этих полей
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// КОДЫ ОШИБОК ВАЛИДАЦИИ
+// Клиенты могут полагаться на эти значения, поэтому их нельзя менять
+const (
+	ValidationCodeRequired    = "required"    // Поле обязательно, но не заполнено
+	ValidationCodeNegative    = "negative"    // Число не может быть отрицательным
+	ValidationCodePositive    = "positive"    // Число должно быть больше нуля
+	ValidationCodeUnsupported = "unsupported" // Значение не входит в список допустимых
+	ValidationCodeFuture      = "future"      // Время не может быть в будущем
+	ValidationCodeSchema      = "schema"      // Тело не соответствует JSON Schema (подробности в message)
+	ValidationCodeInvalid     = "invalid"     // Значение не разобрать (число, дата, метка)
+	ValidationCodeTooMany     = "too_many"    // Слишком много элементов в списке
+	ValidationCodeTooLong     = "too_long"    // Строка длиннее допустимого
+)
+
+// Валюта по умолчанию: существующие клиенты не передают currency
+const defaultCurrency = "RUB"
+
+// Поддерживаемые валюты (коды ISO 4217)
+var supportedCurrencies = map[string]bool{
+	"RUB": true, "USD": true, "EUR": true, "GBP": true, "CHF": true, "CNY": true,
+	"JPY": true, "KZT": true, "BYN": true, "UAH": true, "UZS": true, "AMD": true,
+	"GEL": true, "AZN": true, "KGS": true, "TRY": true, "AED": true, "INR": true,
+	"CAD": true, "AUD": true, "PLN": true, "CZK": true, "SEK": true, "NOK": true,
+	"DKK": true, "ILS": true, "KRW": true, "SGD": true, "HKD": true, "THB": true,
+}
+
+// Максимальная длина текста цели и срока в символах (не байтах); 0 — без ограничения
+var (
+	maxGoalLength     = 1000
+	maxTimelineLength = 200
+)
+
+// ИНИЦИАЛИЗАЦИЯ ОГРАНИЧЕНИЙ ДЛИНЫ
+func initGoalLengthLimits() {
+	maxGoalLength = max(getEnvInt("MAX_GOAL_LENGTH", 1000), 0)
+	maxTimelineLength = max(getEnvInt("MAX_TIMELINE_LENGTH", 200), 0)
+	logger.InfoLogger.Printf("📏 Максимальная длина: цель %d, срок %d символов", maxGoalLength, maxTimelineLength)
+}
+
+// Длиннее ли строка ограничения (limit <= 0 — ограничения нет)
+func exceedsLength(value string, limit int) bool {
+	return limit > 0 && utf8.RuneCountInString(value) > limit
+}
+
+// ОГРАНИЧЕНИЯ МЕТОК
+const (
+	maxGoalTags     = 20 // Меток у одной цели
+	maxGoalTagRunes = 32 // Символов в одной метке
+)
+
+// Метка: буквы, цифры, "-" и "_" (кириллица разрешена)
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+
+// Описание правил для сообщений об ошибке
+const tagRulesMessage = "метка — от 1 до 32 символов: буквы, цифры, - и _"
+
+// Метки сравниваются без учёта регистра и пробелов по краям
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// Допустима ли (уже нормализованная) метка
+func isValidTag(tag string) bool {
+	return utf8.RuneCountInString(tag) <= maxGoalTagRunes && tagPattern.MatchString(tag)
+}
+
+// Значения по умолчанию для новых целей (пустые — поле обязательно, как раньше)
+var (
+	defaultTimeline     string
+	defaultSalaryTarget Decimal
+)
+
+// ИНИЦИАЛИЗАЦИЯ ЗНАЧЕНИЙ ПО УМОЛЧАНИЮ
+func initGoalDefaults() {
+	defaultTimeline = strings.TrimSpace(os.Getenv("DEFAULT_TIMELINE"))
+
+	defaultSalaryTarget = ""
+	if raw := os.Getenv("DEFAULT_SALARY_TARGET"); raw != "" {
+		value, ok := parseDecimal(raw)
+		if !ok || value.IsNegative() {
+			log.Fatalf("❌ Некорректный DEFAULT_SALARY_TARGET: %q (нужно неотрицательное число)", raw)
+		}
+		defaultSalaryTarget = value
+	}
+
+	if defaultTimeline != "" || defaultSalaryTarget != "" {
+		logger.InfoLogger.Printf("📝 Значения по умолчанию для новых целей: срок %q, зарплата %s", defaultTimeline, defaultSalaryTarget)
+	}
+}
+
+// ФУНКЦИЯ: applyNewGoalDefaults
+// НАЗНАЧЕНИЕ: Подставляет DEFAULT_TIMELINE и DEFAULT_SALARY_TARGET в незаполненные поля новой цели
+// Явно переданные значения (в том числе зарплата 0) не трогаются; проверка идёт уже после подстановки
+func applyNewGoalDefaults(g *Goal) {
+	if strings.TrimSpace(g.Timeline) == "" && defaultTimeline != "" {
+		g.Timeline = defaultTimeline
+	}
+	if g.SalaryTarget == "" {
+		g.SalaryTarget = defaultSalaryTarget
+	}
+}
+
+// ФУНКЦИЯ: goalDefaultsDescription
+// НАЗНАЧЕНИЕ: Описание значений по умолчанию для корневой страницы
+func goalDefaultsDescription() string {
+	timeline := "не задан (поле обязательно)"
+	if defaultTimeline != "" {
+		timeline = fmt.Sprintf("%q", defaultTimeline)
+	}
+	return fmt.Sprintf("timeline — %s, salary_target_rub_per_hour — %s", timeline, defaultSalaryTarget)
+}
+
+// ФУНКЦИЯ: applyGoalDefaults
+// НАЗНАЧЕНИЕ: Заполняет необязательные поля значениями по умолчанию перед проверкой
+func applyGoalDefaults(g *Goal) {
+	g.Currency = strings.ToUpper(strings.TrimSpace(g.Currency))
+	if g.Currency == "" {
+		g.Currency = defaultCurrency
+	}
+
+	// Пустой, а не nil срез: в БД колонка NOT NULL, в JSON — [] вместо null
+	tags := make([]string, 0, len(g.Tags))
+	seen := make(map[string]bool, len(g.Tags))
+	for _, tag := range g.Tags {
+		tag = normalizeTag(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	g.Tags = tags
+}
+
+// СТРУКТУРА ОШИБКИ ВАЛИДАЦИИ ПОЛЯ
+type FieldError struct {
+	Field   string `json:"field"`             // Имя поля в JSON
+	Code    string `json:"code"`              // Код ошибки (см. константы выше)
+	Message string `json:"message,omitempty"` // Пояснение (только для ошибок схемы)
+}
+
+// ФУНКЦИЯ: validateGoal
+// НАЗНАЧЕНИЕ: Проверяет цель и возвращает все найденные ошибки (пустой срез — всё в порядке)
+func validateGoal(g Goal) []FieldError {
+	var errs []FieldError
+
+	if strings.TrimSpace(g.Goal) == "" {
+		errs = append(errs, FieldError{Field: "goal", Code: ValidationCodeRequired})
+	} else if exceedsLength(g.Goal, maxGoalLength) {
+		errs = append(errs, FieldError{Field: "goal", Code: ValidationCodeTooLong})
+	}
+	if strings.TrimSpace(g.Timeline) == "" {
+		errs = append(errs, FieldError{Field: "timeline", Code: ValidationCodeRequired})
+	} else if exceedsLength(g.Timeline, maxTimelineLength) {
+		errs = append(errs, FieldError{Field: "timeline", Code: ValidationCodeTooLong})
+	}
+	if g.SalaryTarget.IsNegative() {
+		errs = append(errs, FieldError{Field: "salary_target_rub_per_hour", Code: ValidationCodeNegative})
+	}
+	if !supportedCurrencies[g.Currency] {
+		errs = append(errs, FieldError{Field: "currency", Code: ValidationCodeUnsupported})
+	}
+	if len(g.Tags) > maxGoalTags {
+		errs = append(errs, FieldError{Field: "tags", Code: ValidationCodeTooMany})
+	}
+	for _, tag := range g.Tags {
+		if !isValidTag(tag) {
+			errs = append(errs, FieldError{Field: "tags", Code: ValidationCodeInvalid})
+			break
+		}
+	}
+	if g.CreatedAt.After(time.Now()) {
+		errs = append(errs, FieldError{Field: "created_at", Code: ValidationCodeFuture})
+	}
+
+	return errs
+}
+
+// ФУНКЦИЯ: writeValidationErrors
+// НАЗНАЧЕНИЕ: Отправляет 422 с телом {"errors":[{"field":...,"code":...}]}
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	writeJSON(w, r, http.StatusUnprocessableEntity, map[string][]FieldError{"errors": errs})
+}
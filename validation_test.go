@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// ТЕСТ: Валюта по умолчанию и проверка по списку ISO 4217
+func TestValidateGoalCurrency(t *testing.T) {
+	cases := []struct {
+		currency string
+		expected string
+		valid    bool
+	}{
+		{"", "RUB", true},
+		{"usd", "USD", true},
+		{" EUR ", "EUR", true},
+		{"XYZ", "XYZ", false},
+	}
+
+	for _, tc := range cases {
+		g := Goal{Goal: "Goal", Timeline: "2026", Currency: tc.currency}
+		applyGoalDefaults(&g)
+
+		if g.Currency != tc.expected {
+			t.Errorf("%q: expected currency %q, got %q", tc.currency, tc.expected, g.Currency)
+		}
+		errs := validateGoal(g)
+		if tc.valid && len(errs) != 0 {
+			t.Errorf("%q: expected no errors, got %v", tc.currency, errs)
+		}
+		if !tc.valid && (len(errs) != 1 || errs[0] != (FieldError{Field: "currency", Code: ValidationCodeUnsupported})) {
+			t.Errorf("%q: expected unsupported currency error, got %v", tc.currency, errs)
+		}
+	}
+}
+
+// ТЕСТ: DEFAULT_TIMELINE и DEFAULT_SALARY_TARGET заполняют только незаданные поля
+func TestApplyNewGoalDefaults(t *testing.T) {
+	defer func(timeline string, salary Decimal) {
+		defaultTimeline, defaultSalaryTarget = timeline, salary
+	}(defaultTimeline, defaultSalaryTarget)
+	defaultTimeline, defaultSalaryTarget = "2027", "1500"
+
+	g := Goal{Goal: "Goal"}
+	applyNewGoalDefaults(&g)
+	applyGoalDefaults(&g)
+	if g.Timeline != "2027" || g.SalaryTarget != "1500" {
+		t.Errorf("Expected defaults to be applied, got timeline %q salary %q", g.Timeline, g.SalaryTarget)
+	}
+	if errs := validateGoal(g); len(errs) != 0 {
+		t.Errorf("Expected no errors with defaults, got %v", errs)
+	}
+
+	explicit := Goal{Goal: "Goal", Timeline: "2030", SalaryTarget: "0"}
+	applyNewGoalDefaults(&explicit)
+	if explicit.Timeline != "2030" || explicit.SalaryTarget != "0" {
+		t.Errorf("Expected explicit values to be kept, got timeline %q salary %q", explicit.Timeline, explicit.SalaryTarget)
+	}
+
+	// Без DEFAULT_TIMELINE срок по-прежнему обязателен
+	defaultTimeline = ""
+	missing := Goal{Goal: "Goal"}
+	applyNewGoalDefaults(&missing)
+	applyGoalDefaults(&missing)
+	errs := validateGoal(missing)
+	if len(errs) != 1 || errs[0] != (FieldError{Field: "timeline", Code: ValidationCodeRequired}) {
+		t.Errorf("Expected required timeline error, got %v", errs)
+	}
+}
+
+// ТЕСТ: Длина цели и срока ограничена в символах, а не байтах (граница включительно)
+func TestValidateGoalLength(t *testing.T) {
+	defer func(goal, timeline int) { maxGoalLength, maxTimelineLength = goal, timeline }(maxGoalLength, maxTimelineLength)
+	maxGoalLength, maxTimelineLength = 5, 3
+
+	cases := []struct {
+		goal, timeline string
+		expected       []FieldError
+	}{
+		{"Цельь", "год", nil}, // 5 и 3 символа кириллицей (10 и 6 байт) — ровно на границе
+		{"Цельъя", "год", []FieldError{{Field: "goal", Code: ValidationCodeTooLong}}},
+		{"Цель", "годы", []FieldError{{Field: "timeline", Code: ValidationCodeTooLong}}},
+		{"🎯🎯🎯🎯🎯", "год", nil}, // каждый эмодзи — один символ, хотя занимает 4 байта
+	}
+
+	for _, tc := range cases {
+		g := Goal{Goal: tc.goal, Timeline: tc.timeline}
+		applyGoalDefaults(&g)
+		errs := validateGoal(g)
+		if len(errs) != len(tc.expected) {
+			t.Errorf("%q/%q: expected %v, got %v", tc.goal, tc.timeline, tc.expected, errs)
+			continue
+		}
+		for i := range errs {
+			if errs[i] != tc.expected[i] {
+				t.Errorf("%q/%q: expected %v, got %v", tc.goal, tc.timeline, tc.expected, errs)
+			}
+		}
+	}
+
+	// 0 отключает ограничение
+	maxGoalLength = 0
+	if errs := validateGoal(Goal{Goal: strings.Repeat("x", 100000), Timeline: "y", Currency: defaultCurrency}); len(errs) != 0 {
+		t.Errorf("Expected no limit with MAX_GOAL_LENGTH=0, got %v", errs)
+	}
+}